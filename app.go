@@ -29,6 +29,17 @@ func (a *App) startup(ctx context.Context) {
 		log.Printf("消息数据库管理器初始化失败: %v", err)
 	}
 
+	// 恢复上次保存的会话
+	count, err := core.GlobalSessionManager.RestoreSessions()
+	if err != nil {
+		log.Printf("恢复会话失败: %v", err)
+	} else {
+		log.Printf("已恢复 %d 个会话", count)
+	}
+
+	// 加载会话脚本
+	core.GlobalScriptManager.StartAll()
+
 	// 初始化WebSocket管理器
 	err = core.InitWebSocketManager()
 	if err != nil {
@@ -42,10 +53,19 @@ func (a *App) startup(ctx context.Context) {
 			log.Printf("WebSocket服务器启动成功，端口: %d", core.GlobalWebSocketManager.GetPort())
 		}
 	}
+
+	// 启动串口插拔监视
+	core.GlobalSerialManager.StartPortWatcher()
 }
 
 // shutdown is called when the app is shutting down
 func (a *App) shutdown(ctx context.Context) {
+	// 停止串口插拔监视
+	core.GlobalSerialManager.StopPortWatcher()
+
+	// 停止会话脚本
+	core.GlobalScriptManager.StopAll()
+
 	// 停止WebSocket服务器
 	if core.GlobalWebSocketManager != nil {
 		err := core.GlobalWebSocketManager.StopServer()
@@ -56,11 +76,19 @@ func (a *App) shutdown(ctx context.Context) {
 		}
 	}
 
-	// 清理所有消息数据库
+	// 保存会话定义
+	core.GlobalSessionManager.PersistSessions()
+
+	// 关闭消息数据库，仅在保留策略要求时清空历史记录
 	if core.GlobalMessageDBManager != nil {
-		err := core.GlobalMessageDBManager.CloseAllDatabases()
+		var err error
+		if core.GlobalConfigStore.GetRetentionPolicy().ClearHistoryOnExit {
+			err = core.GlobalMessageDBManager.DeleteAllDatabases()
+		} else {
+			err = core.GlobalMessageDBManager.CloseAllDatabases()
+		}
 		if err != nil {
-			log.Printf("清理消息数据库失败: %v", err)
+			log.Printf("关闭消息数据库失败: %v", err)
 		}
 	}
 }
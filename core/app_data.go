@@ -0,0 +1,24 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// AppName 应用数据目录名称
+const AppName = "Netser"
+
+// AppDataDir 获取应用数据目录（不存在时创建），可附加子目录
+func AppDataDir(elem ...string) (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户配置目录失败: %v", err)
+	}
+
+	dir := filepath.Join(append([]string{base, AppName}, elem...)...)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建应用数据目录失败: %v", err)
+	}
+	return dir, nil
+}
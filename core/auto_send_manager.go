@@ -0,0 +1,114 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+// 定时发送最小间隔（毫秒）
+const minAutoSendInterval = 1
+
+// AutoSendManager 会话定时发送管理器
+type AutoSendManager struct{}
+
+var GlobalAutoSendManager = &AutoSendManager{}
+
+// autoSendTasks 会话上正在运行的定时发送
+var autoSendTasks = &taskSlot[*sessionTask, session.AutoSendState]{
+	task:  func(sess *Session) **sessionTask { return &sess.autoSend },
+	state: func(sess *Session) **session.AutoSendState { return &sess.Info.AutoSend },
+	halt:  func(state *session.AutoSendState) { state.Running = false },
+}
+
+// Start 启动会话的定时发送，已在运行时替换为新的配置
+func (am *AutoSendManager) Start(sessionID string, cfg session.AutoSendConfig) (*session.AutoSendState, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Interval < minAutoSendInterval {
+		return nil, fmt.Errorf("发送间隔必须大于等于%d毫秒", minAutoSendInterval)
+	}
+	if cfg.Count < 0 {
+		return nil, fmt.Errorf("发送次数不能为负数")
+	}
+	if _, err := DecodePayload(cfg.Data, cfg.IsHex); err != nil {
+		return nil, err
+	}
+
+	state := &session.AutoSendState{
+		AutoSendConfig: cfg,
+		Running:        true,
+		StartTime:      time.Now().UnixMilli(),
+	}
+	task := newSessionTask()
+	autoSendTasks.start(sess, &task, state)
+
+	log.Printf("会话 %s 启动定时发送，间隔 %dms，次数 %d", sessionID, cfg.Interval, cfg.Count)
+	go am.run(sess, &task, *state)
+
+	return state, nil
+}
+
+// Stop 停止会话的定时发送
+func (am *AutoSendManager) Stop(sessionID string) (*session.AutoSendState, error) {
+	return autoSendTasks.stop(sessionID)
+}
+
+// GetState 获取会话的定时发送状态，未启动过时返回空状态
+func (am *AutoSendManager) GetState(sessionID string) (*session.AutoSendState, error) {
+	return autoSendTasks.get(sessionID)
+}
+
+// run 定时发送循环
+func (am *AutoSendManager) run(sess *Session, task *sessionTask, state session.AutoSendState) {
+	sessionID := sess.Info.SessionID
+	ticker := time.NewTicker(time.Duration(state.Interval) * time.Millisecond)
+	defer ticker.Stop()
+
+	defer func() {
+		state.Running = false
+		autoSendTasks.finish(sess, task, state)
+
+		log.Printf("会话 %s 定时发送结束，成功 %d 次，失败 %d 次", sessionID, state.Sent, state.Failed)
+		if GlobalWebSocketManager != nil {
+			GlobalWebSocketManager.NotifyAutoSend(sessionID, state)
+		}
+	}()
+
+	for {
+		select {
+		case <-task.stop:
+			return
+		default:
+		}
+
+		_, err := GlobalSessionManager.SendData(sessionID, state.Data, state.IsHex, state.Target)
+		if err != nil {
+			state.Failed++
+			state.LastError = err.Error()
+		} else {
+			state.Sent++
+		}
+
+		if state.Count > 0 && state.Sent+state.Failed >= state.Count {
+			return
+		}
+
+		// 更新状态并推送进度
+		autoSendTasks.publish(sess, task, state)
+		if GlobalWebSocketManager != nil {
+			GlobalWebSocketManager.NotifyAutoSend(sessionID, state)
+		}
+
+		select {
+		case <-task.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,430 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/cert"
+)
+
+const (
+	// 证书目录（位于应用数据目录下）
+	CertDirectory = "certs"
+	// 证书、私钥与元数据文件名
+	certFileName = "cert.pem"
+	keyFileName  = "key.pem"
+	metaFileName = "meta.json"
+	// 默认有效天数
+	defaultCAValidDays   = 3650
+	defaultCertValidDays = 365
+)
+
+// 证书名称只允许安全的文件名字符
+var certNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// CertManager 本地证书颁发管理器
+type CertManager struct {
+	mutex sync.Mutex
+}
+
+var GlobalCertManager = &CertManager{}
+
+// CreateCA 创建自签名本地CA
+func (cm *CertManager) CreateCA(req cert.CertCreateCARequest) (*cert.CertInfo, error) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if err := cm.checkNewName(req.Name); err != nil {
+		return nil, err
+	}
+
+	if req.CommonName == "" {
+		req.CommonName = req.Name
+	}
+	if req.ValidDays <= 0 {
+		req.ValidDays = defaultCAValidDays
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成私钥失败: %v", err)
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	subject := pkix.Name{CommonName: req.CommonName}
+	if req.Organization != "" {
+		subject.Organization = []string{req.Organization}
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.AddDate(0, 0, req.ValidDays),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("创建CA证书失败: %v", err)
+	}
+
+	return cm.save(req.Name, "ca", "", der, key)
+}
+
+// IssueCert 使用本地CA签发服务端或客户端证书
+func (cm *CertManager) IssueCert(req cert.CertIssueRequest) (*cert.CertInfo, error) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if err := cm.checkNewName(req.Name); err != nil {
+		return nil, err
+	}
+
+	var extUsage x509.ExtKeyUsage
+	switch req.Kind {
+	case "server":
+		extUsage = x509.ExtKeyUsageServerAuth
+	case "client":
+		extUsage = x509.ExtKeyUsageClientAuth
+	default:
+		return nil, fmt.Errorf("不支持的证书类型: %s", req.Kind)
+	}
+
+	caInfo, err := cm.load(req.CAName)
+	if err != nil {
+		return nil, fmt.Errorf("CA不存在: %s", req.CAName)
+	}
+	if caInfo.Kind != "ca" {
+		return nil, fmt.Errorf("证书 %s 不是CA", req.CAName)
+	}
+
+	caPair, err := tls.LoadX509KeyPair(caInfo.CertFile, caInfo.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("加载CA失败: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(caPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("解析CA证书失败: %v", err)
+	}
+
+	if req.CommonName == "" {
+		req.CommonName = req.Name
+	}
+	if req.ValidDays <= 0 {
+		req.ValidDays = defaultCertValidDays
+	}
+
+	ips := make([]net.IP, 0, len(req.IPAddresses))
+	for _, addr := range req.IPAddresses {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, fmt.Errorf("无效的IP地址: %s", addr)
+		}
+		ips = append(ips, ip)
+	}
+
+	// TLS客户端不再使用CN校验主机名，服务端证书未指定SAN时以CN作为SAN
+	dnsNames := req.DNSNames
+	if req.Kind == "server" && len(dnsNames) == 0 && len(ips) == 0 {
+		if ip := net.ParseIP(req.CommonName); ip != nil {
+			ips = append(ips, ip)
+		} else {
+			dnsNames = []string{req.CommonName}
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成私钥失败: %v", err)
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	notAfter := now.AddDate(0, 0, req.ValidDays)
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: req.CommonName},
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{extUsage},
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caPair.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("签发证书失败: %v", err)
+	}
+
+	return cm.save(req.Name, req.Kind, req.CAName, der, key)
+}
+
+// ListCerts 获取所有证书
+func (cm *CertManager) ListCerts() ([]cert.CertInfo, error) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	return cm.list()
+}
+
+// list 读取证书目录下的全部证书
+func (cm *CertManager) list() ([]cert.CertInfo, error) {
+	dir, err := AppDataDir(CertDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取证书目录失败: %v", err)
+	}
+
+	certs := make([]cert.CertInfo, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := cm.load(entry.Name())
+		if err != nil {
+			log.Printf("读取证书 %s 失败: %v", entry.Name(), err)
+			continue
+		}
+		certs = append(certs, *info)
+	}
+
+	sort.Slice(certs, func(i, j int) bool {
+		return certs[i].CreatedAt < certs[j].CreatedAt
+	})
+	return certs, nil
+}
+
+// GetCert 根据名称获取证书
+func (cm *CertManager) GetCert(name string) (*cert.CertInfo, error) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	return cm.load(name)
+}
+
+// DeleteCert 删除证书，仍被其他证书或会话使用时拒绝删除
+func (cm *CertManager) DeleteCert(name string) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	dir, err := cm.certDir(name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("证书不存在: %s", name)
+	}
+
+	dependents, err := cm.dependents(name)
+	if err != nil {
+		return err
+	}
+	if len(dependents) > 0 {
+		return fmt.Errorf("证书 %s 仍被使用: %s", name, strings.Join(dependents, "、"))
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("删除证书失败: %v", err)
+	}
+
+	log.Printf("已删除证书: %s", name)
+	return nil
+}
+
+// dependents 列出由该CA签发的证书及在TLS配置中引用该证书的会话
+func (cm *CertManager) dependents(name string) ([]string, error) {
+	certs, err := cm.list()
+	if err != nil {
+		return nil, err
+	}
+
+	var dependents []string
+	for _, info := range certs {
+		if info.CAName == name {
+			dependents = append(dependents, "证书 "+info.Name)
+		}
+	}
+
+	sessions := GlobalSessionManager.GetAllSessions()
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].SessionID < sessions[j].SessionID
+	})
+	for _, info := range sessions {
+		if info.TLS == nil || (info.TLS.CAName != name && info.TLS.CertName != name) {
+			continue
+		}
+		label := info.Name
+		if label == "" {
+			label = info.SessionID
+		}
+		dependents = append(dependents, "会话 "+label)
+	}
+	return dependents, nil
+}
+
+// checkNewName 校验新证书名称
+func (cm *CertManager) checkNewName(name string) error {
+	dir, err := cm.certDir(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("证书已存在: %s", name)
+	}
+	return nil
+}
+
+// certDir 获取证书存储目录
+func (cm *CertManager) certDir(name string) (string, error) {
+	if !certNamePattern.MatchString(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("无效的证书名称: %s", name)
+	}
+
+	base, err := AppDataDir(CertDirectory)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, name), nil
+}
+
+// save 保存证书、私钥和元数据
+func (cm *CertManager) save(name, kind, caName string, der []byte, key *ecdsa.PrivateKey) (*cert.CertInfo, error) {
+	dir, err := cm.certDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("解析证书失败: %v", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("编码私钥失败: %v", err)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建证书目录失败: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	fingerprint := sha256.Sum256(der)
+	info := &cert.CertInfo{
+		Name:        name,
+		Kind:        kind,
+		CAName:      caName,
+		CommonName:  parsed.Subject.CommonName,
+		DNSNames:    parsed.DNSNames,
+		IPAddresses: make([]string, 0, len(parsed.IPAddresses)),
+		NotBefore:   parsed.NotBefore.UnixMilli(),
+		NotAfter:    parsed.NotAfter.UnixMilli(),
+		SHA256:      hex.EncodeToString(fingerprint[:]),
+		CertFile:    filepath.Join(dir, certFileName),
+		KeyFile:     filepath.Join(dir, keyFileName),
+		CreatedAt:   time.Now().UnixMilli(),
+	}
+	for _, ip := range parsed.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+
+	meta, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化证书信息失败: %v", err)
+	}
+
+	writes := []struct {
+		path string
+		data []byte
+		perm os.FileMode
+	}{
+		{info.CertFile, certPEM, 0644},
+		{info.KeyFile, keyPEM, 0600},
+		{filepath.Join(dir, metaFileName), meta, 0644},
+	}
+	for _, w := range writes {
+		if err := os.WriteFile(w.path, w.data, w.perm); err != nil {
+			os.RemoveAll(dir)
+			return nil, fmt.Errorf("写入证书文件失败: %v", err)
+		}
+	}
+
+	log.Printf("证书已生成: %s (%s)", name, kind)
+	return info, nil
+}
+
+// load 读取证书元数据
+func (cm *CertManager) load(name string) (*cert.CertInfo, error) {
+	dir, err := cm.certDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, metaFileName))
+	if err != nil {
+		return nil, fmt.Errorf("证书不存在: %s", name)
+	}
+
+	var info cert.CertInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("解析证书信息失败: %v", err)
+	}
+
+	// 以实际目录为准，数据目录迁移后仍可使用
+	info.CertFile = filepath.Join(dir, certFileName)
+	info.KeyFile = filepath.Join(dir, keyFileName)
+	return &info, nil
+}
+
+// newSerialNumber 生成随机证书序列号
+func newSerialNumber() (*big.Int, error) {
+	limit := new(big.Int).Lsh(big.NewInt(1), 128)
+	serial, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return nil, fmt.Errorf("生成序列号失败: %v", err)
+	}
+	return serial, nil
+}
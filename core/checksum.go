@@ -0,0 +1,215 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+// 校验算法
+const (
+	ChecksumNone        = "none"
+	ChecksumCRC16Modbus = "crc16Modbus" // CRC16/MODBUS，低字节在前
+	ChecksumCRC16CCITT  = "crc16Ccitt"  // CRC16/CCITT-FALSE，高字节在前
+	ChecksumCRC32       = "crc32"       // CRC32 (IEEE)，高字节在前
+	ChecksumXOR         = "xor"         // 异或校验(BCC)
+	ChecksumSum8        = "sum8"        // 累加和取低8位
+	ChecksumLRC         = "lrc"         // 纵向冗余校验(累加和取补码)
+)
+
+// 校验结果
+const (
+	ChecksumStatusOK       = "ok"
+	ChecksumStatusMismatch = "mismatch"
+)
+
+// ComputeChecksum 按算法计算校验值，多字节结果按算法默认字节序输出
+func ComputeChecksum(algorithm string, data []byte) ([]byte, error) {
+	switch algorithm {
+	case ChecksumCRC16Modbus:
+		out := make([]byte, 2)
+		binary.LittleEndian.PutUint16(out, crc16Modbus(data))
+		return out, nil
+	case ChecksumCRC16CCITT:
+		out := make([]byte, 2)
+		binary.BigEndian.PutUint16(out, crc16CCITT(data))
+		return out, nil
+	case ChecksumCRC32:
+		out := make([]byte, 4)
+		binary.BigEndian.PutUint32(out, crc32.ChecksumIEEE(data))
+		return out, nil
+	case ChecksumXOR:
+		var x byte
+		for _, b := range data {
+			x ^= b
+		}
+		return []byte{x}, nil
+	case ChecksumSum8:
+		var sum byte
+		for _, b := range data {
+			sum += b
+		}
+		return []byte{sum}, nil
+	case ChecksumLRC:
+		var sum byte
+		for _, b := range data {
+			sum += b
+		}
+		return []byte{-sum}, nil
+	default:
+		return nil, fmt.Errorf("不支持的校验算法: %s", algorithm)
+	}
+}
+
+// AppendChecksum 按配置在数据末尾追加校验值
+func AppendChecksum(cfg *session.ChecksumConfig, data []byte) ([]byte, error) {
+	start := cfg.StartOffset
+	if start > len(data) {
+		return nil, fmt.Errorf("校验起始偏移超出数据长度")
+	}
+
+	sum, err := checksumBytes(cfg, data[start:])
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(data)+len(sum))
+	out = append(out, data...)
+	return append(out, sum...), nil
+}
+
+// VerifyChecksum 校验帧末尾的校验值，返回校验结果及期望的校验值
+func VerifyChecksum(cfg *session.ChecksumConfig, frame []byte) (bool, []byte, error) {
+	size, err := checksumSize(cfg.Algorithm)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if len(frame) < cfg.StartOffset+size {
+		return false, nil, nil
+	}
+
+	body := frame[cfg.StartOffset : len(frame)-size]
+	expected, err := checksumBytes(cfg, body)
+	if err != nil {
+		return false, nil, err
+	}
+
+	return bytes.Equal(expected, frame[len(frame)-size:]), expected, nil
+}
+
+// ValidateChecksumConfig 校验配置是否合法
+func ValidateChecksumConfig(cfg *session.ChecksumConfig) error {
+	if cfg == nil || cfg.Algorithm == "" || cfg.Algorithm == ChecksumNone {
+		return nil
+	}
+	if _, err := checksumSize(cfg.Algorithm); err != nil {
+		return err
+	}
+	if cfg.StartOffset < 0 {
+		return fmt.Errorf("校验起始偏移不能为负数")
+	}
+	return nil
+}
+
+// buildSendPayload 解析待发送数据，按会话配置追加校验值
+func buildSendPayload(sessionID, data string, isHex bool) ([]byte, error) {
+	sendData, err := DecodePayload(data, isHex)
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return appendSessionChecksum(sess, sendData)
+}
+
+// appendSessionChecksum 会话配置了发送时追加校验则追加校验值
+func appendSessionChecksum(sess *Session, data []byte) ([]byte, error) {
+	cfg := sess.Info.Checksum
+	if !checksumEnabled(cfg) || !cfg.AppendOnSend {
+		return data, nil
+	}
+	return AppendChecksum(cfg, data)
+}
+
+// checksumEnabled 是否配置了校验算法
+func checksumEnabled(cfg *session.ChecksumConfig) bool {
+	return cfg != nil && cfg.Algorithm != "" && cfg.Algorithm != ChecksumNone
+}
+
+// checksumBytes 计算校验值并按配置调整字节序
+func checksumBytes(cfg *session.ChecksumConfig, data []byte) ([]byte, error) {
+	sum, err := ComputeChecksum(cfg.Algorithm, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.SwapBytes {
+		for i, j := 0, len(sum)-1; i < j; i, j = i+1, j-1 {
+			sum[i], sum[j] = sum[j], sum[i]
+		}
+	}
+	return sum, nil
+}
+
+// checksumSize 校验值字节数
+func checksumSize(algorithm string) (int, error) {
+	switch algorithm {
+	case ChecksumCRC16Modbus, ChecksumCRC16CCITT:
+		return 2, nil
+	case ChecksumCRC32:
+		return 4, nil
+	case ChecksumXOR, ChecksumSum8, ChecksumLRC:
+		return 1, nil
+	default:
+		return 0, fmt.Errorf("不支持的校验算法: %s", algorithm)
+	}
+}
+
+// crc16Modbus CRC16/MODBUS: 多项式0xA001(反射)，初值0xFFFF
+func crc16Modbus(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// crc16CCITT CRC16/CCITT-FALSE: 多项式0x1021，初值0xFFFF
+func crc16CCITT(data []byte) uint16 {
+	return crc16Poly1021(0xFFFF, data)
+}
+
+// crc16XModem CRC16/XMODEM: 多项式0x1021，初值0
+func crc16XModem(data []byte) uint16 {
+	return crc16Poly1021(0, data)
+}
+
+// crc16Poly1021 多项式0x1021的CRC16
+func crc16Poly1021(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
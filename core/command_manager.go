@@ -0,0 +1,389 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/command"
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+// 快捷指令行尾
+const (
+	LineEndingNone = ""
+	LineEndingCR   = "cr"
+	LineEndingLF   = "lf"
+	LineEndingCRLF = "crlf"
+)
+
+// CommandManager 快捷指令库管理器
+type CommandManager struct {
+	commands []command.CommandEntry
+	loaded   bool
+	mutex    sync.Mutex
+}
+
+var GlobalCommandManager = &CommandManager{}
+
+// List 获取指令列表及所有分组
+func (cm *CommandManager) List(req command.CommandListRequest) (*command.CommandListResponse, error) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if err := cm.load(); err != nil {
+		return nil, err
+	}
+
+	folder := normalizeCommandFolder(req.Folder)
+	resp := &command.CommandListResponse{Commands: []command.CommandEntry{}, Folders: []string{}}
+	folders := make(map[string]bool)
+	for _, entry := range cm.commands {
+		if req.SessionID != "" && entry.SessionID != "" && entry.SessionID != req.SessionID {
+			continue
+		}
+		for _, parent := range commandFolderParents(entry.Folder) {
+			folders[parent] = true
+		}
+		if folder != "" && !inCommandFolder(entry.Folder, folder) {
+			continue
+		}
+		resp.Commands = append(resp.Commands, entry)
+	}
+
+	for name := range folders {
+		resp.Folders = append(resp.Folders, name)
+	}
+	sort.Strings(resp.Folders)
+	return resp, nil
+}
+
+// Save 新建或更新指令，ID为空或不存在时新建
+func (cm *CommandManager) Save(entry command.CommandEntry) (*command.CommandEntry, error) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if err := cm.load(); err != nil {
+		return nil, err
+	}
+
+	entry.Folder = normalizeCommandFolder(entry.Folder)
+	if err := validateCommand(entry); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	entry.UpdatedAt = now
+
+	index := cm.indexOf(entry.ID)
+	if index >= 0 {
+		entry.CreatedAt = cm.commands[index].CreatedAt
+		cm.commands[index] = entry
+	} else {
+		if entry.ID == "" {
+			entry.ID = cm.newID()
+		}
+		entry.CreatedAt = now
+		cm.commands = append(cm.commands, entry)
+	}
+
+	if err := GlobalConfigStore.SaveCommands(cm.commands); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Delete 删除指令
+func (cm *CommandManager) Delete(id string) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if err := cm.load(); err != nil {
+		return err
+	}
+
+	index := cm.indexOf(id)
+	if index < 0 {
+		return fmt.Errorf("指令不存在: %s", id)
+	}
+	cm.commands = append(cm.commands[:index], cm.commands[index+1:]...)
+	return GlobalConfigStore.SaveCommands(cm.commands)
+}
+
+// Get 获取指令
+func (cm *CommandManager) Get(id string) (*command.CommandEntry, error) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if err := cm.load(); err != nil {
+		return nil, err
+	}
+
+	index := cm.indexOf(id)
+	if index < 0 {
+		return nil, fmt.Errorf("指令不存在: %s", id)
+	}
+	entry := cm.commands[index]
+	return &entry, nil
+}
+
+// Send 通过会话发送指令
+func (cm *CommandManager) Send(req command.CommandSendRequest) (*session.MessageRecord, error) {
+	entry, err := cm.Get(req.CommandID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.SessionID != "" && entry.SessionID != req.SessionID {
+		return nil, fmt.Errorf("指令 %s 不属于该会话", entry.Name)
+	}
+
+	sess, err := GlobalSessionManager.GetSession(req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := buildCommandPayload(sess, *entry)
+	if err != nil {
+		return nil, err
+	}
+	return GlobalSessionManager.SendBytes(req.SessionID, data, entry.IsHex, req.Target)
+}
+
+// Export 导出指令到JSON文件
+func (cm *CommandManager) Export(req command.CommandExportRequest) (*command.CommandExportResponse, error) {
+	cm.mutex.Lock()
+	entries := make([]command.CommandEntry, 0, len(cm.commands))
+	err := cm.load()
+	if err == nil {
+		folder := normalizeCommandFolder(req.Folder)
+		for _, entry := range cm.commands {
+			if folder != "" && !inCommandFolder(entry.Folder, folder) {
+				continue
+			}
+			if len(req.IDs) > 0 && !slices.Contains(req.IDs, entry.ID) {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+	cm.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	path := req.Path
+	if path == "" {
+		dir, err := AppDataDir("exports")
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, fmt.Sprintf("commands_%s.json", time.Now().Format("20060102_150405")))
+	}
+
+	data, err := json.MarshalIndent(command.CommandFile{Version: 1, Commands: entries}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化指令失败: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return nil, fmt.Errorf("写入导出文件失败: %v", err)
+	}
+
+	return &command.CommandExportResponse{Path: path, Count: len(entries)}, nil
+}
+
+// Import 从JSON文件导入指令，文件可以是导出的指令库或指令数组，绑定本机不存在会话的指令改为共享
+func (cm *CommandManager) Import(req command.CommandImportRequest) (*command.CommandImportResponse, error) {
+	data, err := os.ReadFile(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("读取导入文件失败: %v", err)
+	}
+
+	var entries []command.CommandEntry
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(data, &entries)
+	} else {
+		var file command.CommandFile
+		err = json.Unmarshal(data, &file)
+		entries = file.Commands
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析导入文件失败: %v", err)
+	}
+
+	for i := range entries {
+		entries[i].Folder = normalizeCommandFolder(entries[i].Folder)
+		// 绑定的会话在本机不存在时改为共享指令
+		if entries[i].SessionID != "" {
+			if _, err := GlobalSessionManager.GetSession(entries[i].SessionID); err != nil {
+				entries[i].SessionID = ""
+			}
+		}
+		if err := validateCommand(entries[i]); err != nil {
+			return nil, fmt.Errorf("第%d条指令(%s)无效: %v", i+1, entries[i].Name, err)
+		}
+	}
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if err := cm.load(); err != nil {
+		return nil, err
+	}
+
+	if req.Replace {
+		cm.commands = nil
+	}
+
+	now := time.Now().UnixMilli()
+	for _, entry := range entries {
+		if entry.CreatedAt == 0 {
+			entry.CreatedAt = now
+		}
+		if entry.UpdatedAt == 0 {
+			entry.UpdatedAt = now
+		}
+
+		if index := cm.indexOf(entry.ID); entry.ID != "" && index >= 0 {
+			cm.commands[index] = entry
+			continue
+		}
+		if entry.ID == "" {
+			entry.ID = cm.newID()
+		}
+		cm.commands = append(cm.commands, entry)
+	}
+
+	if err := GlobalConfigStore.SaveCommands(cm.commands); err != nil {
+		return nil, err
+	}
+	return &command.CommandImportResponse{Count: len(entries)}, nil
+}
+
+// load 首次使用时读取指令库
+func (cm *CommandManager) load() error {
+	if cm.loaded {
+		return nil
+	}
+
+	commands, err := GlobalConfigStore.LoadCommands()
+	if err != nil {
+		return err
+	}
+	cm.commands = commands
+	cm.loaded = true
+	return nil
+}
+
+// indexOf 查找指令位置，不存在时返回-1
+func (cm *CommandManager) indexOf(id string) int {
+	if id == "" {
+		return -1
+	}
+	for i, entry := range cm.commands {
+		if entry.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// newID 生成未被使用的指令ID
+func (cm *CommandManager) newID() string {
+	for n := time.Now().UnixNano(); ; n++ {
+		id := fmt.Sprintf("cmd_%d", n)
+		if cm.indexOf(id) < 0 {
+			return id
+		}
+	}
+}
+
+// validateCommand 校验指令内容
+func validateCommand(entry command.CommandEntry) error {
+	if strings.TrimSpace(entry.Name) == "" {
+		return fmt.Errorf("指令名称不能为空")
+	}
+	if _, err := DecodePayload(entry.Payload, entry.IsHex); err != nil {
+		return err
+	}
+	if _, err := lineEndingBytes(entry.LineEnding); err != nil {
+		return err
+	}
+	return ValidateChecksumConfig(entry.Checksum)
+}
+
+// buildCommandPayload 生成指令发送内容：指令内容+校验值+行尾
+func buildCommandPayload(sess *Session, entry command.CommandEntry) ([]byte, error) {
+	data, err := DecodePayload(entry.Payload, entry.IsHex)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Checksum != nil {
+		if checksumEnabled(entry.Checksum) {
+			data, err = AppendChecksum(entry.Checksum, data)
+		}
+	} else {
+		data, err = appendSessionChecksum(sess, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	suffix, err := lineEndingBytes(entry.LineEnding)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, suffix...), nil
+}
+
+// lineEndingBytes 行尾对应的字节
+func lineEndingBytes(lineEnding string) ([]byte, error) {
+	switch lineEnding {
+	case LineEndingNone:
+		return nil, nil
+	case LineEndingCR:
+		return []byte("\r"), nil
+	case LineEndingLF:
+		return []byte("\n"), nil
+	case LineEndingCRLF:
+		return []byte("\r\n"), nil
+	default:
+		return nil, fmt.Errorf("不支持的行尾: %s", lineEnding)
+	}
+}
+
+// normalizeCommandFolder 规范化分组路径，去除多余的/和空白
+func normalizeCommandFolder(folder string) string {
+	var parts []string
+	for _, part := range strings.Split(folder, "/") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// commandFolderParents 分组及其所有上级分组
+func commandFolderParents(folder string) []string {
+	var parents []string
+	for folder != "" {
+		parents = append(parents, folder)
+		index := strings.LastIndex(folder, "/")
+		if index < 0 {
+			break
+		}
+		folder = folder[:index]
+	}
+	return parents
+}
+
+// inCommandFolder 分组是否为指定分组或其子分组
+func inCommandFolder(folder, parent string) bool {
+	return folder == parent || strings.HasPrefix(folder, parent+"/")
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/zhoudm1743/Netser/dto/command"
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+const (
+	// 会话配置文件名
+	sessionsFileName = "sessions.json"
+	// 应用设置文件名
+	settingsFileName = "settings.json"
+	// 快捷指令库文件名
+	commandsFileName = "commands.json"
+)
+
+// ConfigStore 会话定义与应用设置的持久化存储
+type ConfigStore struct {
+	mutex sync.Mutex
+}
+
+var GlobalConfigStore = &ConfigStore{}
+
+// sessionsFile 会话配置文件内容
+type sessionsFile struct {
+	Version  int                   `json:"version"`
+	Sessions []session.SessionInfo `json:"sessions"`
+}
+
+// settingsFile 应用设置文件内容
+type settingsFile struct {
+	Retention session.RetentionPolicy `json:"retention"`
+}
+
+// SaveSessions 保存会话定义
+func (cs *ConfigStore) SaveSessions(infos []session.SessionInfo) error {
+	return cs.writeJSON(sessionsFileName, sessionsFile{Version: 1, Sessions: infos})
+}
+
+// LoadSessions 读取会话定义，文件不存在时返回空列表
+func (cs *ConfigStore) LoadSessions() ([]session.SessionInfo, error) {
+	var file sessionsFile
+	if err := cs.readJSON(sessionsFileName, &file); err != nil {
+		return nil, err
+	}
+	return file.Sessions, nil
+}
+
+// SaveCommands 保存快捷指令库
+func (cs *ConfigStore) SaveCommands(entries []command.CommandEntry) error {
+	return cs.writeJSON(commandsFileName, command.CommandFile{Version: 1, Commands: entries})
+}
+
+// LoadCommands 读取快捷指令库，文件不存在时返回空列表
+func (cs *ConfigStore) LoadCommands() ([]command.CommandEntry, error) {
+	var file command.CommandFile
+	if err := cs.readJSON(commandsFileName, &file); err != nil {
+		return nil, err
+	}
+	return file.Commands, nil
+}
+
+// GetRetentionPolicy 获取历史记录保留策略，默认全部保留
+func (cs *ConfigStore) GetRetentionPolicy() session.RetentionPolicy {
+	var file settingsFile
+	if err := cs.readJSON(settingsFileName, &file); err != nil {
+		return session.RetentionPolicy{}
+	}
+	return file.Retention
+}
+
+// SetRetentionPolicy 保存历史记录保留策略
+func (cs *ConfigStore) SetRetentionPolicy(policy session.RetentionPolicy) error {
+	var file settingsFile
+	if err := cs.readJSON(settingsFileName, &file); err != nil {
+		return err
+	}
+	file.Retention = policy
+	return cs.writeJSON(settingsFileName, file)
+}
+
+// readJSON 读取应用数据目录下的JSON文件，文件不存在时保持v不变
+func (cs *ConfigStore) readJSON(name string, v any) error {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	dir, err := AppDataDir()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("解析配置文件失败: %v", err)
+	}
+	return nil
+}
+
+// writeJSON 写入应用数据目录下的JSON文件，先写临时文件再替换避免写入中断损坏配置
+func (cs *ConfigStore) writeJSON(name string, v any) error {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	dir, err := AppDataDir()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %v", err)
+	}
+
+	path := filepath.Join(dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("写入配置文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("保存配置文件失败: %v", err)
+	}
+	return nil
+}
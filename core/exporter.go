@@ -0,0 +1,369 @@
+package core
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+// 导出格式
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatJSONL   = "jsonl"
+	ExportFormatText    = "text"
+	ExportFormatHexdump = "hexdump"
+	ExportFormatPcapng  = "pcapng"
+)
+
+// 导出文件中的时间格式
+const exportTimeLayout = "2006-01-02 15:04:05.000"
+
+// messageWriter 按格式写出单条消息
+type messageWriter interface {
+	WriteRecord(record session.MessageRecord) error
+	Flush() error
+}
+
+// ExportMessages 将会话历史记录按格式流式导出到文件
+func ExportMessages(req session.SessionExportRequest) (*session.SessionExportResponse, error) {
+	if GlobalMessageDBManager == nil {
+		return nil, fmt.Errorf("消息数据库管理器未初始化")
+	}
+	sess, err := GlobalSessionManager.GetSession(req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ext, err := exportExtension(req.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	path := req.Path
+	if path == "" {
+		dir, err := AppDataDir("exports")
+		if err != nil {
+			return nil, err
+		}
+		name := fmt.Sprintf("%s_%s%s", req.SessionID, time.Now().Format("20060102_150405"), ext)
+		path = filepath.Join(dir, name)
+	}
+
+	db, err := GlobalMessageDBManager.GetOrCreateMessageDB(req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库失败: %v", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建导出文件失败: %v", err)
+	}
+
+	buffered := bufio.NewWriter(file)
+	writer := newMessageWriter(req.Format, buffered, sess.Info)
+
+	count := 0
+	err = db.ForEachMessage(func(record session.MessageRecord) error {
+		if !matchMessageFilter(record, req.MessageFilter) {
+			return nil
+		}
+		count++
+		return writer.WriteRecord(RenderRecord(record, PayloadFormatBoth))
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("导出消息失败: %v", err)
+	}
+
+	log.Printf("会话 %s 已导出 %d 条消息到 %s", req.SessionID, count, path)
+	return &session.SessionExportResponse{Path: path, Count: count}, nil
+}
+
+// matchMessageFilter 判断消息是否满足过滤条件
+func matchMessageFilter(record session.MessageRecord, filter session.MessageFilter) bool {
+	if filter.StartTime > 0 && record.Timestamp < filter.StartTime {
+		return false
+	}
+	if filter.EndTime > 0 && record.Timestamp > filter.EndTime {
+		return false
+	}
+	if filter.Direction != "" && record.Direction != filter.Direction {
+		return false
+	}
+	return true
+}
+
+// exportExtension 导出格式对应的文件扩展名
+func exportExtension(format string) (string, error) {
+	switch format {
+	case ExportFormatCSV:
+		return ".csv", nil
+	case ExportFormatJSONL:
+		return ".jsonl", nil
+	case ExportFormatText:
+		return ".log", nil
+	case ExportFormatHexdump:
+		return ".txt", nil
+	case ExportFormatPcapng:
+		return ".pcapng", nil
+	default:
+		return "", fmt.Errorf("不支持的导出格式: %s", format)
+	}
+}
+
+// newMessageWriter 创建对应格式的写出器，格式需先经exportExtension校验
+func newMessageWriter(format string, w io.Writer, info session.SessionInfo) messageWriter {
+	switch format {
+	case ExportFormatCSV:
+		return newCSVMessageWriter(w)
+	case ExportFormatJSONL:
+		return &jsonlMessageWriter{encoder: json.NewEncoder(w)}
+	case ExportFormatHexdump:
+		return &hexdumpMessageWriter{w: w}
+	case ExportFormatPcapng:
+		return newPcapngMessageWriter(w, info)
+	default:
+		return &textMessageWriter{w: w}
+	}
+}
+
+// formatExportTime 格式化消息时间
+func formatExportTime(timestamp int64) string {
+	return time.UnixMilli(timestamp).Format(exportTimeLayout)
+}
+
+// csvMessageWriter CSV格式
+type csvMessageWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+func newCSVMessageWriter(w io.Writer) *csvMessageWriter {
+	return &csvMessageWriter{writer: csv.NewWriter(w)}
+}
+
+func (cw *csvMessageWriter) WriteRecord(record session.MessageRecord) error {
+	if !cw.header {
+		cw.header = true
+		err := cw.writer.Write([]string{"timestamp", "time", "direction", "remote_addr", "length", "hex", "text", "checksum", "rule", "transfer"})
+		if err != nil {
+			return err
+		}
+	}
+
+	// 文件传输汇总记录在transfer列写入汇总JSON，导入时据此跳过
+	transfer := ""
+	if record.Transfer != nil {
+		data, err := json.Marshal(record.Transfer)
+		if err != nil {
+			return err
+		}
+		transfer = string(data)
+	}
+
+	return cw.writer.Write([]string{
+		strconv.FormatInt(record.Timestamp, 10),
+		formatExportTime(record.Timestamp),
+		record.Direction,
+		record.RemoteAddr,
+		strconv.Itoa(record.ByteLength),
+		record.Hex,
+		record.Text,
+		record.ChecksumStatus,
+		record.Rule,
+		transfer,
+	})
+}
+
+func (cw *csvMessageWriter) Flush() error {
+	cw.writer.Flush()
+	return cw.writer.Error()
+}
+
+// jsonlMessageWriter JSON Lines格式，每行一条消息
+type jsonlMessageWriter struct {
+	encoder *json.Encoder
+}
+
+// jsonlRecord JSON Lines导出的消息结构
+type jsonlRecord struct {
+	Timestamp      int64  `json:"timestamp"`
+	Time           string `json:"time"`
+	Direction      string `json:"direction"`
+	RemoteAddr     string `json:"remoteAddr,omitempty"`
+	ByteLength     int    `json:"byteLength"`
+	Hex            string `json:"hex"`
+	Text           string `json:"text"`
+	ChecksumStatus string `json:"checksumStatus,omitempty"`
+	Rule           string `json:"rule,omitempty"`
+
+	Transfer *session.TransferSummary `json:"transfer,omitempty"` // 文件传输汇总，导入时跳过
+}
+
+func (jw *jsonlMessageWriter) WriteRecord(record session.MessageRecord) error {
+	return jw.encoder.Encode(jsonlRecord{
+		Timestamp:      record.Timestamp,
+		Time:           formatExportTime(record.Timestamp),
+		Direction:      record.Direction,
+		RemoteAddr:     record.RemoteAddr,
+		ByteLength:     record.ByteLength,
+		Hex:            record.Hex,
+		Text:           record.Text,
+		ChecksumStatus: record.ChecksumStatus,
+		Rule:           record.Rule,
+		Transfer:       record.Transfer,
+	})
+}
+
+func (jw *jsonlMessageWriter) Flush() error {
+	return nil
+}
+
+// textMessageWriter 可读的文本日志格式
+type textMessageWriter struct {
+	w io.Writer
+}
+
+func (tw *textMessageWriter) WriteRecord(record session.MessageRecord) error {
+	_, err := fmt.Fprintf(tw.w, "[%s] %s%s%s (%d字节)%s%s: %s\n",
+		formatExportTime(record.Timestamp),
+		exportDirectionLabel(record.Direction),
+		exportTransferLabel(record.Transfer),
+		exportRemoteLabel(record.RemoteAddr),
+		record.ByteLength,
+		exportChecksumLabel(record.ChecksumStatus),
+		exportRuleLabel(record.Rule),
+		textLogPayload(record))
+	return err
+}
+
+// textLogEscaper 转义控制字符，保证每条消息占一行
+var textLogEscaper = strings.NewReplacer("\r", "\\r", "\n", "\\n", "\t", "\\t")
+
+// textLogPayload 文本日志中的数据内容，十六进制记录带HEX前缀
+func textLogPayload(record session.MessageRecord) string {
+	if record.IsHex {
+		return "HEX " + record.Data
+	}
+	return textLogEscaper.Replace(record.Data)
+}
+
+func (tw *textMessageWriter) Flush() error {
+	return nil
+}
+
+// hexdumpMessageWriter hexdump -C 风格，每条消息一个块
+type hexdumpMessageWriter struct {
+	w io.Writer
+}
+
+func (hw *hexdumpMessageWriter) WriteRecord(record session.MessageRecord) error {
+	// 文件传输汇总记录不是实际收发的数据
+	if record.Transfer != nil {
+		return nil
+	}
+
+	raw, err := recordRaw(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(hw.w, "# [%s] %s%s (%d字节)%s%s\n",
+		formatExportTime(record.Timestamp),
+		exportDirectionLabel(record.Direction),
+		exportRemoteLabel(record.RemoteAddr),
+		len(raw),
+		exportChecksumLabel(record.ChecksumStatus),
+		exportRuleLabel(record.Rule))
+	if err != nil {
+		return err
+	}
+
+	dumper := hex.Dumper(hw.w)
+	if _, err := dumper.Write(raw); err != nil {
+		return err
+	}
+	if err := dumper.Close(); err != nil {
+		return err
+	}
+
+	// 与hexdump -C一致，以总长度偏移结尾
+	_, err = fmt.Fprintf(hw.w, "%08x\n\n", len(raw))
+	return err
+}
+
+func (hw *hexdumpMessageWriter) Flush() error {
+	return nil
+}
+
+// recordRaw 获取消息的原始字节，兼容未保存原始字节的旧记录
+func recordRaw(record session.MessageRecord) ([]byte, error) {
+	if record.Raw != nil {
+		return record.Raw, nil
+	}
+	return DecodePayload(record.Data, record.IsHex)
+}
+
+// exportDirectionLabel 方向标签
+func exportDirectionLabel(direction string) string {
+	switch direction {
+	case "send":
+		return "TX"
+	case "receive":
+		return "RX"
+	default:
+		return strings.ToUpper(direction)
+	}
+}
+
+// exportTransferLabel 文件传输汇总标签
+func exportTransferLabel(transfer *session.TransferSummary) string {
+	if transfer == nil {
+		return ""
+	}
+	return " [文件传输汇总]"
+}
+
+// exportRemoteLabel 对端地址标签
+func exportRemoteLabel(remoteAddr string) string {
+	if remoteAddr == "" {
+		return ""
+	}
+	return " " + remoteAddr
+}
+
+// exportRuleLabel 自动应答规则标签
+func exportRuleLabel(rule string) string {
+	if rule == "" {
+		return ""
+	}
+	return " [自动应答:" + rule + "]"
+}
+
+// exportChecksumLabel 校验结果标签
+func exportChecksumLabel(status string) string {
+	if status == ChecksumStatusMismatch {
+		return " [校验错误]"
+	}
+	return ""
+}
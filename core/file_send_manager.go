@@ -0,0 +1,348 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+const (
+	// 默认每块字节数
+	defaultFileChunkSize = 1024
+	// 最大每块字节数
+	maxFileChunkSize = 1 << 20
+	// 等待应答默认超时（毫秒）
+	defaultFileAckTimeout = 1000
+	// 发送进度推送最小间隔
+	fileSendNotifyInterval = 200 * time.Millisecond
+)
+
+// 文件传输结果
+const (
+	TransferCompleted = "completed"
+	TransferCanceled  = "canceled"
+	TransferFailed    = "failed"
+)
+
+// FileSendManager 发送文件管理器
+type FileSendManager struct{}
+
+var GlobalFileSendManager = &FileSendManager{}
+
+// fileSendTasks 会话上正在进行的文件发送
+var fileSendTasks = &taskSlot[*sessionTask, session.FileSendState]{
+	task:  func(sess *Session) **sessionTask { return &sess.fileSend },
+	state: func(sess *Session) **session.FileSendState { return &sess.Info.FileSend },
+	halt: func(state *session.FileSendState) {
+		state.Running = false
+		state.Canceled = true
+	},
+}
+
+// Start 开始通过会话发送文件，已在发送时替换为新的发送
+func (fm *FileSendManager) Start(sessionID string, cfg session.FileSendConfig) (*session.FileSendState, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch sess.Info.Type {
+	case "tcpClient", "tcpServer", "tlsClient", "tlsServer", "serial":
+	default:
+		return nil, fmt.Errorf("发送文件仅支持TCP和串口会话")
+	}
+	if isServerSession(sess) {
+		if cfg.Target != "" && sess.getPeer(cfg.Target) == nil {
+			return nil, fmt.Errorf("客户端不存在: %s", cfg.Target)
+		}
+		if len(sess.listPeers()) == 0 {
+			return nil, fmt.Errorf("没有已连接的客户端")
+		}
+	} else if sess.Connection == nil {
+		return nil, fmt.Errorf("连接未建立")
+	}
+
+	sess.mutex.RLock()
+	transferring := sess.xmodem != nil
+	sess.mutex.RUnlock()
+	if transferring {
+		return nil, fmt.Errorf("会话正在进行XMODEM/YMODEM传输")
+	}
+
+	if cfg.ChunkSize == 0 {
+		cfg.ChunkSize = defaultFileChunkSize
+	}
+	if cfg.ChunkSize < 0 || cfg.ChunkSize > maxFileChunkSize {
+		return nil, fmt.Errorf("每块字节数必须在1到%d之间", maxFileChunkSize)
+	}
+	if cfg.Delay < 0 {
+		return nil, fmt.Errorf("块间延迟不能为负数")
+	}
+	if cfg.AckTimeout < 0 {
+		return nil, fmt.Errorf("应答超时不能为负数")
+	}
+	if cfg.AckTimeout == 0 {
+		cfg.AckTimeout = defaultFileAckTimeout
+	}
+
+	var ack []byte
+	if cfg.Ack != "" {
+		ack, err = DecodePayload(cfg.Ack, true)
+		if err != nil {
+			return nil, fmt.Errorf("应答字节格式错误: %v", err)
+		}
+	}
+
+	file, err := os.Open(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %v", err)
+	}
+	info, err := file.Stat()
+	if err == nil && info.IsDir() {
+		err = fmt.Errorf("%s 是目录", cfg.Path)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("读取文件信息失败: %v", err)
+	}
+
+	state := &session.FileSendState{
+		FileSendConfig: cfg,
+		Running:        true,
+		Size:           info.Size(),
+		StartTime:      time.Now().UnixMilli(),
+	}
+	task := newSessionTask()
+	fileSendTasks.start(sess, &task, state)
+
+	log.Printf("会话 %s 开始发送文件 %s，%d 字节，每块 %d 字节", sessionID, cfg.Path, state.Size, cfg.ChunkSize)
+	go fm.run(sess, &task, file, ack, *state)
+
+	return state, nil
+}
+
+// Stop 取消会话正在进行的文件发送
+func (fm *FileSendManager) Stop(sessionID string) (*session.FileSendState, error) {
+	return fileSendTasks.stop(sessionID)
+}
+
+// GetState 获取会话的文件发送状态，未发送过时返回空状态
+func (fm *FileSendManager) GetState(sessionID string) (*session.FileSendState, error) {
+	return fileSendTasks.get(sessionID)
+}
+
+// run 发送循环，结束时在历史中写入一条汇总记录
+func (fm *FileSendManager) run(sess *Session, task *sessionTask, file *os.File, ack []byte, state session.FileSendState) {
+	sessionID := sess.Info.SessionID
+	start := time.Now()
+
+	defer func() {
+		file.Close()
+
+		state.Running = false
+		state.EndTime = time.Now().UnixMilli()
+		state.Throughput = transferThroughput(state.Sent, time.Since(start))
+		fileSendTasks.finish(sess, task, state)
+
+		log.Printf("会话 %s 文件发送结束，已发送 %d/%d 字节，%d 块", sessionID, state.Sent, state.Size, state.Chunks)
+		record := sess.addTransferRecord("send", state.Target, session.TransferSummary{
+			File:       filepath.Base(state.Path),
+			Size:       state.Size,
+			Sent:       state.Sent,
+			Chunks:     state.Chunks,
+			Duration:   time.Since(start).Milliseconds(),
+			Throughput: state.Throughput,
+			Status:     transferStatus(state.Canceled, state.LastError),
+			Error:      state.LastError,
+		})
+		if GlobalWebSocketManager != nil {
+			GlobalWebSocketManager.NotifyMessageRecord(sessionID, record)
+			GlobalWebSocketManager.NotifyFileSend(sessionID, state)
+		}
+	}()
+
+	var collector *receiveCollector
+	if ack != nil {
+		collector = newReceiveCollector()
+		unsubscribe := sess.observe(collector.observe)
+		defer unsubscribe()
+	}
+
+	buffer := make([]byte, state.ChunkSize)
+	lastNotify := start
+	for {
+		select {
+		case <-task.stop:
+			state.Canceled = true
+			return
+		default:
+		}
+
+		n, err := io.ReadFull(file, buffer)
+		if n == 0 {
+			if err != io.EOF {
+				state.LastError = fmt.Sprintf("读取文件失败: %v", err)
+			}
+			return
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			state.LastError = fmt.Sprintf("读取文件失败: %v", err)
+			return
+		}
+
+		if collector != nil {
+			collector.reset()
+		}
+		if err := sess.writeRaw(buffer[:n], state.Target); err != nil {
+			state.LastError = err.Error()
+			return
+		}
+		state.Sent += int64(n)
+		state.Chunks++
+
+		if collector != nil {
+			timeout := time.Duration(state.AckTimeout) * time.Millisecond
+			_, ok, stopped := collector.waitUntil(func(received []byte) bool {
+				return bytes.Contains(received, ack)
+			}, timeout, task.stop)
+			if stopped {
+				state.Canceled = true
+				return
+			}
+			if !ok {
+				state.LastError = fmt.Sprintf("等待第%d块应答超时", state.Chunks)
+				return
+			}
+		}
+
+		// 按间隔推送进度
+		if time.Since(lastNotify) >= fileSendNotifyInterval {
+			lastNotify = time.Now()
+			state.Throughput = transferThroughput(state.Sent, time.Since(start))
+			fileSendTasks.publish(sess, task, state)
+			if GlobalWebSocketManager != nil {
+				GlobalWebSocketManager.NotifyFileSend(sessionID, state)
+			}
+		}
+
+		if state.Delay > 0 && state.Sent < state.Size {
+			timer := time.NewTimer(time.Duration(state.Delay) * time.Millisecond)
+			select {
+			case <-task.stop:
+				timer.Stop()
+				state.Canceled = true
+				return
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// writeRaw 直接写入会话连接，不记录消息；服务端未指定target时发送给全部客户端
+func (s *Session) writeRaw(data []byte, target string) error {
+	if s.Info.Type == "serial" {
+		if s.Connection == nil {
+			return fmt.Errorf("串口未连接")
+		}
+		if err := writeSerial(s, data); err != nil {
+			return fmt.Errorf("发送数据失败: %v", err)
+		}
+		return nil
+	}
+
+	if !isServerSession(s) {
+		if s.Connection == nil {
+			return fmt.Errorf("连接未建立")
+		}
+		if _, err := s.Connection.Write(data); err != nil {
+			return fmt.Errorf("发送数据失败: %v", err)
+		}
+		return nil
+	}
+
+	var peers []*tcpPeer
+	if target != "" {
+		peer := s.getPeer(target)
+		if peer == nil {
+			return fmt.Errorf("客户端不存在: %s", target)
+		}
+		peers = append(peers, peer)
+	} else {
+		peers = s.listPeers()
+	}
+	if len(peers) == 0 {
+		return fmt.Errorf("没有已连接的客户端")
+	}
+
+	for _, peer := range peers {
+		if _, err := peer.Conn.Write(data); err != nil {
+			return fmt.Errorf("发送数据到 %s 失败: %v", peer.Address, err)
+		}
+	}
+	return nil
+}
+
+// addTransferRecord 在历史中记录文件传输汇总
+func (s *Session) addTransferRecord(direction, target string, summary session.TransferSummary) session.MessageRecord {
+	label := "发送文件"
+	if direction == "receive" {
+		label = "接收文件"
+	}
+	if summary.Protocol != "" {
+		label += "/" + strings.ToUpper(summary.Protocol)
+	}
+
+	result := "完成"
+	switch summary.Status {
+	case TransferFailed:
+		result = "失败: " + summary.Error
+	case TransferCanceled:
+		result = "已取消"
+	}
+
+	text := fmt.Sprintf("[%s] %s 已传输 %d/%d 字节，%d 块，耗时 %.1fs，%.1f KB/s，%s",
+		label, summary.File, summary.Sent, summary.Size, summary.Chunks,
+		float64(summary.Duration)/1000, summary.Throughput/1024, result)
+
+	record := session.MessageRecord{
+		Direction:  direction,
+		Timestamp:  time.Now().UnixMilli(),
+		RemoteAddr: target,
+		LocalAddr:  s.localAddr(target),
+		Raw:        []byte(text),
+		Transfer:   &summary,
+	}
+	record = RenderRecord(record, PayloadFormatAuto)
+
+	if err := StoreMessageToDB(s.Info.SessionID, record); err != nil {
+		log.Printf("存储文件传输记录失败: %v", err)
+	}
+	return record
+}
+
+// transferStatus 文件传输结果
+func transferStatus(canceled bool, lastError string) string {
+	switch {
+	case lastError != "":
+		return TransferFailed
+	case canceled:
+		return TransferCanceled
+	default:
+		return TransferCompleted
+	}
+}
+
+// transferThroughput 平均速率（字节/秒）
+func transferThroughput(sent int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(sent) / elapsed.Seconds()
+}
@@ -0,0 +1,388 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+// 分帧模式
+const (
+	FramingNone        = "none"        // 不分帧，按每次读取的数据记录
+	FramingDelimiter   = "delimiter"   // 分隔符
+	FramingFixed       = "fixed"       // 固定长度
+	FramingLengthField = "lengthField" // 长度字段前缀
+	FramingSTXETX      = "stxEtx"      // 起止符
+	FramingIdle        = "idle"        // 空闲超时间隔
+)
+
+const (
+	// 默认最大帧长度，缓冲超过该长度仍未成帧时强制输出
+	defaultMaxFrameLength = 64 * 1024
+	// 空闲模式默认间隔（毫秒）
+	defaultIdleTimeout = 50
+)
+
+// Framer 分帧器，将字节流切分为逻辑帧
+type Framer interface {
+	// Push 追加数据，返回已完整的帧
+	Push(data []byte) [][]byte
+	// Flush 取出缓冲中剩余的数据
+	Flush() []byte
+}
+
+// NewFramer 根据配置创建分帧器，配置为空或none时返回nil
+func NewFramer(cfg *session.FramingConfig) (Framer, error) {
+	if cfg == nil || cfg.Mode == "" || cfg.Mode == FramingNone {
+		return nil, nil
+	}
+
+	maxLength := cfg.MaxFrameLength
+	if maxLength <= 0 {
+		maxLength = defaultMaxFrameLength
+	}
+	base := frameBuffer{maxLength: maxLength}
+
+	switch cfg.Mode {
+	case FramingDelimiter:
+		delimiter, err := parseFramingBytes(cfg.Delimiter, cfg.DelimiterIsHex)
+		if err != nil {
+			return nil, fmt.Errorf("分隔符格式错误: %v", err)
+		}
+		if len(delimiter) == 0 {
+			return nil, fmt.Errorf("分隔符不能为空")
+		}
+		return &delimiterFramer{frameBuffer: base, delimiter: delimiter, keep: cfg.KeepDelimiter}, nil
+
+	case FramingFixed:
+		if cfg.FixedLength <= 0 {
+			return nil, fmt.Errorf("固定长度必须大于0")
+		}
+		return &fixedFramer{frameBuffer: base, length: cfg.FixedLength}, nil
+
+	case FramingLengthField:
+		switch cfg.LengthSize {
+		case 1, 2, 4:
+		default:
+			return nil, fmt.Errorf("长度字段大小只能为1、2或4字节")
+		}
+		if cfg.LengthOffset < 0 {
+			return nil, fmt.Errorf("长度字段偏移不能为负数")
+		}
+		var order binary.ByteOrder = binary.BigEndian
+		if cfg.LittleEndian {
+			order = binary.LittleEndian
+		}
+		return &lengthFieldFramer{
+			frameBuffer: base,
+			offset:      cfg.LengthOffset,
+			size:        cfg.LengthSize,
+			adjustment:  cfg.LengthAdjustment,
+			order:       order,
+		}, nil
+
+	case FramingSTXETX:
+		stx, err := parseFramingBytes(defaultString(cfg.STX, "02"), true)
+		if err != nil || len(stx) == 0 {
+			return nil, fmt.Errorf("起始符格式错误")
+		}
+		etx, err := parseFramingBytes(defaultString(cfg.ETX, "03"), true)
+		if err != nil || len(etx) == 0 {
+			return nil, fmt.Errorf("结束符格式错误")
+		}
+		return &stxEtxFramer{frameBuffer: base, stx: stx, etx: etx}, nil
+
+	case FramingIdle:
+		return &idleFramer{frameBuffer: base}, nil
+
+	default:
+		return nil, fmt.Errorf("不支持的分帧模式: %s", cfg.Mode)
+	}
+}
+
+// frameBuffer 分帧缓冲
+type frameBuffer struct {
+	buffer    []byte
+	maxLength int
+}
+
+// Flush 取出缓冲中剩余的数据
+func (fb *frameBuffer) Flush() []byte {
+	if len(fb.buffer) == 0 {
+		return nil
+	}
+	data := fb.buffer
+	fb.buffer = nil
+	return data
+}
+
+// take 从缓冲头部取出n字节
+func (fb *frameBuffer) take(n int) []byte {
+	frame := append([]byte(nil), fb.buffer[:n]...)
+	fb.buffer = fb.buffer[n:]
+	return frame
+}
+
+// overflow 缓冲超过最大帧长度时强制输出
+func (fb *frameBuffer) overflow(frames [][]byte) [][]byte {
+	for len(fb.buffer) > fb.maxLength {
+		frames = append(frames, fb.take(fb.maxLength))
+	}
+	return frames
+}
+
+// delimiterFramer 分隔符分帧
+type delimiterFramer struct {
+	frameBuffer
+	delimiter []byte
+	keep      bool
+}
+
+func (f *delimiterFramer) Push(data []byte) [][]byte {
+	f.buffer = append(f.buffer, data...)
+
+	var frames [][]byte
+	for {
+		index := bytes.Index(f.buffer, f.delimiter)
+		if index < 0 {
+			break
+		}
+		end := index + len(f.delimiter)
+		frame := f.take(end)
+		if !f.keep {
+			frame = frame[:index]
+		}
+		frames = append(frames, frame)
+	}
+	return f.overflow(frames)
+}
+
+// fixedFramer 固定长度分帧
+type fixedFramer struct {
+	frameBuffer
+	length int
+}
+
+func (f *fixedFramer) Push(data []byte) [][]byte {
+	f.buffer = append(f.buffer, data...)
+
+	var frames [][]byte
+	for len(f.buffer) >= f.length {
+		frames = append(frames, f.take(f.length))
+	}
+	return frames
+}
+
+// lengthFieldFramer 长度字段前缀分帧
+// 帧长度 = 偏移 + 字段大小 + 字段值 + 调整值
+type lengthFieldFramer struct {
+	frameBuffer
+	offset     int
+	size       int
+	adjustment int
+	order      binary.ByteOrder
+}
+
+func (f *lengthFieldFramer) Push(data []byte) [][]byte {
+	f.buffer = append(f.buffer, data...)
+
+	var frames [][]byte
+	header := f.offset + f.size
+	for len(f.buffer) >= header {
+		field := f.buffer[f.offset:header]
+
+		var value int
+		switch f.size {
+		case 1:
+			value = int(field[0])
+		case 2:
+			value = int(f.order.Uint16(field))
+		case 4:
+			value = int(f.order.Uint32(field))
+		}
+
+		frameLength := header + value + f.adjustment
+		if frameLength < header || frameLength > f.maxLength {
+			// 长度非法，无法再同步，输出缓冲内容
+			frames = append(frames, f.take(len(f.buffer)))
+			break
+		}
+		if len(f.buffer) < frameLength {
+			break
+		}
+		frames = append(frames, f.take(frameLength))
+	}
+	return frames
+}
+
+// stxEtxFramer 起止符分帧，起始符之前的数据单独输出
+type stxEtxFramer struct {
+	frameBuffer
+	stx []byte
+	etx []byte
+}
+
+func (f *stxEtxFramer) Push(data []byte) [][]byte {
+	f.buffer = append(f.buffer, data...)
+
+	var frames [][]byte
+	for len(f.buffer) > 0 {
+		start := bytes.Index(f.buffer, f.stx)
+		if start < 0 {
+			// 保留可能是起始符前缀的尾部字节
+			keep := len(f.stx) - 1
+			if len(f.buffer) > keep {
+				frames = append(frames, f.take(len(f.buffer)-keep))
+			}
+			break
+		}
+		if start > 0 {
+			frames = append(frames, f.take(start))
+		}
+
+		end := bytes.Index(f.buffer[len(f.stx):], f.etx)
+		if end < 0 {
+			break
+		}
+		frames = append(frames, f.take(len(f.stx)+end+len(f.etx)))
+	}
+	return f.overflow(frames)
+}
+
+// idleFramer 空闲超时分帧，数据全部缓冲，由超时触发Flush
+type idleFramer struct {
+	frameBuffer
+}
+
+func (f *idleFramer) Push(data []byte) [][]byte {
+	f.buffer = append(f.buffer, data...)
+	return f.overflow(nil)
+}
+
+// frameAssembler 将分帧器与空闲定时器组合，在接收协程中使用
+type frameAssembler struct {
+	cfg     *session.FramingConfig
+	framer  Framer
+	timeout time.Duration // 剩余数据的空闲输出时间，0表示不超时
+	emit    func([]byte)
+	timer   *time.Timer
+	mutex   sync.Mutex
+}
+
+// newFrameAssembler 创建帧组装器，emit在每个完整帧时调用
+func newFrameAssembler(cfg *session.FramingConfig, emit func([]byte)) *frameAssembler {
+	framer, err := NewFramer(cfg)
+	if err != nil {
+		log.Printf("分帧配置无效，按不分帧处理: %v", err)
+		framer = nil
+	}
+
+	fa := &frameAssembler{cfg: cfg, framer: framer, emit: emit}
+	if framer != nil {
+		timeout := cfg.IdleTimeout
+		if cfg.Mode == FramingIdle && timeout <= 0 {
+			timeout = defaultIdleTimeout
+		}
+		fa.timeout = time.Duration(timeout) * time.Millisecond
+	}
+	return fa
+}
+
+// newSessionAssembler 创建记录会话接收消息的帧组装器
+func newSessionAssembler(sess *Session, remoteAddr string) *frameAssembler {
+	return newFrameAssembler(sess.Info.Framing, func(frame []byte) {
+		record := sess.AddRawMessage("receive", frame, sess.Info.IsHex, remoteAddr)
+
+		if GlobalWebSocketManager != nil {
+			GlobalWebSocketManager.NotifyMessageRecord(sess.Info.SessionID, record)
+		}
+
+		sess.respond(record)
+		sess.serveModbus(record)
+	})
+}
+
+// sync 会话分帧配置变更时输出残留数据并重建组装器
+func (fa *frameAssembler) sync(sess *Session, remoteAddr string) *frameAssembler {
+	if fa.cfg == sess.Info.Framing {
+		return fa
+	}
+	fa.Close()
+	return newSessionAssembler(sess, remoteAddr)
+}
+
+// Write 处理一次读取到的数据
+func (fa *frameAssembler) Write(data []byte) {
+	if fa.framer == nil {
+		fa.emit(append([]byte(nil), data...))
+		return
+	}
+
+	// 持锁输出，保证与超时输出的顺序一致
+	fa.mutex.Lock()
+	defer fa.mutex.Unlock()
+
+	for _, frame := range fa.framer.Push(data) {
+		fa.emit(frame)
+	}
+
+	if fa.timeout > 0 {
+		if fa.timer == nil {
+			fa.timer = time.AfterFunc(fa.timeout, fa.flush)
+		} else {
+			fa.timer.Reset(fa.timeout)
+		}
+	}
+}
+
+// Close 输出剩余数据并停止定时器
+func (fa *frameAssembler) Close() {
+	if fa.framer == nil {
+		return
+	}
+
+	fa.mutex.Lock()
+	if fa.timer != nil {
+		fa.timer.Stop()
+	}
+	fa.mutex.Unlock()
+
+	fa.flush()
+}
+
+// flush 输出缓冲中剩余的数据
+func (fa *frameAssembler) flush() {
+	fa.mutex.Lock()
+	defer fa.mutex.Unlock()
+
+	if data := fa.framer.Flush(); len(data) > 0 {
+		fa.emit(data)
+	}
+}
+
+// parseFramingBytes 解析分帧字节序列，文本模式支持\r\n等转义
+func parseFramingBytes(value string, isHex bool) ([]byte, error) {
+	if isHex {
+		return DecodePayload(value, true)
+	}
+
+	unquoted, err := strconv.Unquote(`"` + value + `"`)
+	if err != nil {
+		return []byte(value), nil
+	}
+	return []byte(unquoted), nil
+}
+
+// defaultString 为空时返回默认值
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,536 @@
+package core
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+// 抓包文件中单个报文的最大长度，超出时视为文件损坏，避免按文件中的长度分配过大内存
+const (
+	maxCaptureRecord = 256 * 1024
+	// pcapng报文块除报文外的块头和选项的最大长度
+	pcapngBlockOverhead = 64 * 1024
+)
+
+// 支持解析负载的链路类型
+const (
+	linkTypeNull   = 0
+	linkTypeRaw    = 101
+	linkTypeSLL    = 113
+	linkTypeUser15 = 162
+)
+
+// ImportMessages 导入消息记录，支持本程序导出的CSV/JSONL及pcap/pcapng抓包文件
+func ImportMessages(path string) ([]session.MessageRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开导入文件失败: %v", err)
+	}
+	defer file.Close()
+
+	var records []session.MessageRecord
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		records, err = importCSV(file)
+	case ".jsonl":
+		records, err = importJSONL(file)
+	case ".pcap", ".pcapng", ".cap":
+		records, err = importCapture(file)
+	default:
+		return nil, fmt.Errorf("不支持的导入文件类型: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析导入文件失败: %v", err)
+	}
+	return records, nil
+}
+
+// importCSV 按表头解析CSV导出文件，需要timestamp、direction、hex列，跳过transfer列不为空的文件传输汇总记录
+func importCSV(r io.Reader) ([]session.MessageRecord, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range []string{"timestamp", "direction", "hex"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("缺少列: %s", name)
+		}
+	}
+
+	var records []session.MessageRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := columns["transfer"]; ok && row[i] != "" {
+			continue
+		}
+
+		timestamp, err := strconv.ParseInt(row[columns["timestamp"]], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("时间戳格式错误: %v", err)
+		}
+		record, err := importedRecord(timestamp, row[columns["direction"]], row[columns["hex"]])
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+// importJSONL 解析JSON Lines导出文件，跳过文件传输汇总记录
+func importJSONL(r io.Reader) ([]session.MessageRecord, error) {
+	var records []session.MessageRecord
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var item jsonlRecord
+		if err := json.Unmarshal([]byte(line), &item); err != nil {
+			return nil, err
+		}
+		if item.Transfer != nil {
+			continue
+		}
+		record, err := importedRecord(item.Timestamp, item.Direction, item.Hex)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// importedRecord 由导出文件中的字段构造消息记录
+func importedRecord(timestamp int64, direction, hexData string) (session.MessageRecord, error) {
+	if direction != "send" && direction != "receive" {
+		return session.MessageRecord{}, fmt.Errorf("未知的消息方向: %s", direction)
+	}
+	raw, err := DecodePayload(hexData, true)
+	if err != nil {
+		return session.MessageRecord{}, err
+	}
+	return session.MessageRecord{
+		Direction:  direction,
+		Timestamp:  timestamp,
+		ByteLength: len(raw),
+		IsHex:      true,
+		Raw:        raw,
+	}, nil
+}
+
+// capturedPacket 抓包文件中的一个报文
+type capturedPacket struct {
+	Timestamp int64  // 毫秒
+	LinkType  uint16 // 链路类型
+	Data      []byte
+	Direction string // 由epb_flags或注释得到，未知时为空
+}
+
+// importCapture 解析pcap/pcapng文件，提取TCP/UDP负载或用户DLT数据
+func importCapture(r io.Reader) ([]session.MessageRecord, error) {
+	reader := bufio.NewReader(r)
+	magic, err := reader.Peek(4)
+	if err != nil {
+		return nil, err
+	}
+
+	var packets []capturedPacket
+	if binary.LittleEndian.Uint32(magic) == pcapngBlockSHB {
+		packets, err = readPcapng(reader)
+	} else {
+		packets, err = readPcap(reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 没有方向信息的报文，以首个报文的发送方作为本端
+	var localKey string
+	var records []session.MessageRecord
+	for _, packet := range packets {
+		payload, srcKey, err := packetPayload(packet.LinkType, packet.Data)
+		if err != nil {
+			return nil, err
+		}
+		if len(payload) == 0 {
+			continue
+		}
+
+		direction := packet.Direction
+		if direction == "" {
+			if localKey == "" {
+				localKey = srcKey
+			}
+			direction = "send"
+			if srcKey != localKey {
+				direction = "receive"
+			}
+		}
+
+		records = append(records, session.MessageRecord{
+			Direction:  direction,
+			Timestamp:  packet.Timestamp,
+			ByteLength: len(payload),
+			IsHex:      true,
+			Raw:        payload,
+		})
+	}
+	return records, nil
+}
+
+// readPcap 解析pcap文件
+func readPcap(r io.Reader) ([]capturedPacket, error) {
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	var order binary.ByteOrder
+	var nano bool
+	switch {
+	case binary.LittleEndian.Uint32(header) == 0xA1B2C3D4:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(header) == 0xA1B2C3D4:
+		order = binary.BigEndian
+	case binary.LittleEndian.Uint32(header) == 0xA1B23C4D:
+		order, nano = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(header) == 0xA1B23C4D:
+		order, nano = binary.BigEndian, true
+	default:
+		return nil, fmt.Errorf("不是有效的pcap文件")
+	}
+	snapLen := order.Uint32(header[16:])
+	linkType := uint16(order.Uint32(header[20:]))
+
+	var packets []capturedPacket
+	record := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(r, record); err == io.EOF {
+			return packets, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		inclLen := order.Uint32(record[8:])
+		if inclLen > maxCaptureRecord || (snapLen > 0 && inclLen > snapLen) {
+			return nil, fmt.Errorf("pcap报文长度错误: %d", inclLen)
+		}
+		data := make([]byte, inclLen)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		sec, frac := int64(order.Uint32(record[0:])), int64(order.Uint32(record[4:]))
+		if nano {
+			frac /= 1000
+		}
+		packets = append(packets, capturedPacket{
+			Timestamp: sec*1000 + frac/1000,
+			LinkType:  linkType,
+			Data:      data,
+		})
+	}
+}
+
+// pcapngInterface pcapng接口描述
+type pcapngInterface struct {
+	linkType uint16
+	tsUnit   uint64 // 每秒的时间戳单位数
+}
+
+// readPcapng 解析pcapng文件，支持多个段和接口
+func readPcapng(r io.Reader) ([]capturedPacket, error) {
+	var order binary.ByteOrder = binary.LittleEndian
+	var interfaces []pcapngInterface
+	var packets []capturedPacket
+
+	head := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, head); err == io.EOF {
+			return packets, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		blockType := order.Uint32(head)
+		if blockType == pcapngBlockSHB {
+			// 每个段可能使用不同的字节序
+			magic := make([]byte, 4)
+			if _, err := io.ReadFull(r, magic); err != nil {
+				return nil, err
+			}
+			if binary.LittleEndian.Uint32(magic) == 0x1A2B3C4D {
+				order = binary.LittleEndian
+			} else {
+				order = binary.BigEndian
+			}
+			length := order.Uint32(head[4:])
+			if length < 16 {
+				return nil, fmt.Errorf("pcapng块长度错误")
+			}
+			if _, err := io.CopyN(io.Discard, r, int64(length)-12); err != nil {
+				return nil, err
+			}
+			interfaces = nil
+			continue
+		}
+
+		length := order.Uint32(head[4:])
+		if length < 12 || length%4 != 0 {
+			return nil, fmt.Errorf("pcapng块长度错误")
+		}
+		if blockType != pcapngBlockIDB && blockType != pcapngBlockEPB {
+			// 不需要的块直接跳过，不分配内存
+			if _, err := io.CopyN(io.Discard, r, int64(length)-8); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if length > maxCaptureRecord+pcapngBlockOverhead {
+			return nil, fmt.Errorf("pcapng块长度错误: %d", length)
+		}
+		body := make([]byte, length-8)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, err
+		}
+		body = body[:len(body)-4]
+
+		switch blockType {
+		case pcapngBlockIDB:
+			if len(body) < 8 {
+				return nil, fmt.Errorf("pcapng接口块长度错误")
+			}
+			iface := pcapngInterface{linkType: order.Uint16(body), tsUnit: 1000000}
+			for _, opt := range parsePcapngOptions(order, body[8:]) {
+				if opt.code == 9 && len(opt.value) == 1 { // if_tsresol
+					iface.tsUnit = pcapngTimeUnit(opt.value[0])
+					if iface.tsUnit == 0 {
+						return nil, fmt.Errorf("不支持的pcapng时间戳精度: %d", opt.value[0])
+					}
+				}
+			}
+			interfaces = append(interfaces, iface)
+
+		case pcapngBlockEPB:
+			if len(body) < 20 {
+				return nil, fmt.Errorf("pcapng报文块长度错误")
+			}
+			id := order.Uint32(body)
+			if int(id) >= len(interfaces) {
+				return nil, fmt.Errorf("pcapng报文引用了未定义的接口")
+			}
+			iface := interfaces[id]
+			ts := uint64(order.Uint32(body[4:]))<<32 | uint64(order.Uint32(body[8:]))
+			capLen := int(order.Uint32(body[12:]))
+			if 20+capLen > len(body) {
+				return nil, fmt.Errorf("pcapng报文长度错误")
+			}
+
+			packet := capturedPacket{
+				Timestamp: pcapngMillis(ts, iface.tsUnit),
+				LinkType:  iface.linkType,
+				Data:      body[20 : 20+capLen],
+			}
+			options := body[20+capLen+(4-capLen%4)%4:]
+			for _, opt := range parsePcapngOptions(order, options) {
+				switch {
+				case opt.code == pcapngOptEPBFlags && len(opt.value) == 4:
+					switch order.Uint32(opt.value) & 0x3 {
+					case pcapngFlagInbound:
+						packet.Direction = "receive"
+					case pcapngFlagOutbound:
+						packet.Direction = "send"
+					}
+				case opt.code == pcapngOptComment && packet.Direction == "":
+					if comment := string(opt.value); strings.HasPrefix(comment, "TX") {
+						packet.Direction = "send"
+					} else if strings.HasPrefix(comment, "RX") {
+						packet.Direction = "receive"
+					}
+				}
+			}
+			packets = append(packets, packet)
+		}
+	}
+}
+
+// pcapngOption pcapng块选项
+type pcapngOption struct {
+	code  uint16
+	value []byte
+}
+
+// parsePcapngOptions 解析选项列表，格式错误时忽略剩余选项
+func parsePcapngOptions(order binary.ByteOrder, data []byte) []pcapngOption {
+	var options []pcapngOption
+	for len(data) >= 4 {
+		code, length := order.Uint16(data), int(order.Uint16(data[2:]))
+		if code == pcapngOptEnd {
+			break
+		}
+		padded := length + (4-length%4)%4
+		if 4+padded > len(data) {
+			break
+		}
+		options = append(options, pcapngOption{code: code, value: data[4 : 4+length]})
+		data = data[4+padded:]
+	}
+	return options
+}
+
+// pcapngTimeUnit 由if_tsresol计算每秒的时间戳单位数，超出uint64范围时返回0
+func pcapngTimeUnit(resol byte) uint64 {
+	unit := uint64(1)
+	if resol&0x80 != 0 {
+		if resol&0x7F > 63 {
+			return 0
+		}
+		return unit << (resol & 0x7F)
+	}
+	if resol > 19 {
+		return 0
+	}
+	for i := byte(0); i < resol; i++ {
+		unit *= 10
+	}
+	return unit
+}
+
+// pcapngMillis 将接口时间戳换算为毫秒，按128位计算ts*1000/unit避免精度损失和溢出
+func pcapngMillis(ts, unit uint64) int64 {
+	hi, lo := bits.Mul64(ts, 1000)
+	if hi >= unit {
+		return math.MaxInt64
+	}
+	ms, _ := bits.Div64(hi, lo, unit)
+	if ms > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(ms)
+}
+
+// packetPayload 提取报文的应用层负载，srcKey为发送方地址标识
+func packetPayload(linkType uint16, data []byte) ([]byte, string, error) {
+	switch {
+	case linkType == linkTypeEthernet:
+		if len(data) < 14 {
+			return nil, "", nil
+		}
+		etherType := binary.BigEndian.Uint16(data[12:])
+		data = data[14:]
+		for etherType == 0x8100 && len(data) >= 4 { // VLAN
+			etherType = binary.BigEndian.Uint16(data[2:])
+			data = data[4:]
+		}
+		return ipPayload(etherType, data)
+	case linkType == linkTypeNull:
+		if len(data) < 4 {
+			return nil, "", nil
+		}
+		return ipPayload(0, data[4:])
+	case linkType == linkTypeRaw:
+		return ipPayload(0, data)
+	case linkType == linkTypeSLL:
+		if len(data) < 16 {
+			return nil, "", nil
+		}
+		return ipPayload(binary.BigEndian.Uint16(data[14:]), data[16:])
+	case linkType >= linkTypeUser0 && linkType <= linkTypeUser15:
+		return data, "", nil
+	default:
+		return nil, "", fmt.Errorf("不支持的链路类型: %d", linkType)
+	}
+}
+
+// ipPayload 解析IPv4/IPv6报文中的TCP/UDP负载，etherType为0时按版本号判断
+func ipPayload(etherType uint16, data []byte) ([]byte, string, error) {
+	if len(data) == 0 {
+		return nil, "", nil
+	}
+	if etherType == 0 {
+		switch data[0] >> 4 {
+		case 4:
+			etherType = 0x0800
+		case 6:
+			etherType = 0x86DD
+		}
+	}
+
+	var proto byte
+	var src string
+	switch etherType {
+	case 0x0800:
+		if len(data) < 20 {
+			return nil, "", nil
+		}
+		headerLen := int(data[0]&0x0F) * 4
+		totalLen := int(binary.BigEndian.Uint16(data[2:]))
+		if headerLen < 20 || totalLen < headerLen || totalLen > len(data) {
+			return nil, "", nil
+		}
+		proto = data[9]
+		src = hex.EncodeToString(data[12:16])
+		data = data[headerLen:totalLen]
+	case 0x86DD:
+		if len(data) < 40 {
+			return nil, "", nil
+		}
+		payloadLen := int(binary.BigEndian.Uint16(data[4:]))
+		if 40+payloadLen > len(data) {
+			return nil, "", nil
+		}
+		proto = data[6]
+		src = hex.EncodeToString(data[8:24])
+		data = data[40 : 40+payloadLen]
+	default:
+		return nil, "", nil
+	}
+
+	switch proto {
+	case ipProtoTCP:
+		if len(data) < 20 {
+			return nil, "", nil
+		}
+		offset := int(data[12]>>4) * 4
+		if offset < 20 || offset > len(data) {
+			return nil, "", nil
+		}
+		return data[offset:], src + ":" + strconv.Itoa(int(binary.BigEndian.Uint16(data))), nil
+	case ipProtoUDP:
+		if len(data) < 8 {
+			return nil, "", nil
+		}
+		return data[8:], src + ":" + strconv.Itoa(int(binary.BigEndian.Uint16(data))), nil
+	default:
+		return nil, "", nil
+	}
+}
@@ -1,11 +1,14 @@
 package core
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -20,8 +23,15 @@ const (
 	DBDirectory = "message_data"
 	// 消息bucket名称
 	MessageBucket = "messages"
+	// 元数据bucket名称
+	MetaBucket = "meta"
+	// 当前数据库格式版本，2起消息key为NextSequence的8字节大端序编码
+	messageDBSchemaVersion = 2
 )
 
+// 元数据中记录格式版本的key
+var schemaVersionKey = []byte("schemaVersion")
+
 // MessageDB 消息数据库管理器
 type MessageDB struct {
 	sessionID string
@@ -32,24 +42,26 @@ type MessageDB struct {
 
 // MessageDBManager 全局数据库管理器
 type MessageDBManager struct {
+	dir       string                // 数据库目录
 	databases map[string]*MessageDB // sessionID -> MessageDB
 	mutex     sync.RWMutex
 }
 
 var GlobalMessageDBManager *MessageDBManager
 
-// InitMessageDBManager 初始化消息数据库管理器
+// InitMessageDBManager 初始化消息数据库管理器，数据保存在应用数据目录下，重启后保留
 func InitMessageDBManager() error {
-	// 创建数据库目录
-	if err := os.MkdirAll(DBDirectory, 0755); err != nil {
+	dir, err := AppDataDir(DBDirectory)
+	if err != nil {
 		return fmt.Errorf("创建数据库目录失败: %v", err)
 	}
 
 	GlobalMessageDBManager = &MessageDBManager{
+		dir:       dir,
 		databases: make(map[string]*MessageDB),
 	}
 
-	log.Printf("消息数据库管理器初始化成功，数据目录: %s", DBDirectory)
+	log.Printf("消息数据库管理器初始化成功，数据目录: %s", dir)
 	return nil
 }
 
@@ -67,13 +79,13 @@ func (manager *MessageDBManager) GetOrCreateMessageDB(sessionID string) (*Messag
 	}
 
 	// 确保数据库目录存在
-	if err := os.MkdirAll(DBDirectory, 0755); err != nil {
+	if err := os.MkdirAll(manager.dir, 0755); err != nil {
 		log.Printf("创建数据库目录失败: %v", err)
 		return nil, fmt.Errorf("创建数据库目录失败: %v", err)
 	}
 
-	// 创建新的数据库
-	dbPath := filepath.Join(DBDirectory, sessionID+DBFileExtension)
+	// 打开数据库，不存在时创建
+	dbPath := manager.sessionDBPath(sessionID)
 	log.Printf("创建新数据库: %s", dbPath)
 
 	boltDB, err := bbolt.Open(dbPath, 0600, &bbolt.Options{
@@ -84,14 +96,16 @@ func (manager *MessageDBManager) GetOrCreateMessageDB(sessionID string) (*Messag
 		return nil, fmt.Errorf("打开数据库失败: %v", err)
 	}
 
-	// 创建bucket
+	// 创建bucket，旧版本数据库迁移到序列号key
 	err = boltDB.Update(func(tx *bbolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(MessageBucket))
-		return err
+		if _, err := tx.CreateBucketIfNotExists([]byte(MessageBucket)); err != nil {
+			return err
+		}
+		return migrateMessageKeys(tx)
 	})
 	if err != nil {
 		boltDB.Close()
-		return nil, fmt.Errorf("创建bucket失败: %v", err)
+		return nil, fmt.Errorf("初始化数据库失败: %v", err)
 	}
 
 	messageDB := &MessageDB{
@@ -106,62 +120,75 @@ func (manager *MessageDBManager) GetOrCreateMessageDB(sessionID string) (*Messag
 	return messageDB, nil
 }
 
-// CloseSessionDB 关闭并删除会话数据库
+// sessionDBPath 会话数据库文件路径
+func (manager *MessageDBManager) sessionDBPath(sessionID string) string {
+	return filepath.Join(manager.dir, sessionID+DBFileExtension)
+}
+
+// CloseSessionDB 关闭会话数据库，保留数据库文件
 func (manager *MessageDBManager) CloseSessionDB(sessionID string) error {
 	manager.mutex.Lock()
 	defer manager.mutex.Unlock()
 
 	db, exists := manager.databases[sessionID]
 	if !exists {
-		return nil // 数据库不存在，无需处理
+		return nil // 数据库未打开，无需处理
 	}
 
-	// 关闭数据库
+	delete(manager.databases, sessionID)
 	if err := db.db.Close(); err != nil {
+		return fmt.Errorf("关闭数据库失败: %v", err)
+	}
+	return nil
+}
+
+// DeleteSessionDB 关闭并删除会话数据库
+func (manager *MessageDBManager) DeleteSessionDB(sessionID string) error {
+	if err := manager.CloseSessionDB(sessionID); err != nil {
 		log.Printf("关闭数据库失败: %v", err)
 	}
 
-	// 删除数据库文件
-	if err := os.Remove(db.dbPath); err != nil {
-		log.Printf("删除数据库文件失败: %v", err)
-	} else {
-		log.Printf("已删除会话 %s 的数据库文件: %s", sessionID, db.dbPath)
+	dbPath := manager.sessionDBPath(sessionID)
+	if err := os.Remove(dbPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除数据库文件失败: %v", err)
 	}
 
-	// 从管理器中移除
-	delete(manager.databases, sessionID)
+	log.Printf("已删除会话 %s 的数据库文件: %s", sessionID, dbPath)
 	return nil
 }
 
-// CloseAllDatabases 关闭并删除所有数据库
+// CloseAllDatabases 关闭所有数据库，保留数据库文件
 func (manager *MessageDBManager) CloseAllDatabases() error {
 	manager.mutex.Lock()
 	defer manager.mutex.Unlock()
 
 	for sessionID, db := range manager.databases {
-		// 关闭数据库
 		if err := db.db.Close(); err != nil {
 			log.Printf("关闭数据库失败 [%s]: %v", sessionID, err)
 		}
-
-		// 删除数据库文件
-		if err := os.Remove(db.dbPath); err != nil {
-			log.Printf("删除数据库文件失败 [%s]: %v", sessionID, err)
-		} else {
-			log.Printf("已删除会话 %s 的数据库文件", sessionID)
-		}
 	}
 
 	// 清空管理器
 	manager.databases = make(map[string]*MessageDB)
+	log.Printf("已关闭所有消息数据库")
+	return nil
+}
+
+// DeleteAllDatabases 关闭并删除所有数据库文件
+func (manager *MessageDBManager) DeleteAllDatabases() error {
+	manager.CloseAllDatabases()
 
-	// 删除整个数据库目录
-	if err := os.RemoveAll(DBDirectory); err != nil {
-		log.Printf("删除数据库目录失败: %v", err)
-	} else {
-		log.Printf("已清理所有消息数据库")
+	files, err := filepath.Glob(filepath.Join(manager.dir, "*"+DBFileExtension))
+	if err != nil {
+		return fmt.Errorf("查找数据库文件失败: %v", err)
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			log.Printf("删除数据库文件失败 [%s]: %v", file, err)
+		}
 	}
 
+	log.Printf("已清理所有消息数据库")
 	return nil
 }
 
@@ -176,8 +203,11 @@ func (db *MessageDB) AddMessage(record session.MessageRecord) error {
 			return fmt.Errorf("bucket不存在")
 		}
 
-		// 使用时间戳作为key
-		key := fmt.Sprintf("%d", record.Timestamp)
+		// 使用单调递增的序列号作为key，同一毫秒内的消息也不会相互覆盖
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return fmt.Errorf("生成消息序列号失败: %v", err)
+		}
 
 		// 序列化消息记录
 		data, err := json.Marshal(record)
@@ -185,7 +215,7 @@ func (db *MessageDB) AddMessage(record session.MessageRecord) error {
 			return fmt.Errorf("序列化消息失败: %v", err)
 		}
 
-		return bucket.Put([]byte(key), data)
+		return bucket.Put(sequenceKey(seq), data)
 	})
 }
 
@@ -206,7 +236,7 @@ func (db *MessageDB) GetMessages(limit, offset int) ([]session.MessageRecord, er
 		count := 0
 		skipped := 0
 
-		// 按时间戳顺序遍历
+		// 按写入顺序遍历
 		for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
 			// 跳过offset条记录
 			if skipped < offset {
@@ -235,6 +265,28 @@ func (db *MessageDB) GetMessages(limit, offset int) ([]session.MessageRecord, er
 	return messages, err
 }
 
+// ForEachMessage 按写入顺序遍历消息，fn返回错误时停止遍历
+func (db *MessageDB) ForEachMessage(fn func(record session.MessageRecord) error) error {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return db.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(MessageBucket))
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(key, value []byte) error {
+			var record session.MessageRecord
+			if err := json.Unmarshal(value, &record); err != nil {
+				log.Printf("反序列化消息失败: %v", err)
+				return nil
+			}
+			return fn(record)
+		})
+	})
+}
+
 // ClearMessages 清空所有消息
 func (db *MessageDB) ClearMessages() error {
 	db.mutex.Lock()
@@ -279,6 +331,67 @@ func (db *MessageDB) GetMessageCount() (int, error) {
 	return count, err
 }
 
+// sequenceKey 将序列号编码为8字节大端序key，字节序与数值顺序一致
+func sequenceKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// migrateMessageKeys 将旧版以毫秒时间戳字符串为key的消息迁移为序列号key
+func migrateMessageKeys(tx *bbolt.Tx) error {
+	meta, err := tx.CreateBucketIfNotExists([]byte(MetaBucket))
+	if err != nil {
+		return err
+	}
+
+	if version := meta.Get(schemaVersionKey); len(version) == 8 && binary.BigEndian.Uint64(version) >= messageDBSchemaVersion {
+		return nil
+	}
+
+	bucket := tx.Bucket([]byte(MessageBucket))
+	type legacyRecord struct {
+		timestamp int64
+		value     []byte
+	}
+	var records []legacyRecord
+	err = bucket.ForEach(func(key, value []byte) error {
+		timestamp, _ := strconv.ParseInt(string(key), 10, 64)
+		records = append(records, legacyRecord{timestamp: timestamp, value: append([]byte(nil), value...)})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(records) > 0 {
+		// 旧key按字典序排列，迁移时按时间戳数值排序
+		sort.SliceStable(records, func(i, j int) bool {
+			return records[i].timestamp < records[j].timestamp
+		})
+
+		if err := tx.DeleteBucket([]byte(MessageBucket)); err != nil {
+			return err
+		}
+		bucket, err = tx.CreateBucket([]byte(MessageBucket))
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(sequenceKey(seq), record.value); err != nil {
+				return err
+			}
+		}
+		log.Printf("已迁移 %d 条消息到序列号key", len(records))
+	}
+
+	return meta.Put(schemaVersionKey, sequenceKey(messageDBSchemaVersion))
+}
+
 // StoreMessageToDB 存储消息到数据库
 func StoreMessageToDB(sessionID string, record session.MessageRecord) error {
 	if GlobalMessageDBManager == nil {
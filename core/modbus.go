@@ -0,0 +1,212 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Modbus传输模式
+const (
+	ModbusRTU   = "rtu"   // 二进制帧，CRC16校验
+	ModbusASCII = "ascii" // ':'开头、CRLF结尾的十六进制文本帧，LRC校验
+	ModbusTCP   = "tcp"   // MBAP报文头，无校验
+)
+
+// Modbus功能码
+const (
+	ModbusReadCoils              = 0x01
+	ModbusReadDiscreteInputs     = 0x02
+	ModbusReadHoldingRegisters   = 0x03
+	ModbusReadInputRegisters     = 0x04
+	ModbusWriteSingleCoil        = 0x05
+	ModbusWriteSingleRegister    = 0x06
+	ModbusWriteMultipleCoils     = 0x0F
+	ModbusWriteMultipleRegisters = 0x10
+)
+
+// Modbus异常码
+const (
+	ModbusIllegalFunction    = 0x01
+	ModbusIllegalDataAddress = 0x02
+	ModbusIllegalDataValue   = 0x03
+	ModbusSlaveDeviceFailure = 0x04
+	ModbusAcknowledge        = 0x05
+	ModbusSlaveDeviceBusy    = 0x06
+	ModbusGatewayUnavailable = 0x0A
+	ModbusGatewayNoResponse  = 0x0B
+)
+
+// 单次请求的最大数量
+const (
+	modbusMaxReadBits       = 2000
+	modbusMaxReadRegisters  = 125
+	modbusMaxWriteBits      = 1968
+	modbusMaxWriteRegisters = 123
+)
+
+// modbusFrame 解析出的Modbus帧
+type modbusFrame struct {
+	unit byte   // 从站地址(单元标识)
+	pdu  []byte // 功能码和数据
+	txID uint16 // Modbus TCP事务ID
+}
+
+// encodeModbusFrame 按传输模式封装PDU，txID仅用于Modbus TCP
+func encodeModbusFrame(mode string, unit byte, pdu []byte, txID uint16) []byte {
+	switch mode {
+	case ModbusASCII:
+		body := append([]byte{unit}, pdu...)
+		lrc, _ := ComputeChecksum(ChecksumLRC, body)
+		body = append(body, lrc...)
+		return []byte(":" + strings.ToUpper(hex.EncodeToString(body)) + "\r\n")
+	case ModbusTCP:
+		frame := make([]byte, 0, 7+len(pdu))
+		frame = binary.BigEndian.AppendUint16(frame, txID)
+		frame = binary.BigEndian.AppendUint16(frame, 0)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(pdu)+1))
+		frame = append(frame, unit)
+		return append(frame, pdu...)
+	default:
+		frame := make([]byte, 0, len(pdu)+3)
+		frame = append(frame, unit)
+		frame = append(frame, pdu...)
+		return binary.LittleEndian.AppendUint16(frame, crc16Modbus(frame))
+	}
+}
+
+// decodeModbusFrame 从数据开头解析一帧，返回帧和消耗的字节数；数据不完整时返回0字节。
+// pduSize根据PDU前几个字节返回PDU总长度，长度还无法确定时返回-1，未知功能码返回0(RTU模式下取全部数据)
+func decodeModbusFrame(mode string, data []byte, pduSize func(pdu []byte) int) (*modbusFrame, int, error) {
+	switch mode {
+	case ModbusASCII:
+		start := bytes.IndexByte(data, ':')
+		if start < 0 {
+			return nil, 0, nil
+		}
+		end := bytes.Index(data[start:], []byte("\r\n"))
+		if end < 0 {
+			return nil, 0, nil
+		}
+		n := start + end + 2
+
+		body, err := hex.DecodeString(string(data[start+1 : start+end]))
+		if err != nil {
+			return nil, n, fmt.Errorf("ASCII帧格式错误: %v", err)
+		}
+		if len(body) < 3 {
+			return nil, n, fmt.Errorf("ASCII帧长度不足")
+		}
+		lrc, _ := ComputeChecksum(ChecksumLRC, body[:len(body)-1])
+		if lrc[0] != body[len(body)-1] {
+			return nil, n, fmt.Errorf("LRC校验失败")
+		}
+		return &modbusFrame{unit: body[0], pdu: body[1 : len(body)-1]}, n, nil
+
+	case ModbusTCP:
+		if len(data) < 7 {
+			return nil, 0, nil
+		}
+		length := int(binary.BigEndian.Uint16(data[4:6]))
+		if binary.BigEndian.Uint16(data[2:4]) != 0 || length < 2 || length > 254 {
+			return nil, len(data), fmt.Errorf("MBAP报文头错误")
+		}
+		n := 6 + length
+		if len(data) < n {
+			return nil, 0, nil
+		}
+		return &modbusFrame{
+			unit: data[6],
+			pdu:  bytes.Clone(data[7:n]),
+			txID: binary.BigEndian.Uint16(data[0:2]),
+		}, n, nil
+
+	default:
+		if len(data) < 4 {
+			return nil, 0, nil
+		}
+		size := pduSize(data[1:])
+		if size < 0 {
+			return nil, 0, nil
+		}
+		if size == 0 {
+			size = len(data) - 3
+		}
+		n := size + 3
+		if len(data) < n {
+			return nil, 0, nil
+		}
+		if binary.LittleEndian.Uint16(data[n-2:n]) != crc16Modbus(data[:n-2]) {
+			return nil, n, fmt.Errorf("CRC校验失败")
+		}
+		return &modbusFrame{unit: data[0], pdu: bytes.Clone(data[1 : n-2])}, n, nil
+	}
+}
+
+// modbusResponseSize 响应PDU长度
+func modbusResponseSize(pdu []byte) int {
+	if pdu[0]&0x80 != 0 {
+		return 2
+	}
+	switch pdu[0] {
+	case ModbusReadCoils, ModbusReadDiscreteInputs, ModbusReadHoldingRegisters, ModbusReadInputRegisters:
+		if len(pdu) < 2 {
+			return -1
+		}
+		return 2 + int(pdu[1])
+	case ModbusWriteSingleCoil, ModbusWriteSingleRegister, ModbusWriteMultipleCoils, ModbusWriteMultipleRegisters:
+		return 5
+	default:
+		return 0
+	}
+}
+
+// defaultModbusMode 会话类型对应的默认传输模式，串口为RTU，其他为Modbus TCP
+func defaultModbusMode(sessionType string) string {
+	if sessionType == "serial" {
+		return ModbusRTU
+	}
+	return ModbusTCP
+}
+
+// validModbusMode 是否为支持的传输模式
+func validModbusMode(mode string) bool {
+	return mode == ModbusRTU || mode == ModbusASCII || mode == ModbusTCP
+}
+
+// packModbusBits 按Modbus位序(低位在前)打包线圈
+func packModbusBits(bits []bool) []byte {
+	out := make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		if bit {
+			out[i/8] |= 1 << (i % 8)
+		}
+	}
+	return out
+}
+
+// modbusExceptionText 异常码说明
+func modbusExceptionText(code byte) string {
+	switch code {
+	case ModbusIllegalFunction:
+		return "非法功能码"
+	case ModbusIllegalDataAddress:
+		return "非法数据地址"
+	case ModbusIllegalDataValue:
+		return "非法数据值"
+	case ModbusSlaveDeviceFailure:
+		return "从站设备故障"
+	case ModbusAcknowledge:
+		return "确认"
+	case ModbusSlaveDeviceBusy:
+		return "从站设备忙"
+	case ModbusGatewayUnavailable:
+		return "网关路径不可用"
+	case ModbusGatewayNoResponse:
+		return "网关目标设备无响应"
+	default:
+		return fmt.Sprintf("未知异常码 %d", code)
+	}
+}
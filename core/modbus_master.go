@@ -0,0 +1,493 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/modbus"
+)
+
+const (
+	// 等待响应默认超时（毫秒）
+	defaultModbusTimeout = 1000
+	// 轮询最小间隔（毫秒）
+	minModbusPollInterval = 10
+)
+
+// ModbusMasterManager Modbus主站管理器
+type ModbusMasterManager struct{}
+
+var GlobalModbusMasterManager = &ModbusMasterManager{}
+
+// modbusPoll 正在运行的Modbus轮询
+type modbusPoll struct {
+	stop  chan struct{}
+	state modbus.ModbusPollState
+}
+
+// Execute 执行一次主站请求；超时、校验失败和异常响应记录在结果中，不作为错误返回
+func (mm *ModbusMasterManager) Execute(sessionID string, req modbus.ModbusRequest) (*modbus.ModbusResult, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	req, pdu, err := prepareModbusRequest(sess, req)
+	if err != nil {
+		return nil, err
+	}
+	return mm.execute(sess, req, pdu, nil)
+}
+
+// StartPoll 按间隔重复执行主站请求，ID已存在时替换原轮询
+func (mm *ModbusMasterManager) StartPoll(sessionID string, cfg modbus.ModbusPollConfig) (*modbus.ModbusPollState, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Interval < minModbusPollInterval {
+		return nil, fmt.Errorf("轮询间隔必须大于等于%d毫秒", minModbusPollInterval)
+	}
+	if cfg.Count < 0 {
+		return nil, fmt.Errorf("轮询次数不能为负数")
+	}
+
+	req, pdu, err := prepareModbusRequest(sess, cfg.ModbusRequest)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ModbusRequest = req
+	if cfg.ID == "" {
+		cfg.ID = fmt.Sprintf("poll_%d", time.Now().UnixNano())
+	}
+
+	mm.cancelPoll(sess, cfg.ID)
+
+	poll := &modbusPoll{
+		stop: make(chan struct{}),
+		state: modbus.ModbusPollState{
+			ModbusPollConfig: cfg,
+			Running:          true,
+			StartTime:        time.Now().UnixMilli(),
+		},
+	}
+
+	sess.mutex.Lock()
+	if sess.modbusPolls == nil {
+		sess.modbusPolls = make(map[string]*modbusPoll)
+	}
+	sess.modbusPolls[cfg.ID] = poll
+	state := poll.state
+	sess.mutex.Unlock()
+
+	log.Printf("会话 %s 启动Modbus轮询 %s，功能码 %d，间隔 %dms", sessionID, cfg.ID, cfg.Function, cfg.Interval)
+	go mm.runPoll(sess, poll, pdu)
+
+	return &state, nil
+}
+
+// StopPoll 停止会话的轮询，id为空时停止全部，返回仍在运行的轮询
+func (mm *ModbusMasterManager) StopPoll(sessionID, id string) ([]modbus.ModbusPollState, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if id == "" {
+		mm.cancelPolls(sess)
+	} else {
+		mm.cancelPoll(sess, id)
+	}
+	return mm.ListPolls(sessionID)
+}
+
+// ListPolls 获取会话正在运行的轮询
+func (mm *ModbusMasterManager) ListPolls(sessionID string) ([]modbus.ModbusPollState, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sess.mutex.RLock()
+	defer sess.mutex.RUnlock()
+
+	states := make([]modbus.ModbusPollState, 0, len(sess.modbusPolls))
+	for _, poll := range sess.modbusPolls {
+		states = append(states, poll.state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].StartTime < states[j].StartTime })
+	return states, nil
+}
+
+// cancelPoll 停止指定轮询
+func (mm *ModbusMasterManager) cancelPoll(sess *Session, id string) {
+	sess.mutex.Lock()
+	defer sess.mutex.Unlock()
+
+	if poll, exists := sess.modbusPolls[id]; exists {
+		close(poll.stop)
+		delete(sess.modbusPolls, id)
+	}
+}
+
+// cancelPolls 停止会话的全部轮询
+func (mm *ModbusMasterManager) cancelPolls(sess *Session) {
+	sess.mutex.Lock()
+	defer sess.mutex.Unlock()
+
+	for id, poll := range sess.modbusPolls {
+		close(poll.stop)
+		delete(sess.modbusPolls, id)
+	}
+}
+
+// runPoll 轮询循环
+func (mm *ModbusMasterManager) runPoll(sess *Session, poll *modbusPoll, pdu []byte) {
+	sessionID := sess.Info.SessionID
+	state := poll.state
+	ticker := time.NewTicker(time.Duration(state.Interval) * time.Millisecond)
+	defer ticker.Stop()
+
+	defer func() {
+		state.Running = false
+		sess.mutex.Lock()
+		if sess.modbusPolls[state.ID] == poll {
+			delete(sess.modbusPolls, state.ID)
+		}
+		sess.mutex.Unlock()
+
+		log.Printf("会话 %s Modbus轮询 %s 结束，共 %d 次，失败 %d 次", sessionID, state.ID, state.Polls, state.Failed)
+		if GlobalWebSocketManager != nil {
+			GlobalWebSocketManager.NotifyModbusPoll(sessionID, state)
+		}
+	}()
+
+	for {
+		result, err := mm.execute(sess, state.ModbusRequest, pdu, poll.stop)
+		select {
+		case <-poll.stop:
+			return
+		default:
+		}
+		if err != nil {
+			result = newModbusResult(state.ModbusRequest)
+			result.Error = err.Error()
+		}
+
+		state.Polls++
+		if result.Error != "" || result.Exception != 0 {
+			state.Failed++
+		}
+		state.LastResult = result
+
+		// 更新状态并推送结果
+		sess.mutex.Lock()
+		if sess.modbusPolls[state.ID] == poll {
+			poll.state = state
+		}
+		sess.mutex.Unlock()
+		if GlobalWebSocketManager != nil {
+			GlobalWebSocketManager.NotifyModbusPoll(sessionID, state)
+		}
+
+		if state.Count > 0 && state.Polls >= state.Count {
+			return
+		}
+
+		select {
+		case <-poll.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// execute 发送请求帧并等待响应，同一会话的请求依次进行；stop关闭时放弃等待
+func (mm *ModbusMasterManager) execute(sess *Session, req modbus.ModbusRequest, pdu []byte, stop chan struct{}) (*modbus.ModbusResult, error) {
+	sess.modbusMutex.Lock()
+	defer sess.modbusMutex.Unlock()
+
+	sess.mutex.RLock()
+	transferring := sess.xmodem != nil
+	sess.mutex.RUnlock()
+	if transferring {
+		return nil, fmt.Errorf("会话正在进行XMODEM/YMODEM传输")
+	}
+
+	sess.modbusTxID++
+	txID := sess.modbusTxID
+
+	frame := encodeModbusFrame(req.Mode, byte(req.SlaveID), pdu, txID)
+	result := newModbusResult(req)
+	result.Request = modbusFrameText(req.Mode, frame)
+
+	collector := newReceiveCollector()
+	unsubscribe := sess.observe(collector.observe)
+	defer unsubscribe()
+
+	start := time.Now()
+	if _, err := GlobalSessionManager.SendBytes(sess.Info.SessionID, frame, req.Mode != ModbusASCII, ""); err != nil {
+		return nil, err
+	}
+
+	// 广播请求从站不响应
+	if req.Mode != ModbusTCP && req.SlaveID == 0 {
+		result.Values = modbusWrittenValues(req)
+		return result, nil
+	}
+
+	var response *modbusFrame
+	var raw []byte
+	var frameErr error
+	received, ok, stopped := collector.waitUntil(func(data []byte) bool {
+		response, raw, frameErr = findModbusResponse(req.Mode, data, txID)
+		return response != nil || frameErr != nil
+	}, time.Duration(req.Timeout)*time.Millisecond, stop)
+	if stopped {
+		return nil, fmt.Errorf("请求已取消")
+	}
+
+	result.Elapsed = time.Since(start).Milliseconds()
+	if !ok {
+		result.Error = "等待响应超时"
+		if len(received) > 0 {
+			result.Response = modbusFrameText(req.Mode, received)
+		}
+		return result, nil
+	}
+
+	result.Response = modbusFrameText(req.Mode, raw)
+	if frameErr != nil {
+		result.Error = frameErr.Error()
+		return result, nil
+	}
+	parseModbusResponse(req, pdu, response, result)
+	return result, nil
+}
+
+// prepareModbusRequest 校验请求并补全默认值，返回请求PDU
+func prepareModbusRequest(sess *Session, req modbus.ModbusRequest) (modbus.ModbusRequest, []byte, error) {
+	switch sess.Info.Type {
+	case "serial", "tcpClient", "tlsClient":
+	default:
+		return req, nil, fmt.Errorf("Modbus主站仅支持串口和TCP客户端会话")
+	}
+
+	if req.Mode == "" {
+		req.Mode = defaultModbusMode(sess.Info.Type)
+	}
+	if !validModbusMode(req.Mode) {
+		return req, nil, fmt.Errorf("不支持的传输模式: %s", req.Mode)
+	}
+
+	maxSlave := 247
+	if req.Mode == ModbusTCP {
+		maxSlave = 255
+	}
+	if req.SlaveID < 0 || req.SlaveID > maxSlave {
+		return req, nil, fmt.Errorf("从站地址必须在0到%d之间", maxSlave)
+	}
+
+	if req.Timeout < 0 {
+		return req, nil, fmt.Errorf("响应超时不能为负数")
+	}
+	if req.Timeout == 0 {
+		req.Timeout = defaultModbusTimeout
+	}
+
+	pdu, quantity, err := buildModbusPDU(req)
+	if err != nil {
+		return req, nil, err
+	}
+	req.Quantity = quantity
+
+	if req.Mode != ModbusTCP && req.SlaveID == 0 && req.Function <= ModbusReadInputRegisters {
+		return req, nil, fmt.Errorf("广播地址只能用于写入")
+	}
+	return req, pdu, nil
+}
+
+// buildModbusPDU 组装请求PDU，返回PDU和数量
+func buildModbusPDU(req modbus.ModbusRequest) ([]byte, int, error) {
+	if req.Address < 0 || req.Address > 0xFFFF {
+		return nil, 0, fmt.Errorf("起始地址必须在0到65535之间")
+	}
+
+	quantity, limit := req.Quantity, 0
+	switch req.Function {
+	case ModbusReadCoils, ModbusReadDiscreteInputs:
+		limit = modbusMaxReadBits
+	case ModbusReadHoldingRegisters, ModbusReadInputRegisters:
+		limit = modbusMaxReadRegisters
+	case ModbusWriteSingleCoil, ModbusWriteSingleRegister:
+		quantity, limit = len(req.Values), 1
+	case ModbusWriteMultipleCoils:
+		quantity, limit = len(req.Values), modbusMaxWriteBits
+	case ModbusWriteMultipleRegisters:
+		quantity, limit = len(req.Values), modbusMaxWriteRegisters
+	default:
+		return nil, 0, fmt.Errorf("不支持的功能码: %d", req.Function)
+	}
+	if quantity < 1 || quantity > limit {
+		return nil, 0, fmt.Errorf("功能码 %d 的数量必须在1到%d之间", req.Function, limit)
+	}
+	if req.Address+quantity > 0x10000 {
+		return nil, 0, fmt.Errorf("地址范围超出65535")
+	}
+
+	pdu := []byte{byte(req.Function)}
+	pdu = binary.BigEndian.AppendUint16(pdu, uint16(req.Address))
+
+	switch req.Function {
+	case ModbusReadCoils, ModbusReadDiscreteInputs, ModbusReadHoldingRegisters, ModbusReadInputRegisters:
+		pdu = binary.BigEndian.AppendUint16(pdu, uint16(quantity))
+	case ModbusWriteSingleCoil:
+		value := uint16(0)
+		if req.Values[0] != 0 {
+			value = 0xFF00
+		}
+		pdu = binary.BigEndian.AppendUint16(pdu, value)
+	case ModbusWriteSingleRegister:
+		if err := validateModbusRegister(req.Values[0]); err != nil {
+			return nil, 0, err
+		}
+		pdu = binary.BigEndian.AppendUint16(pdu, uint16(req.Values[0]))
+	case ModbusWriteMultipleCoils:
+		bits := make([]bool, quantity)
+		for i, v := range req.Values {
+			bits[i] = v != 0
+		}
+		packed := packModbusBits(bits)
+		pdu = binary.BigEndian.AppendUint16(pdu, uint16(quantity))
+		pdu = append(pdu, byte(len(packed)))
+		pdu = append(pdu, packed...)
+	case ModbusWriteMultipleRegisters:
+		pdu = binary.BigEndian.AppendUint16(pdu, uint16(quantity))
+		pdu = append(pdu, byte(quantity*2))
+		for _, v := range req.Values {
+			if err := validateModbusRegister(v); err != nil {
+				return nil, 0, err
+			}
+			pdu = binary.BigEndian.AppendUint16(pdu, uint16(v))
+		}
+	}
+	return pdu, quantity, nil
+}
+
+// validateModbusRegister 寄存器值允许有符号或无符号16位整数
+func validateModbusRegister(value int) error {
+	if value < -0x8000 || value > 0xFFFF {
+		return fmt.Errorf("寄存器值 %d 超出16位范围", value)
+	}
+	return nil
+}
+
+// findModbusResponse 在收到的数据中查找响应帧，Modbus TCP跳过事务ID不匹配的帧
+func findModbusResponse(mode string, data []byte, txID uint16) (*modbusFrame, []byte, error) {
+	for {
+		frame, n, err := decodeModbusFrame(mode, data, modbusResponseSize)
+		if n == 0 {
+			return nil, nil, nil
+		}
+		if err != nil {
+			return nil, data[:n], err
+		}
+		if mode == ModbusTCP && frame.txID != txID {
+			data = data[n:]
+			continue
+		}
+		return frame, data[:n], nil
+	}
+}
+
+// parseModbusResponse 校验响应并解析出地址和值
+func parseModbusResponse(req modbus.ModbusRequest, requestPDU []byte, frame *modbusFrame, result *modbus.ModbusResult) {
+	pdu := frame.pdu
+	if frame.unit != byte(req.SlaveID) {
+		result.Error = fmt.Sprintf("从站地址不匹配，收到 %d", frame.unit)
+		return
+	}
+	if len(pdu) >= 2 && pdu[0] == byte(req.Function)|0x80 {
+		result.Exception = int(pdu[1])
+		result.ExceptionText = modbusExceptionText(pdu[1])
+		return
+	}
+	if pdu[0] != byte(req.Function) {
+		result.Error = fmt.Sprintf("功能码不匹配，收到 %d", pdu[0])
+		return
+	}
+
+	switch req.Function {
+	case ModbusReadCoils, ModbusReadDiscreteInputs:
+		count := (req.Quantity + 7) / 8
+		if len(pdu) < 2 || int(pdu[1]) != count || len(pdu) != 2+count {
+			result.Error = "响应长度错误"
+			return
+		}
+		result.Values = make([]modbus.ModbusValue, req.Quantity)
+		for i := range result.Values {
+			result.Values[i] = modbus.ModbusValue{
+				Address: req.Address + i,
+				Value:   int(pdu[2+i/8] >> (i % 8) & 1),
+			}
+		}
+	case ModbusReadHoldingRegisters, ModbusReadInputRegisters:
+		count := req.Quantity * 2
+		if len(pdu) < 2 || int(pdu[1]) != count || len(pdu) != 2+count {
+			result.Error = "响应长度错误"
+			return
+		}
+		result.Values = make([]modbus.ModbusValue, req.Quantity)
+		for i := range result.Values {
+			result.Values[i] = modbus.ModbusValue{
+				Address: req.Address + i,
+				Value:   int(binary.BigEndian.Uint16(pdu[2+i*2:])),
+			}
+		}
+	default:
+		if len(pdu) != 5 || !bytes.Equal(pdu[1:5], requestPDU[1:5]) {
+			result.Error = "写入确认与请求不一致"
+			return
+		}
+		result.Values = modbusWrittenValues(req)
+	}
+}
+
+// modbusWrittenValues 写入请求的地址和值
+func modbusWrittenValues(req modbus.ModbusRequest) []modbus.ModbusValue {
+	coil := req.Function == ModbusWriteSingleCoil || req.Function == ModbusWriteMultipleCoils
+	values := make([]modbus.ModbusValue, len(req.Values))
+	for i, v := range req.Values {
+		if coil && v != 0 {
+			v = 1
+		}
+		values[i] = modbus.ModbusValue{Address: req.Address + i, Value: int(uint16(v))}
+	}
+	return values
+}
+
+// newModbusResult 根据请求创建结果
+func newModbusResult(req modbus.ModbusRequest) *modbus.ModbusResult {
+	return &modbus.ModbusResult{
+		SlaveID:   req.SlaveID,
+		Function:  req.Function,
+		Address:   req.Address,
+		Quantity:  req.Quantity,
+		Values:    []modbus.ModbusValue{},
+		Timestamp: time.Now().UnixMilli(),
+	}
+}
+
+// modbusFrameText 帧的显示文本，ASCII模式为帧内容，其他模式为十六进制
+func modbusFrameText(mode string, frame []byte) string {
+	if mode == ModbusASCII {
+		return strings.TrimSpace(string(frame))
+	}
+	return EncodeHex(frame)
+}
@@ -0,0 +1,544 @@
+package core
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/modbus"
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+// Modbus从站数据表
+const (
+	ModbusTableCoils            = "coils"
+	ModbusTableDiscreteInputs   = "discreteInputs"
+	ModbusTableHoldingRegisters = "holdingRegisters"
+	ModbusTableInputRegisters   = "inputRegisters"
+)
+
+// 每个来源未解析数据的上限，超过时丢弃
+const modbusSlaveBufferLimit = 4096
+
+// ModbusSlaveManager Modbus从站模拟管理器
+type ModbusSlaveManager struct{}
+
+var GlobalModbusSlaveManager = &ModbusSlaveManager{}
+
+// modbusSlave 运行中的Modbus从站模拟器，数据表从配置载入后独立修改
+type modbusSlave struct {
+	cfg        *modbus.ModbusSlaveConfig
+	mode       string
+	registers  modbus.ModbusRegisterMap
+	buffers    map[string][]byte // 来源地址 -> 未解析的数据
+	requests   int
+	exceptions int
+	mutex      sync.Mutex
+}
+
+// modbusSlaveChange 数据表变化
+type modbusSlaveChange struct {
+	table   string
+	address int
+	values  []int
+}
+
+// Set 设置会话的从站模拟配置，cfg为空时关闭；数据表重新载入配置中的初始值
+func (sm *ModbusSlaveManager) Set(sessionID string, cfg *modbus.ModbusSlaveConfig) error {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if cfg != nil {
+		if err := validateModbusSlaveConfig(sess, cfg); err != nil {
+			return err
+		}
+	}
+
+	sess.mutex.Lock()
+	sess.Info.ModbusSlave = cfg
+	sess.modbusSlave = nil
+	sess.mutex.Unlock()
+
+	GlobalSessionManager.PersistSessions()
+	return nil
+}
+
+// GetState 获取会话的从站模拟配置和当前数据
+func (sm *ModbusSlaveManager) GetState(sessionID string) (*modbus.ModbusSlaveState, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if slave := sess.currentModbusSlave(); slave != nil {
+		return slave.state(), nil
+	}
+
+	sess.mutex.RLock()
+	defer sess.mutex.RUnlock()
+
+	state := &modbus.ModbusSlaveState{Config: sess.Info.ModbusSlave}
+	if cfg := sess.Info.ModbusSlave; cfg != nil {
+		state.Registers = cloneModbusRegisters(cfg.Registers)
+	}
+	return state, nil
+}
+
+// WriteValues 修改从站当前数据，只读数据表(离散输入、输入寄存器)也可修改
+func (sm *ModbusSlaveManager) WriteValues(sessionID, table string, address int, values []int) (*modbus.ModbusSlaveState, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	slave := sess.currentModbusSlave()
+	if slave == nil {
+		return nil, fmt.Errorf("Modbus从站模拟未启用")
+	}
+
+	change, err := slave.write(table, address, values)
+	if err != nil {
+		return nil, err
+	}
+	if GlobalWebSocketManager != nil {
+		GlobalWebSocketManager.NotifyModbusSlave(sessionID, change.table, change.address, change.values)
+	}
+	return slave.state(), nil
+}
+
+// currentModbusSlave 获取会话当前配置对应的从站模拟器，配置变更时重新载入
+func (s *Session) currentModbusSlave() *modbusSlave {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cfg := s.Info.ModbusSlave
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	if s.modbusSlave == nil || s.modbusSlave.cfg != cfg {
+		s.modbusSlave = &modbusSlave{
+			cfg:       cfg,
+			mode:      cfg.Mode,
+			registers: cloneModbusRegisters(cfg.Registers),
+			buffers:   make(map[string][]byte),
+		}
+		if s.modbusSlave.mode == "" {
+			s.modbusSlave.mode = defaultModbusMode(s.Info.Type)
+		}
+	}
+	return s.modbusSlave
+}
+
+// serveModbus 从站模拟器处理收到的请求，应答发往请求的来源
+func (s *Session) serveModbus(record session.MessageRecord) {
+	slave := s.currentModbusSlave()
+	if slave == nil {
+		return
+	}
+
+	replies, changes := slave.receive(record.RemoteAddr, record.Raw)
+
+	sessionID := s.Info.SessionID
+	if GlobalWebSocketManager != nil {
+		for _, change := range changes {
+			GlobalWebSocketManager.NotifyModbusSlave(sessionID, change.table, change.address, change.values)
+		}
+	}
+
+	for _, reply := range replies {
+		send := func() {
+			if _, err := GlobalSessionManager.sendBytes(sessionID, reply, slave.mode != ModbusASCII, record.RemoteAddr, "Modbus从站"); err != nil {
+				log.Printf("会话 %s Modbus从站应答失败: %v", sessionID, err)
+			}
+		}
+		if slave.cfg.Delay > 0 {
+			time.AfterFunc(time.Duration(slave.cfg.Delay)*time.Millisecond, send)
+			continue
+		}
+		send()
+	}
+}
+
+// receive 缓存来源的数据并处理其中完整的请求帧，返回应答帧和数据表变化
+func (ms *modbusSlave) receive(source string, data []byte) ([][]byte, []modbusSlaveChange) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	buffer := append(ms.buffers[source], data...)
+	var replies [][]byte
+	var changes []modbusSlaveChange
+	for {
+		frame, n, err := decodeModbusFrame(ms.mode, buffer, modbusRequestSize)
+		if n == 0 {
+			break
+		}
+		buffer = buffer[n:]
+		if err != nil {
+			log.Printf("Modbus从站丢弃无效请求: %v", err)
+			continue
+		}
+
+		reply, change := ms.handle(frame)
+		if change != nil {
+			changes = append(changes, *change)
+		}
+		if reply != nil {
+			replies = append(replies, encodeModbusFrame(ms.mode, frame.unit, reply, frame.txID))
+		}
+	}
+
+	if len(buffer) == 0 || len(buffer) > modbusSlaveBufferLimit {
+		delete(ms.buffers, source)
+	} else {
+		ms.buffers[source] = buffer
+	}
+	return replies, changes
+}
+
+// handle 处理一个请求，返回应答PDU(不应答时为空)和写入造成的数据表变化
+func (ms *modbusSlave) handle(frame *modbusFrame) ([]byte, *modbusSlaveChange) {
+	broadcast := ms.mode != ModbusTCP && frame.unit == 0
+	if !broadcast && ms.cfg.SlaveID != 0 && int(frame.unit) != ms.cfg.SlaveID {
+		return nil, nil
+	}
+	ms.requests++
+
+	pdu := frame.pdu
+	function := pdu[0]
+	if rule := ms.matchException(pdu); rule != nil {
+		if rule.Exception == 0 || broadcast {
+			return nil, nil
+		}
+		ms.exceptions++
+		return []byte{function | 0x80, byte(rule.Exception)}, nil
+	}
+
+	reply, change, code := ms.execute(pdu)
+	if code != 0 {
+		ms.exceptions++
+		reply = []byte{function | 0x80, code}
+	}
+	if broadcast {
+		return nil, change
+	}
+	return reply, change
+}
+
+// matchException 查找匹配请求的异常注入规则
+func (ms *modbusSlave) matchException(pdu []byte) *modbus.ModbusExceptionRule {
+	address, quantity, ranged := modbusRequestRange(pdu)
+	for i := range ms.cfg.Exceptions {
+		rule := &ms.cfg.Exceptions[i]
+		if rule.Function != 0 && rule.Function != int(pdu[0]) {
+			continue
+		}
+		if rule.Count > 0 && (!ranged || address >= rule.Address+rule.Count || address+quantity <= rule.Address) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// execute 按功能码读写数据表，返回应答PDU、数据表变化和异常码
+func (ms *modbusSlave) execute(pdu []byte) ([]byte, *modbusSlaveChange, byte) {
+	function := pdu[0]
+	switch function {
+	case ModbusReadCoils, ModbusReadDiscreteInputs, ModbusReadHoldingRegisters, ModbusReadInputRegisters,
+		ModbusWriteSingleCoil, ModbusWriteSingleRegister, ModbusWriteMultipleCoils, ModbusWriteMultipleRegisters:
+	default:
+		return nil, nil, ModbusIllegalFunction
+	}
+	if len(pdu) < 5 {
+		return nil, nil, ModbusIllegalDataValue
+	}
+
+	address := int(binary.BigEndian.Uint16(pdu[1:3]))
+	value := int(binary.BigEndian.Uint16(pdu[3:5]))
+
+	switch function {
+	case ModbusReadCoils, ModbusReadDiscreteInputs:
+		name := ModbusTableCoils
+		if function == ModbusReadDiscreteInputs {
+			name = ModbusTableDiscreteInputs
+		}
+		if value < 1 || value > modbusMaxReadBits {
+			return nil, nil, ModbusIllegalDataValue
+		}
+		values, ok := ms.read(name, address, value)
+		if !ok {
+			return nil, nil, ModbusIllegalDataAddress
+		}
+		bits := make([]bool, len(values))
+		for i, v := range values {
+			bits[i] = v != 0
+		}
+		packed := packModbusBits(bits)
+		return append([]byte{function, byte(len(packed))}, packed...), nil, 0
+
+	case ModbusReadHoldingRegisters, ModbusReadInputRegisters:
+		name := ModbusTableHoldingRegisters
+		if function == ModbusReadInputRegisters {
+			name = ModbusTableInputRegisters
+		}
+		if value < 1 || value > modbusMaxReadRegisters {
+			return nil, nil, ModbusIllegalDataValue
+		}
+		values, ok := ms.read(name, address, value)
+		if !ok {
+			return nil, nil, ModbusIllegalDataAddress
+		}
+		reply := []byte{function, byte(len(values) * 2)}
+		for _, v := range values {
+			reply = binary.BigEndian.AppendUint16(reply, uint16(v))
+		}
+		return reply, nil, 0
+
+	case ModbusWriteSingleCoil:
+		if value != 0 && value != 0xFF00 {
+			return nil, nil, ModbusIllegalDataValue
+		}
+		coil := 0
+		if value != 0 {
+			coil = 1
+		}
+		return ms.writeRequest(ModbusTableCoils, address, []int{coil}, pdu)
+
+	case ModbusWriteSingleRegister:
+		return ms.writeRequest(ModbusTableHoldingRegisters, address, []int{value}, pdu)
+
+	case ModbusWriteMultipleCoils:
+		if value < 1 || value > modbusMaxWriteBits || len(pdu) < 6 ||
+			int(pdu[5]) != (value+7)/8 || len(pdu) != 6+int(pdu[5]) {
+			return nil, nil, ModbusIllegalDataValue
+		}
+		values := make([]int, value)
+		for i := range values {
+			values[i] = int(pdu[6+i/8] >> (i % 8) & 1)
+		}
+		return ms.writeRequest(ModbusTableCoils, address, values, pdu)
+
+	default:
+		if value < 1 || value > modbusMaxWriteRegisters || len(pdu) < 6 ||
+			int(pdu[5]) != value*2 || len(pdu) != 6+int(pdu[5]) {
+			return nil, nil, ModbusIllegalDataValue
+		}
+		values := make([]int, value)
+		for i := range values {
+			values[i] = int(binary.BigEndian.Uint16(pdu[6+i*2:]))
+		}
+		return ms.writeRequest(ModbusTableHoldingRegisters, address, values, pdu)
+	}
+}
+
+// writeRequest 执行主站的写入请求，应答为请求PDU的前5字节
+func (ms *modbusSlave) writeRequest(name string, address int, values []int, pdu []byte) ([]byte, *modbusSlaveChange, byte) {
+	table := ms.table(name)
+	index, ok := modbusTableIndex(table, address, len(values))
+	if !ok {
+		return nil, nil, ModbusIllegalDataAddress
+	}
+	copy(table.Values[index:], values)
+	return slices.Clone(pdu[:5]), &modbusSlaveChange{table: name, address: address, values: values}, 0
+}
+
+// read 读取数据表，地址范围超出数据表时返回false
+func (ms *modbusSlave) read(name string, address, quantity int) ([]int, bool) {
+	table := ms.table(name)
+	index, ok := modbusTableIndex(table, address, quantity)
+	if !ok {
+		return nil, false
+	}
+	return table.Values[index : index+quantity], true
+}
+
+// write 修改数据表，值按数据表类型校验
+func (ms *modbusSlave) write(name string, address int, values []int) (*modbusSlaveChange, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	table := ms.table(name)
+	if table == nil {
+		return nil, fmt.Errorf("未知的数据表: %s", name)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("写入值不能为空")
+	}
+	index, ok := modbusTableIndex(table, address, len(values))
+	if !ok {
+		return nil, fmt.Errorf("地址 %d 起的 %d 个值超出数据表范围 %d-%d", address, len(values), table.Start, table.Start+len(table.Values)-1)
+	}
+
+	normalized, err := normalizeModbusValues(name, values)
+	if err != nil {
+		return nil, err
+	}
+	copy(table.Values[index:], normalized)
+	return &modbusSlaveChange{table: name, address: address, values: normalized}, nil
+}
+
+// state 当前状态
+func (ms *modbusSlave) state() *modbus.ModbusSlaveState {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	return &modbus.ModbusSlaveState{
+		Config:     ms.cfg,
+		Registers:  cloneModbusRegisters(ms.registers),
+		Requests:   ms.requests,
+		Exceptions: ms.exceptions,
+	}
+}
+
+// table 按名称获取数据表
+func (ms *modbusSlave) table(name string) *modbus.ModbusTable {
+	switch name {
+	case ModbusTableCoils:
+		return &ms.registers.Coils
+	case ModbusTableDiscreteInputs:
+		return &ms.registers.DiscreteInputs
+	case ModbusTableHoldingRegisters:
+		return &ms.registers.HoldingRegisters
+	case ModbusTableInputRegisters:
+		return &ms.registers.InputRegisters
+	default:
+		return nil
+	}
+}
+
+// validateModbusSlaveConfig 校验从站模拟配置，并将数据表的值规范化
+func validateModbusSlaveConfig(sess *Session, cfg *modbus.ModbusSlaveConfig) error {
+	switch sess.Info.Type {
+	case "serial", "tcpServer", "tlsServer":
+	default:
+		return fmt.Errorf("Modbus从站仅支持串口和TCP服务端会话")
+	}
+
+	mode := cfg.Mode
+	if mode == "" {
+		mode = defaultModbusMode(sess.Info.Type)
+	}
+	if !validModbusMode(mode) {
+		return fmt.Errorf("不支持的传输模式: %s", cfg.Mode)
+	}
+	maxSlave := 247
+	if mode == ModbusTCP {
+		maxSlave = 255
+	}
+	if cfg.SlaveID < 0 || cfg.SlaveID > maxSlave {
+		return fmt.Errorf("从站地址必须在0到%d之间", maxSlave)
+	}
+	if cfg.Delay < 0 {
+		return fmt.Errorf("应答延迟不能为负数")
+	}
+
+	tables := map[string]*modbus.ModbusTable{
+		ModbusTableCoils:            &cfg.Registers.Coils,
+		ModbusTableDiscreteInputs:   &cfg.Registers.DiscreteInputs,
+		ModbusTableHoldingRegisters: &cfg.Registers.HoldingRegisters,
+		ModbusTableInputRegisters:   &cfg.Registers.InputRegisters,
+	}
+	for name, table := range tables {
+		if table.Start < 0 || table.Start+len(table.Values) > 0x10000 {
+			return fmt.Errorf("数据表 %s 的地址范围超出0-65535", name)
+		}
+		values, err := normalizeModbusValues(name, table.Values)
+		if err != nil {
+			return fmt.Errorf("数据表 %s: %v", name, err)
+		}
+		table.Values = values
+	}
+
+	for i, rule := range cfg.Exceptions {
+		if rule.Function < 0 || rule.Function > 0x7F {
+			return fmt.Errorf("异常规则 %d 的功能码无效", i+1)
+		}
+		if rule.Exception < 0 || rule.Exception > 0xFF {
+			return fmt.Errorf("异常规则 %d 的异常码无效", i+1)
+		}
+		if rule.Address < 0 || rule.Address > 0xFFFF || rule.Count < 0 {
+			return fmt.Errorf("异常规则 %d 的地址范围无效", i+1)
+		}
+	}
+	return nil
+}
+
+// normalizeModbusValues 校验并规范化写入数据表的值：线圈为0或1，寄存器转换为无符号16位
+func normalizeModbusValues(name string, values []int) ([]int, error) {
+	out := make([]int, len(values))
+	for i, v := range values {
+		switch name {
+		case ModbusTableCoils, ModbusTableDiscreteInputs:
+			if v != 0 && v != 1 {
+				return nil, fmt.Errorf("线圈值必须为0或1")
+			}
+			out[i] = v
+		default:
+			if err := validateModbusRegister(v); err != nil {
+				return nil, err
+			}
+			out[i] = int(uint16(v))
+		}
+	}
+	return out, nil
+}
+
+// cloneModbusRegisters 复制数据表，值已在设置配置时规范化
+func cloneModbusRegisters(registers modbus.ModbusRegisterMap) modbus.ModbusRegisterMap {
+	clone := func(table modbus.ModbusTable) modbus.ModbusTable {
+		return modbus.ModbusTable{Start: table.Start, Values: slices.Clone(table.Values)}
+	}
+	return modbus.ModbusRegisterMap{
+		Coils:            clone(registers.Coils),
+		DiscreteInputs:   clone(registers.DiscreteInputs),
+		HoldingRegisters: clone(registers.HoldingRegisters),
+		InputRegisters:   clone(registers.InputRegisters),
+	}
+}
+
+// modbusTableIndex 地址范围在数据表内时返回起始下标
+func modbusTableIndex(table *modbus.ModbusTable, address, quantity int) (int, bool) {
+	index := address - table.Start
+	if index < 0 || index+quantity > len(table.Values) {
+		return 0, false
+	}
+	return index, true
+}
+
+// modbusRequestSize 请求PDU长度
+func modbusRequestSize(pdu []byte) int {
+	switch pdu[0] {
+	case ModbusReadCoils, ModbusReadDiscreteInputs, ModbusReadHoldingRegisters, ModbusReadInputRegisters,
+		ModbusWriteSingleCoil, ModbusWriteSingleRegister:
+		return 5
+	case ModbusWriteMultipleCoils, ModbusWriteMultipleRegisters:
+		if len(pdu) < 6 {
+			return -1
+		}
+		return 6 + int(pdu[5])
+	default:
+		return 0
+	}
+}
+
+// modbusRequestRange 请求访问的起始地址和数量
+func modbusRequestRange(pdu []byte) (int, int, bool) {
+	if len(pdu) < 5 {
+		return 0, 0, false
+	}
+	address := int(binary.BigEndian.Uint16(pdu[1:3]))
+	switch pdu[0] {
+	case ModbusReadCoils, ModbusReadDiscreteInputs, ModbusReadHoldingRegisters, ModbusReadInputRegisters,
+		ModbusWriteMultipleCoils, ModbusWriteMultipleRegisters:
+		return address, int(binary.BigEndian.Uint16(pdu[3:5])), true
+	case ModbusWriteSingleCoil, ModbusWriteSingleRegister:
+		return address, 1, true
+	default:
+		return 0, 0, false
+	}
+}
@@ -0,0 +1,275 @@
+package core
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// MulticastManager UDP组播/广播会话管理器
+type MulticastManager struct{}
+
+var GlobalMulticastManager = &MulticastManager{}
+
+// multicastConn 组播套接字控制（根据地址族包装ipv4或ipv6）
+type multicastConn struct {
+	p4    *ipv4.PacketConn
+	p6    *ipv6.PacketConn
+	iface *net.Interface
+}
+
+// NetworkInterface 网络接口信息
+type NetworkInterface struct {
+	Name      string   `json:"name"`      // 接口名称
+	Index     int      `json:"index"`     // 接口索引
+	Multicast bool     `json:"multicast"` // 是否支持组播
+	Up        bool     `json:"up"`        // 是否启用
+	Addrs     []string `json:"addrs"`     // 接口地址
+}
+
+// GetNetworkInterfaces 获取可用于组播的网络接口列表
+func (mm *MulticastManager) GetNetworkInterfaces() ([]NetworkInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("获取网络接口失败: %v", err)
+	}
+
+	result := make([]NetworkInterface, 0, len(ifaces))
+	for _, ifi := range ifaces {
+		item := NetworkInterface{
+			Name:      ifi.Name,
+			Index:     ifi.Index,
+			Multicast: ifi.Flags&net.FlagMulticast != 0,
+			Up:        ifi.Flags&net.FlagUp != 0,
+			Addrs:     []string{},
+		}
+		if addrs, err := ifi.Addrs(); err == nil {
+			for _, addr := range addrs {
+				item.Addrs = append(item.Addrs, addr.String())
+			}
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// ListenMulticast 绑定端口并加入组播组
+// group为空时仅绑定端口，可用于发送广播
+func (mm *MulticastManager) ListenMulticast(sessionID, group string, port int, ifaceName string, ttl int, loopback bool) error {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	GlobalSessionManager.UpdateSessionStatus(sessionID, "connecting")
+
+	var groupIP net.IP
+	if group != "" {
+		groupIP = net.ParseIP(group)
+		if groupIP == nil || !groupIP.IsMulticast() {
+			GlobalSessionManager.UpdateSessionStatus(sessionID, "disconnected")
+			return fmt.Errorf("无效的组播地址: %s", group)
+		}
+	}
+
+	var ifi *net.Interface
+	if ifaceName != "" {
+		ifi, err = net.InterfaceByName(ifaceName)
+		if err != nil {
+			GlobalSessionManager.UpdateSessionStatus(sessionID, "disconnected")
+			return fmt.Errorf("网络接口不存在: %v", err)
+		}
+	}
+
+	network := "udp4"
+	if groupIP != nil && groupIP.To4() == nil {
+		network = "udp6"
+	}
+
+	conn, err := net.ListenUDP(network, &net.UDPAddr{Port: port})
+	if err != nil {
+		GlobalSessionManager.UpdateSessionStatus(sessionID, "disconnected")
+		return fmt.Errorf("监听失败: %v", err)
+	}
+
+	mc := &multicastConn{iface: ifi}
+	if network == "udp4" {
+		mc.p4 = ipv4.NewPacketConn(conn)
+	} else {
+		mc.p6 = ipv6.NewPacketConn(conn)
+	}
+
+	if err := mc.configure(ttl, loopback); err != nil {
+		conn.Close()
+		GlobalSessionManager.UpdateSessionStatus(sessionID, "disconnected")
+		return err
+	}
+
+	if groupIP != nil {
+		if err := mc.join(groupIP, ifi); err != nil {
+			conn.Close()
+			GlobalSessionManager.UpdateSessionStatus(sessionID, "disconnected")
+			return err
+		}
+		// 默认发送目标为组播组
+		sess.RemoteAddr = &net.UDPAddr{IP: groupIP, Port: port}
+	} else {
+		sess.RemoteAddr = nil
+	}
+
+	sess.PacketConn = conn
+	sess.multicast = mc
+	sess.IsActive = true
+	GlobalSessionManager.UpdateSessionStatus(sessionID, "listening")
+
+	go GlobalUDPManager.handleUDPReceive(sess)
+
+	return nil
+}
+
+// JoinGroup 会话加入组播组
+func (mm *MulticastManager) JoinGroup(sessionID, group, ifaceName string) error {
+	mc, ifi, groupIP, err := mm.resolve(sessionID, group, ifaceName)
+	if err != nil {
+		return err
+	}
+	return mc.join(groupIP, ifi)
+}
+
+// LeaveGroup 会话离开组播组
+func (mm *MulticastManager) LeaveGroup(sessionID, group, ifaceName string) error {
+	mc, ifi, groupIP, err := mm.resolve(sessionID, group, ifaceName)
+	if err != nil {
+		return err
+	}
+	return mc.leave(groupIP, ifi)
+}
+
+// DisconnectMulticast 断开组播会话
+func (mm *MulticastManager) DisconnectMulticast(sessionID string) error {
+	return GlobalUDPManager.DisconnectUDP(sessionID)
+}
+
+// CreateMulticastSession 创建组播/广播会话
+func (mm *MulticastManager) CreateMulticastSession(name, group string, port int, ifaceName string, ttl int, loopback, isHex bool) (string, error) {
+	sessionID := fmt.Sprintf("udp_%d", time.Now().UnixNano())
+
+	info := session.SessionInfo{
+		SessionID:      sessionID,
+		Type:           "udpMulticast",
+		Name:           name,
+		Status:         "disconnected",
+		Host:           group,
+		Port:           port,
+		Protocol:       "udp",
+		IsHex:          isHex,
+		ConnectTime:    0,
+		MulticastGroup: group,
+		Interface:      ifaceName,
+		TTL:            ttl,
+		Loopback:       loopback,
+	}
+
+	GlobalSessionManager.CreateSession(info)
+	return sessionID, nil
+}
+
+// resolve 解析组播会话、接口与组地址
+func (mm *MulticastManager) resolve(sessionID, group, ifaceName string) (*multicastConn, *net.Interface, net.IP, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if sess.multicast == nil {
+		return nil, nil, nil, fmt.Errorf("组播会话未连接")
+	}
+
+	groupIP := net.ParseIP(group)
+	if groupIP == nil || !groupIP.IsMulticast() {
+		return nil, nil, nil, fmt.Errorf("无效的组播地址: %s", group)
+	}
+
+	ifi := sess.multicast.iface
+	if ifaceName != "" {
+		ifi, err = net.InterfaceByName(ifaceName)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("网络接口不存在: %v", err)
+		}
+	}
+
+	return sess.multicast, ifi, groupIP, nil
+}
+
+// configure 设置TTL、回环及出口接口
+func (mc *multicastConn) configure(ttl int, loopback bool) error {
+	if mc.p4 != nil {
+		if ttl > 0 {
+			if err := mc.p4.SetMulticastTTL(ttl); err != nil {
+				return fmt.Errorf("设置TTL失败: %v", err)
+			}
+		}
+		if err := mc.p4.SetMulticastLoopback(loopback); err != nil {
+			return fmt.Errorf("设置组播回环失败: %v", err)
+		}
+		if mc.iface != nil {
+			if err := mc.p4.SetMulticastInterface(mc.iface); err != nil {
+				return fmt.Errorf("设置组播接口失败: %v", err)
+			}
+		}
+		return nil
+	}
+
+	if ttl > 0 {
+		if err := mc.p6.SetMulticastHopLimit(ttl); err != nil {
+			return fmt.Errorf("设置跳数限制失败: %v", err)
+		}
+	}
+	if err := mc.p6.SetMulticastLoopback(loopback); err != nil {
+		return fmt.Errorf("设置组播回环失败: %v", err)
+	}
+	if mc.iface != nil {
+		if err := mc.p6.SetMulticastInterface(mc.iface); err != nil {
+			return fmt.Errorf("设置组播接口失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// join 加入组播组
+func (mc *multicastConn) join(groupIP net.IP, ifi *net.Interface) error {
+	var err error
+	if mc.p4 != nil {
+		if groupIP.To4() == nil {
+			return fmt.Errorf("IPv4会话不能加入IPv6组播组")
+		}
+		err = mc.p4.JoinGroup(ifi, &net.UDPAddr{IP: groupIP})
+	} else {
+		if groupIP.To4() != nil {
+			return fmt.Errorf("IPv6会话不能加入IPv4组播组")
+		}
+		err = mc.p6.JoinGroup(ifi, &net.UDPAddr{IP: groupIP})
+	}
+	if err != nil {
+		return fmt.Errorf("加入组播组失败: %v", err)
+	}
+	return nil
+}
+
+// leave 离开组播组
+func (mc *multicastConn) leave(groupIP net.IP, ifi *net.Interface) error {
+	var err error
+	if mc.p4 != nil {
+		err = mc.p4.LeaveGroup(ifi, &net.UDPAddr{IP: groupIP})
+	} else {
+		err = mc.p6.LeaveGroup(ifi, &net.UDPAddr{IP: groupIP})
+	}
+	if err != nil {
+		return fmt.Errorf("离开组播组失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+// 消息渲染格式
+const (
+	PayloadFormatAuto = "auto" // 按记录的十六进制标记渲染，非UTF-8数据自动使用十六进制
+	PayloadFormatHex  = "hex"  // 十六进制
+	PayloadFormatText = "text" // 文本（非UTF-8字节转义为\xNN）
+	PayloadFormatBoth = "both" // 同时提供十六进制与文本
+)
+
+// DecodePayload 将界面输入的数据转换为原始字节
+func DecodePayload(data string, isHex bool) ([]byte, error) {
+	if !isHex {
+		return []byte(data), nil
+	}
+
+	// 移除空白字符
+	cleanHex := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', '\r':
+			return -1
+		}
+		return r
+	}, data)
+
+	raw, err := hex.DecodeString(cleanHex)
+	if err != nil {
+		return nil, fmt.Errorf("十六进制数据格式错误: %v", err)
+	}
+	return raw, nil
+}
+
+// EncodeHex 将原始字节格式化为空格分隔的大写十六进制
+func EncodeHex(raw []byte) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(raw) * 3)
+	for i, b := range raw {
+		if i > 0 {
+			builder.WriteByte(' ')
+		}
+		fmt.Fprintf(&builder, "%02X", b)
+	}
+	return builder.String()
+}
+
+// EncodeText 将原始字节转换为文本，无效的UTF-8字节转义为\xNN
+func EncodeText(raw []byte) string {
+	if utf8.Valid(raw) {
+		return string(raw)
+	}
+
+	var builder strings.Builder
+	for len(raw) > 0 {
+		r, size := utf8.DecodeRune(raw)
+		if r == utf8.RuneError && size == 1 {
+			fmt.Fprintf(&builder, "\\x%02X", raw[0])
+		} else {
+			builder.Write(raw[:size])
+		}
+		raw = raw[size:]
+	}
+	return builder.String()
+}
+
+// RenderRecord 按指定格式渲染消息记录
+func RenderRecord(record session.MessageRecord, format string) session.MessageRecord {
+	raw := record.Raw
+	if raw == nil {
+		// 兼容未保存原始字节的旧记录
+		decoded, err := DecodePayload(record.Data, record.IsHex)
+		if err != nil {
+			return record
+		}
+		raw = decoded
+	}
+
+	record.ByteLength = len(raw)
+	if record.Transfer != nil {
+		// 文件传输汇总记录的数据为说明文字，长度取实际传输的字节数
+		record.ByteLength = int(record.Transfer.Sent)
+	}
+	record.Hex = ""
+	record.Text = ""
+
+	switch format {
+	case PayloadFormatHex:
+		record.Data = EncodeHex(raw)
+		record.IsHex = true
+	case PayloadFormatText:
+		record.Data = EncodeText(raw)
+		record.IsHex = false
+	case PayloadFormatBoth:
+		record.Hex = EncodeHex(raw)
+		record.Text = EncodeText(raw)
+		record = renderAuto(record, raw)
+	default:
+		record = renderAuto(record, raw)
+	}
+
+	return record
+}
+
+// ClientRecord 返回给前端的记录，去掉原始字节，数据已按格式渲染在data/hex/text中
+func ClientRecord(record session.MessageRecord) session.MessageRecord {
+	record.Raw = nil
+	return record
+}
+
+// renderAuto 十六进制记录或非UTF-8数据以十六进制渲染，其余以文本渲染
+func renderAuto(record session.MessageRecord, raw []byte) session.MessageRecord {
+	if record.IsHex || !utf8.Valid(raw) {
+		record.Data = EncodeHex(raw)
+		record.IsHex = true
+	} else {
+		record.Data = string(raw)
+	}
+	return record
+}
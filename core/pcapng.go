@@ -0,0 +1,429 @@
+package core
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+// pcapng块类型
+const (
+	pcapngBlockSHB = 0x0A0D0D0A // Section Header Block
+	pcapngBlockIDB = 0x00000001 // Interface Description Block
+	pcapngBlockEPB = 0x00000006 // Enhanced Packet Block
+)
+
+// pcapng选项
+const (
+	pcapngOptEnd      = 0
+	pcapngOptComment  = 1
+	pcapngOptUserAppl = 4 // shb_userappl
+	pcapngOptIfName   = 2 // if_name
+	pcapngOptIfDesc   = 3 // if_description
+	pcapngOptEPBFlags = 2 // epb_flags
+
+	pcapngFlagInbound  = 0x1
+	pcapngFlagOutbound = 0x2
+)
+
+// 链路类型
+const (
+	linkTypeEthernet = 1
+	linkTypeUser0    = 147 // 串口数据使用用户自定义DLT
+)
+
+// 合成报文参数
+const (
+	pcapMaxSegment = 65000 // 单个合成报文的最大负载，超出时拆分
+	ipProtoTCP     = 6
+	ipProtoUDP     = 17
+)
+
+var (
+	pcapLocalMAC  = []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	pcapRemoteMAC = []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x02}
+)
+
+// pcapEndpoint 合成报文的端点
+type pcapEndpoint struct {
+	IP   net.IP
+	Port uint16
+}
+
+// pcapngMessageWriter pcapng格式，TCP/UDP会话合成以太网/IP/TCP-UDP报文头，串口会话使用用户DLT
+type pcapngMessageWriter struct {
+	w       io.Writer
+	info    session.SessionInfo
+	started bool
+	ipID    uint16
+	seq     map[string]uint32 // 每个方向的TCP序列号 src->dst
+	hosts   map[string]net.IP // 主机名 -> 占位地址
+}
+
+func newPcapngMessageWriter(w io.Writer, info session.SessionInfo) *pcapngMessageWriter {
+	return &pcapngMessageWriter{
+		w:     w,
+		info:  info,
+		seq:   make(map[string]uint32),
+		hosts: make(map[string]net.IP),
+	}
+}
+
+func (pw *pcapngMessageWriter) WriteRecord(record session.MessageRecord) error {
+	// 发送文件的汇总记录不是实际收发的数据
+	if record.Transfer != nil {
+		return nil
+	}
+
+	if err := pw.writeHeader(); err != nil {
+		return err
+	}
+
+	raw, err := recordRaw(record)
+	if err != nil {
+		return err
+	}
+
+	flags := uint32(pcapngFlagInbound)
+	if record.Direction == "send" {
+		flags = pcapngFlagOutbound
+	}
+	timestamp := uint64(record.Timestamp) * 1000 // 微秒
+
+	if pw.isSerial() {
+		comment := exportDirectionLabel(record.Direction) + exportChecksumLabel(record.ChecksumStatus) + exportRuleLabel(record.Rule)
+		return pw.writePacket(timestamp, raw, flags, comment)
+	}
+
+	local, remote := pw.endpoints(record)
+	src, dst := local, remote
+	srcMAC, dstMAC := pcapLocalMAC, pcapRemoteMAC
+	if record.Direction != "send" {
+		src, dst = remote, local
+		srcMAC, dstMAC = pcapRemoteMAC, pcapLocalMAC
+	}
+
+	comment := strings.TrimSpace(exportChecksumLabel(record.ChecksumStatus) + exportRuleLabel(record.Rule))
+	for {
+		chunk := raw
+		if len(chunk) > pcapMaxSegment {
+			chunk = raw[:pcapMaxSegment]
+		}
+
+		frame := pw.buildFrame(srcMAC, dstMAC, src, dst, chunk)
+		if err := pw.writePacket(timestamp, frame, flags, comment); err != nil {
+			return err
+		}
+
+		raw = raw[len(chunk):]
+		if len(raw) == 0 {
+			return nil
+		}
+	}
+}
+
+func (pw *pcapngMessageWriter) Flush() error {
+	// 没有消息时也输出有效的空文件
+	return pw.writeHeader()
+}
+
+// isSerial 是否为串口会话
+func (pw *pcapngMessageWriter) isSerial() bool {
+	return pw.info.Type == "serial"
+}
+
+// isTCP 是否为TCP/TLS会话
+func (pw *pcapngMessageWriter) isTCP() bool {
+	switch pw.info.Type {
+	case "tcpClient", "tcpServer", "tlsClient", "tlsServer":
+		return true
+	}
+	return false
+}
+
+// writeHeader 输出Section Header和Interface Description块
+func (pw *pcapngMessageWriter) writeHeader() error {
+	if pw.started {
+		return nil
+	}
+	pw.started = true
+
+	var shb []byte
+	shb = binary.LittleEndian.AppendUint32(shb, 0x1A2B3C4D)
+	shb = binary.LittleEndian.AppendUint16(shb, 1)
+	shb = binary.LittleEndian.AppendUint16(shb, 0)
+	shb = binary.LittleEndian.AppendUint64(shb, 0xFFFFFFFFFFFFFFFF) // 未指定段长度
+	shb = appendPcapngOption(shb, pcapngOptUserAppl, []byte("Netser"))
+	shb = appendPcapngOption(shb, pcapngOptEnd, nil)
+	if err := pw.writeBlock(pcapngBlockSHB, shb); err != nil {
+		return err
+	}
+
+	linkType := uint16(linkTypeEthernet)
+	desc := pw.info.Type
+	if pw.isSerial() {
+		linkType = linkTypeUser0
+		desc = pw.info.SerialPort
+	} else if pw.info.Host != "" {
+		desc = pw.info.Type + " " + net.JoinHostPort(pw.info.Host, strconv.Itoa(pw.info.Port))
+	}
+
+	var idb []byte
+	idb = binary.LittleEndian.AppendUint16(idb, linkType)
+	idb = binary.LittleEndian.AppendUint16(idb, 0)
+	idb = binary.LittleEndian.AppendUint32(idb, 0) // 不限制抓包长度
+	if name := pw.info.Name; name != "" {
+		idb = appendPcapngOption(idb, pcapngOptIfName, []byte(name))
+	}
+	if desc != "" {
+		idb = appendPcapngOption(idb, pcapngOptIfDesc, []byte(desc))
+	}
+	idb = appendPcapngOption(idb, pcapngOptEnd, nil)
+	return pw.writeBlock(pcapngBlockIDB, idb)
+}
+
+// writePacket 输出Enhanced Packet块，时间戳单位为微秒
+func (pw *pcapngMessageWriter) writePacket(timestamp uint64, data []byte, flags uint32, comment string) error {
+	var epb []byte
+	epb = binary.LittleEndian.AppendUint32(epb, 0) // 接口ID
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(timestamp>>32))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(timestamp))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(len(data)))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(len(data)))
+	epb = append(epb, data...)
+	epb = appendPcapngPadding(epb)
+
+	if comment != "" {
+		epb = appendPcapngOption(epb, pcapngOptComment, []byte(comment))
+	}
+	epb = appendPcapngOption(epb, pcapngOptEPBFlags, binary.LittleEndian.AppendUint32(nil, flags))
+	epb = appendPcapngOption(epb, pcapngOptEnd, nil)
+	return pw.writeBlock(pcapngBlockEPB, epb)
+}
+
+// writeBlock 输出一个pcapng块，body需已按4字节对齐
+func (pw *pcapngMessageWriter) writeBlock(blockType uint32, body []byte) error {
+	total := uint32(len(body) + 12)
+
+	var block []byte
+	block = binary.LittleEndian.AppendUint32(block, blockType)
+	block = binary.LittleEndian.AppendUint32(block, total)
+	block = append(block, body...)
+	block = binary.LittleEndian.AppendUint32(block, total)
+
+	_, err := pw.w.Write(block)
+	return err
+}
+
+// appendPcapngOption 追加一个选项，值按4字节对齐
+func appendPcapngOption(buf []byte, code uint16, value []byte) []byte {
+	buf = binary.LittleEndian.AppendUint16(buf, code)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(value)))
+	buf = append(buf, value...)
+	return appendPcapngPadding(buf)
+}
+
+// appendPcapngPadding 补齐到4字节边界
+func appendPcapngPadding(buf []byte) []byte {
+	for len(buf)%4 != 0 {
+		buf = append(buf, 0)
+	}
+	return buf
+}
+
+// endpoints 获取消息的本端与对端地址，缺失时使用会话配置或未指定地址
+func (pw *pcapngMessageWriter) endpoints(record session.MessageRecord) (pcapEndpoint, pcapEndpoint) {
+	remote := pw.parseEndpoint(record.RemoteAddr)
+	if remote.IP == nil && pw.info.Host != "" {
+		// TCP客户端的消息不记录对端地址，使用会话的目标地址
+		remote = pw.parseEndpoint(net.JoinHostPort(pw.info.Host, strconv.Itoa(pw.info.Port)))
+	}
+	local := pw.parseEndpoint(record.LocalAddr)
+
+	// 两端地址族保持一致
+	if local.IP == nil {
+		local.IP = net.IPv4zero
+	}
+	if remote.IP == nil {
+		remote.IP = net.IPv4zero
+	}
+	if local.IP.To4() == nil || remote.IP.To4() == nil {
+		if local.IP.Equal(net.IPv4zero) {
+			local.IP = net.IPv6unspecified
+		}
+		if remote.IP.Equal(net.IPv4zero) {
+			remote.IP = net.IPv6unspecified
+		}
+		local.IP, remote.IP = local.IP.To16(), remote.IP.To16()
+	} else {
+		local.IP, remote.IP = local.IP.To4(), remote.IP.To4()
+	}
+	return local, remote
+}
+
+// parseEndpoint 解析host:port，主机名不做DNS查询，按出现顺序分配文档保留地址(192.0.2.0/24)作为占位
+func (pw *pcapngMessageWriter) parseEndpoint(addr string) pcapEndpoint {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return pcapEndpoint{}
+	}
+	port, _ := strconv.ParseUint(portStr, 10, 16)
+
+	// 去除IPv6链路本地地址的接口后缀
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		host = host[:i]
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil && host != "" {
+		placeholder, ok := pw.hosts[host]
+		if !ok {
+			placeholder = net.IPv4(192, 0, 2, byte(len(pw.hosts)%254+1))
+			pw.hosts[host] = placeholder
+		}
+		ip = placeholder
+	}
+	return pcapEndpoint{IP: ip, Port: uint16(port)}
+}
+
+// buildFrame 合成以太网帧
+func (pw *pcapngMessageWriter) buildFrame(srcMAC, dstMAC []byte, src, dst pcapEndpoint, payload []byte) []byte {
+	proto := byte(ipProtoUDP)
+	if pw.isTCP() {
+		proto = ipProtoTCP
+	}
+
+	var segment []byte
+	if proto == ipProtoTCP {
+		segment = pw.buildTCPSegment(src, dst, payload)
+	} else {
+		segment = buildUDPDatagram(src, dst, payload)
+	}
+
+	var frame []byte
+	frame = append(frame, dstMAC...)
+	frame = append(frame, srcMAC...)
+	if src.IP.To4() != nil {
+		frame = binary.BigEndian.AppendUint16(frame, 0x0800)
+		frame = append(frame, pw.buildIPv4Header(src.IP, dst.IP, proto, len(segment))...)
+	} else {
+		frame = binary.BigEndian.AppendUint16(frame, 0x86DD)
+		frame = append(frame, buildIPv6Header(src.IP, dst.IP, proto, len(segment))...)
+	}
+	return append(frame, segment...)
+}
+
+// buildIPv4Header 合成IPv4头
+func (pw *pcapngMessageWriter) buildIPv4Header(src, dst net.IP, proto byte, payloadLen int) []byte {
+	pw.ipID++
+
+	header := make([]byte, 20)
+	header[0] = 0x45
+	binary.BigEndian.PutUint16(header[2:], uint16(20+payloadLen))
+	binary.BigEndian.PutUint16(header[4:], pw.ipID)
+	binary.BigEndian.PutUint16(header[6:], 0x4000) // DF
+	header[8] = 64
+	header[9] = proto
+	copy(header[12:16], src.To4())
+	copy(header[16:20], dst.To4())
+	binary.BigEndian.PutUint16(header[10:], internetChecksum(header))
+	return header
+}
+
+// buildIPv6Header 合成IPv6头
+func buildIPv6Header(src, dst net.IP, proto byte, payloadLen int) []byte {
+	header := make([]byte, 40)
+	header[0] = 0x60
+	binary.BigEndian.PutUint16(header[4:], uint16(payloadLen))
+	header[6] = proto
+	header[7] = 64
+	copy(header[8:24], src.To16())
+	copy(header[24:40], dst.To16())
+	return header
+}
+
+// buildTCPSegment 合成TCP段，按方向累计序列号使Wireshark可重组数据流
+func (pw *pcapngMessageWriter) buildTCPSegment(src, dst pcapEndpoint, payload []byte) []byte {
+	forward := pcapFlowKey(src, dst)
+	reverse := pcapFlowKey(dst, src)
+	seq, ok := pw.seq[forward]
+	if !ok {
+		seq = 1
+	}
+	ack, ok := pw.seq[reverse]
+	if !ok {
+		ack = 1
+	}
+	pw.seq[forward] = seq + uint32(len(payload))
+
+	segment := make([]byte, 20, 20+len(payload))
+	binary.BigEndian.PutUint16(segment[0:], src.Port)
+	binary.BigEndian.PutUint16(segment[2:], dst.Port)
+	binary.BigEndian.PutUint32(segment[4:], seq)
+	binary.BigEndian.PutUint32(segment[8:], ack)
+	segment[12] = 5 << 4
+	segment[13] = 0x18 // PSH|ACK
+	binary.BigEndian.PutUint16(segment[14:], 65535)
+	segment = append(segment, payload...)
+
+	binary.BigEndian.PutUint16(segment[16:], transportChecksum(src.IP, dst.IP, ipProtoTCP, segment))
+	return segment
+}
+
+// buildUDPDatagram 合成UDP数据报
+func buildUDPDatagram(src, dst pcapEndpoint, payload []byte) []byte {
+	datagram := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint16(datagram[0:], src.Port)
+	binary.BigEndian.PutUint16(datagram[2:], dst.Port)
+	binary.BigEndian.PutUint16(datagram[4:], uint16(8+len(payload)))
+	datagram = append(datagram, payload...)
+
+	checksum := transportChecksum(src.IP, dst.IP, ipProtoUDP, datagram)
+	if checksum == 0 {
+		checksum = 0xFFFF
+	}
+	binary.BigEndian.PutUint16(datagram[6:], checksum)
+	return datagram
+}
+
+// pcapFlowKey TCP单向数据流标识
+func pcapFlowKey(src, dst pcapEndpoint) string {
+	return net.JoinHostPort(src.IP.String(), strconv.Itoa(int(src.Port))) + ">" +
+		net.JoinHostPort(dst.IP.String(), strconv.Itoa(int(dst.Port)))
+}
+
+// transportChecksum 计算含伪首部的TCP/UDP校验和
+func transportChecksum(src, dst net.IP, proto byte, segment []byte) uint16 {
+	var pseudo []byte
+	if src4, dst4 := src.To4(), dst.To4(); src4 != nil && dst4 != nil {
+		pseudo = append(pseudo, src4...)
+		pseudo = append(pseudo, dst4...)
+		pseudo = append(pseudo, 0, proto)
+		pseudo = binary.BigEndian.AppendUint16(pseudo, uint16(len(segment)))
+	} else {
+		pseudo = append(pseudo, src.To16()...)
+		pseudo = append(pseudo, dst.To16()...)
+		pseudo = binary.BigEndian.AppendUint32(pseudo, uint32(len(segment)))
+		pseudo = append(pseudo, 0, 0, 0, proto)
+	}
+	return internetChecksum(append(pseudo, segment...))
+}
+
+// internetChecksum RFC 1071反码和校验
+func internetChecksum(data []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = (sum & 0xFFFF) + (sum >> 16)
+	}
+	return ^uint16(sum)
+}
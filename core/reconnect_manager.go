@@ -0,0 +1,242 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+const (
+	// 默认首次重连延迟（毫秒）
+	defaultReconnectInitialDelay = 1000
+	// 默认最大重连延迟（毫秒）
+	defaultReconnectMaxDelay = 30000
+	// 默认退避倍数
+	defaultReconnectFactor = 2.0
+)
+
+// ReconnectManager 客户端会话自动重连管理器
+type ReconnectManager struct{}
+
+var GlobalReconnectManager = &ReconnectManager{}
+
+// SetPolicy 设置会话的重连策略
+func (rm *ReconnectManager) SetPolicy(sessionID string, policy *session.ReconnectPolicy) error {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if !supportsReconnect(sess.Info.Type) {
+		return fmt.Errorf("会话类型 %s 不支持自动重连", sess.Info.Type)
+	}
+
+	sess.Info.Reconnect = policy
+	GlobalSessionManager.PersistSessions()
+
+	// 关闭策略时停止正在进行的重连
+	if policy == nil || !policy.Enabled {
+		rm.Cancel(sess)
+	}
+	return nil
+}
+
+// Schedule 连接意外断开后按策略开始重连
+func (rm *ReconnectManager) Schedule(sess *Session, cause error) {
+	policy := sess.Info.Reconnect
+	if policy == nil || !policy.Enabled || !supportsReconnect(sess.Info.Type) {
+		return
+	}
+
+	lastError := ""
+	if cause != nil {
+		lastError = cause.Error()
+	}
+	rm.start(sess, *policy, lastError)
+}
+
+// TriggerNow 立即重连意外断开的串口会话，跳过当前的退避等待，返回是否已触发；用于绑定的USB设备以新路径重新插入时。
+// 没有正在进行的重连(如未启用重连策略)时只尝试一次
+func (rm *ReconnectManager) TriggerNow(sess *Session, portName string) bool {
+	sess.mutex.Lock()
+	if sess.Connection != nil || !sess.serialLost {
+		sess.mutex.Unlock()
+		return false
+	}
+	sess.Info.SerialPort = portName
+	wake := sess.reconnectWake
+	policy := session.ReconnectPolicy{Enabled: true, MaxAttempts: 1}
+	if sess.Info.Reconnect != nil && sess.Info.Reconnect.Enabled {
+		policy = *sess.Info.Reconnect
+	}
+	lastError := sess.Info.LastError
+	sess.mutex.Unlock()
+
+	if wake == nil {
+		wake = rm.start(sess, policy, lastError)
+	}
+
+	select {
+	case wake <- struct{}{}:
+	default:
+		// 已有待处理的唤醒
+	}
+	return true
+}
+
+// start 启动重连循环，返回唤醒通道；已在重连中时返回正在进行的重连的唤醒通道
+func (rm *ReconnectManager) start(sess *Session, policy session.ReconnectPolicy, lastError string) chan struct{} {
+	sess.mutex.Lock()
+	defer sess.mutex.Unlock()
+
+	if sess.reconnectStop != nil {
+		// 已在重连中
+		return sess.reconnectWake
+	}
+	stop := make(chan struct{})
+	wake := make(chan struct{}, 1)
+	sess.reconnectStop = stop
+	sess.reconnectWake = wake
+	sess.Info.LastError = lastError
+	sess.Info.ReconnectAttempts = 0
+
+	go rm.run(sess, policy, stop, wake)
+	return wake
+}
+
+// Cancel 停止会话正在进行的重连
+func (rm *ReconnectManager) Cancel(sess *Session) {
+	sess.mutex.Lock()
+	defer sess.mutex.Unlock()
+
+	if sess.reconnectStop != nil {
+		close(sess.reconnectStop)
+		sess.reconnectStop = nil
+		sess.reconnectWake = nil
+	}
+	sess.serialLost = false
+}
+
+// run 重连循环，wake收到信号时跳过等待立即重连
+func (rm *ReconnectManager) run(sess *Session, policy session.ReconnectPolicy, stop, wake chan struct{}) {
+	sessionID := sess.Info.SessionID
+	defer func() {
+		sess.mutex.Lock()
+		if sess.reconnectStop == stop {
+			sess.reconnectStop = nil
+			sess.reconnectWake = nil
+		}
+		sess.mutex.Unlock()
+	}()
+
+	for attempt := 1; policy.MaxAttempts <= 0 || attempt <= policy.MaxAttempts; attempt++ {
+		sess.mutex.Lock()
+		sess.Info.ReconnectAttempts = attempt
+		sess.mutex.Unlock()
+		GlobalSessionManager.UpdateSessionStatus(sessionID, "reconnecting")
+
+		delay := reconnectDelay(policy, attempt)
+		log.Printf("会话 %s 将在 %v 后进行第 %d 次重连", sessionID, delay, attempt)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-stop:
+			timer.Stop()
+			log.Printf("会话 %s 重连已取消", sessionID)
+			return
+		case <-wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+
+		err := rm.reconnect(sess)
+
+		// 连接过程中被主动断开时丢弃新建立的连接
+		select {
+		case <-stop:
+			if err == nil {
+				rm.discard(sess)
+			}
+			log.Printf("会话 %s 重连已取消", sessionID)
+			return
+		default:
+		}
+
+		if err == nil {
+			log.Printf("会话 %s 第 %d 次重连成功", sessionID, attempt)
+			sess.mutex.Lock()
+			sess.Info.ReconnectAttempts = 0
+			sess.Info.LastError = ""
+			sess.mutex.Unlock()
+			GlobalSessionManager.UpdateSessionStatus(sessionID, "connected")
+			return
+		}
+
+		sess.mutex.Lock()
+		sess.Info.LastError = err.Error()
+		sess.mutex.Unlock()
+		log.Printf("会话 %s 第 %d 次重连失败: %v", sessionID, attempt, err)
+	}
+
+	// 放弃重连，与正常断开区分
+	log.Printf("会话 %s 重连次数已达上限", sessionID)
+	GlobalSessionManager.UpdateSessionStatus(sessionID, "reconnectFailed")
+}
+
+// reconnect 按会话类型重新建立连接，连接过程不单独更新状态
+func (rm *ReconnectManager) reconnect(sess *Session) error {
+	info := sess.Info
+	switch info.Type {
+	case "tcpClient":
+		return GlobalTCPManager.connectTCP(info.SessionID, info.Host, info.Port, 5, false)
+	case "tlsClient":
+		return GlobalTLSManager.connectTLS(info.SessionID, info.Host, info.Port, 5, nil, false)
+	case "serial":
+		return GlobalSerialManager.ConnectSerial(info.SessionID, info.SerialConfig)
+	default:
+		return fmt.Errorf("会话类型 %s 不支持自动重连", info.Type)
+	}
+}
+
+// discard 关闭重连取消后才建立完成的连接，接收协程随之结束
+func (rm *ReconnectManager) discard(sess *Session) {
+	sess.IsActive = false
+	if sess.Connection != nil {
+		sess.Connection.Close()
+		sess.Connection = nil
+	}
+}
+
+// reconnectDelay 计算第attempt次重连的退避延迟
+func reconnectDelay(policy session.ReconnectPolicy, attempt int) time.Duration {
+	initial := float64(policy.InitialDelay)
+	if initial <= 0 {
+		initial = defaultReconnectInitialDelay
+	}
+	maxDelay := float64(policy.MaxDelay)
+	if maxDelay <= 0 {
+		maxDelay = defaultReconnectMaxDelay
+	}
+	factor := policy.Factor
+	if factor < 1 {
+		factor = defaultReconnectFactor
+	}
+
+	delay := initial * math.Pow(factor, float64(attempt-1))
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return time.Duration(delay) * time.Millisecond
+}
+
+// supportsReconnect 是否为支持自动重连的客户端会话类型
+func supportsReconnect(sessionType string) bool {
+	switch sessionType {
+	case "tcpClient", "tlsClient", "serial":
+		return true
+	}
+	return false
+}
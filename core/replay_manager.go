@@ -0,0 +1,322 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+const (
+	// 等待应答默认超时（毫秒）
+	defaultReplayResponseTimeout = 1000
+	// 保留的不匹配明细数量
+	maxReplayMismatches = 100
+)
+
+// ReplayManager 流量回放管理器
+type ReplayManager struct{}
+
+var GlobalReplayManager = &ReplayManager{}
+
+// replayTask 正在运行的回放
+type replayTask struct {
+	sessionTask
+	step chan struct{} // 单步回放信号，非单步模式时为空
+}
+
+// replayTasks 会话上正在运行的回放
+var replayTasks = &taskSlot[*replayTask, session.ReplayState]{
+	task:  func(sess *Session) **replayTask { return &sess.replay },
+	state: func(sess *Session) **session.ReplayState { return &sess.Info.Replay },
+	halt:  func(state *session.ReplayState) { state.Running = false },
+}
+
+// replayStep 一条待发送的消息及录制中紧随其后的应答
+type replayStep struct {
+	Timestamp int64
+	Data      []byte
+	IsHex     bool
+	Expected  []byte
+}
+
+// Start 将录制流量中的发送消息回放到会话，已在运行时替换为新的回放
+func (rm *ReplayManager) Start(sessionID string, cfg session.ReplayConfig) (*session.ReplayState, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Speed < 0 {
+		return nil, fmt.Errorf("回放速度不能为负数")
+	}
+	if cfg.Speed == 0 {
+		cfg.Speed = 1
+	}
+	if cfg.ResponseTimeout < 0 {
+		return nil, fmt.Errorf("应答超时不能为负数")
+	}
+	if cfg.ResponseTimeout == 0 {
+		cfg.ResponseTimeout = defaultReplayResponseTimeout
+	}
+
+	records, err := loadReplayRecords(cfg)
+	if err != nil {
+		return nil, err
+	}
+	steps := buildReplaySteps(records)
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("没有可回放的发送消息")
+	}
+
+	state := &session.ReplayState{
+		ReplayConfig: cfg,
+		Running:      true,
+		Total:        len(steps),
+		StartTime:    time.Now().UnixMilli(),
+	}
+	task := &replayTask{sessionTask: newSessionTask()}
+	if cfg.Step {
+		// 不带缓冲，只有回放协程正在等待时才能送达
+		task.step = make(chan struct{})
+	}
+	replayTasks.start(sess, task, state)
+
+	log.Printf("会话 %s 开始回放 %d 条消息，速度 %.2fx，单步 %v", sessionID, len(steps), cfg.Speed, cfg.Step)
+	go rm.run(sess, task, steps, *state)
+
+	return state, nil
+}
+
+// Step 单步模式下发送下一条消息，回放未在等待单步时返回错误
+func (rm *ReplayManager) Step(sessionID string) error {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	sess.mutex.RLock()
+	var step chan struct{}
+	if sess.replay != nil {
+		step = sess.replay.step
+	}
+	sess.mutex.RUnlock()
+
+	if step == nil {
+		return fmt.Errorf("没有正在进行的单步回放")
+	}
+
+	select {
+	case step <- struct{}{}:
+		return nil
+	default:
+		return fmt.Errorf("上一条消息尚未发送完成")
+	}
+}
+
+// Stop 停止会话的流量回放
+func (rm *ReplayManager) Stop(sessionID string) (*session.ReplayState, error) {
+	return replayTasks.stop(sessionID)
+}
+
+// GetState 获取会话的回放状态，未启动过时返回空状态
+func (rm *ReplayManager) GetState(sessionID string) (*session.ReplayState, error) {
+	return replayTasks.get(sessionID)
+}
+
+// loadReplayRecords 读取回放来源的消息记录
+func loadReplayRecords(cfg session.ReplayConfig) ([]session.MessageRecord, error) {
+	if cfg.SourceSessionID != "" {
+		if _, err := GlobalSessionManager.GetSession(cfg.SourceSessionID); err != nil {
+			return nil, err
+		}
+		return GetMessagesFromDB(cfg.SourceSessionID, 0, 0)
+	}
+	if cfg.File != "" {
+		return ImportMessages(cfg.File)
+	}
+	return nil, fmt.Errorf("请指定回放来源会话或导入文件")
+}
+
+// buildReplaySteps 将消息记录整理为发送步骤，发送后到下一次发送前收到的数据作为期望应答
+func buildReplaySteps(records []session.MessageRecord) []replayStep {
+	var steps []replayStep
+	for _, record := range records {
+		// 发送文件的汇总记录不是实际发送的数据
+		if record.Transfer != nil {
+			continue
+		}
+
+		raw, err := recordRaw(record)
+		if err != nil {
+			log.Printf("跳过无法解析的回放消息: %v", err)
+			continue
+		}
+
+		switch record.Direction {
+		case "send":
+			steps = append(steps, replayStep{Timestamp: record.Timestamp, Data: raw, IsHex: record.IsHex})
+		case "receive":
+			// 首次发送之前收到的数据不作为应答
+			if len(steps) > 0 {
+				last := &steps[len(steps)-1]
+				last.Expected = append(last.Expected, raw...)
+			}
+		}
+	}
+	return steps
+}
+
+// run 回放循环
+func (rm *ReplayManager) run(sess *Session, task *replayTask, steps []replayStep, state session.ReplayState) {
+	sessionID := sess.Info.SessionID
+
+	defer func() {
+		state.Running = false
+		replayTasks.finish(sess, task, state)
+
+		log.Printf("会话 %s 回放结束，发送 %d/%d 条，应答匹配 %d 条，不匹配 %d 条", sessionID, state.Sent, state.Total, state.Matched, state.Mismatched)
+		if GlobalWebSocketManager != nil {
+			GlobalWebSocketManager.NotifyReplay(sessionID, state, nil)
+		}
+	}()
+
+	var collector *receiveCollector
+	if state.WaitResponse {
+		collector = newReceiveCollector()
+		unsubscribe := sess.observe(collector.observe)
+		defer unsubscribe()
+	}
+
+	start := time.Now()
+	for i, current := range steps {
+		if task.step != nil {
+			select {
+			case <-task.stop:
+				return
+			case <-task.step:
+			}
+		} else {
+			// 按原始时间间隔和速度计算发送时间
+			offset := time.Duration(float64(current.Timestamp-steps[0].Timestamp) / state.Speed * float64(time.Millisecond))
+			if wait := time.Until(start.Add(offset)); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-task.stop:
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+		}
+
+		if collector != nil {
+			collector.reset()
+		}
+
+		var mismatch *session.ReplayMismatch
+		_, err := GlobalSessionManager.SendBytes(sessionID, current.Data, current.IsHex, state.Target)
+		if err != nil {
+			state.Failed++
+			state.LastError = err.Error()
+		} else {
+			state.Sent++
+
+			if collector != nil && len(current.Expected) > 0 {
+				timeout := time.Duration(state.ResponseTimeout) * time.Millisecond
+				actual, complete, stopped := collector.wait(len(current.Expected), timeout, task.stop)
+				if stopped {
+					return
+				}
+
+				if complete && bytes.Equal(actual[:len(current.Expected)], current.Expected) {
+					state.Matched++
+				} else {
+					state.Mismatched++
+					mismatch = &session.ReplayMismatch{
+						Index:    i,
+						Expected: EncodeHex(current.Expected),
+						Actual:   EncodeHex(actual),
+						Timeout:  !complete,
+					}
+					if len(state.Mismatches) < maxReplayMismatches {
+						state.Mismatches = append(state.Mismatches, *mismatch)
+					}
+				}
+			}
+		}
+
+		// 更新状态并推送进度
+		snapshot := state
+		snapshot.Mismatches = append([]session.ReplayMismatch(nil), state.Mismatches...)
+		replayTasks.publish(sess, task, snapshot)
+		if GlobalWebSocketManager != nil {
+			GlobalWebSocketManager.NotifyReplay(sessionID, snapshot, mismatch)
+		}
+	}
+}
+
+// receiveCollector 收集会话收到的数据，用于等待应答
+type receiveCollector struct {
+	buffer []byte
+	signal chan struct{}
+	mutex  sync.Mutex
+}
+
+func newReceiveCollector() *receiveCollector {
+	return &receiveCollector{signal: make(chan struct{}, 1)}
+}
+
+// observe 会话消息订阅回调
+func (rc *receiveCollector) observe(record session.MessageRecord) {
+	if record.Direction != "receive" {
+		return
+	}
+
+	rc.mutex.Lock()
+	rc.buffer = append(rc.buffer, record.Raw...)
+	rc.mutex.Unlock()
+
+	select {
+	case rc.signal <- struct{}{}:
+	default:
+	}
+}
+
+// reset 丢弃已收到的数据
+func (rc *receiveCollector) reset() {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.buffer = nil
+}
+
+// wait 等待收到至少n字节，返回已收到的数据、是否收齐及是否被停止
+func (rc *receiveCollector) wait(n int, timeout time.Duration, stop chan struct{}) ([]byte, bool, bool) {
+	return rc.waitUntil(func(received []byte) bool { return len(received) >= n }, timeout, stop)
+}
+
+// waitUntil 等待收到的数据满足条件，返回已收到的数据、是否满足及是否被停止
+func (rc *receiveCollector) waitUntil(done func([]byte) bool, timeout time.Duration, stop chan struct{}) ([]byte, bool, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		rc.mutex.Lock()
+		received := append([]byte(nil), rc.buffer...)
+		rc.mutex.Unlock()
+		if done(received) {
+			return received, true, false
+		}
+
+		select {
+		case <-stop:
+			return nil, false, true
+		case <-timer.C:
+			return received, false, false
+		case <-rc.signal:
+		}
+	}
+}
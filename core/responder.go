@@ -0,0 +1,341 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+// 自动应答匹配方式
+const (
+	MatchExact  = "exact"  // 完全相同
+	MatchPrefix = "prefix" // 前缀匹配，$1为剩余部分
+	MatchHex    = "hex"    // 十六进制模式，??匹配任意单字节，连续通配字节为一个分组
+	MatchRegex  = "regex"  // 正则表达式
+)
+
+// responder 已编译的会话自动应答规则
+type responder struct {
+	cfg   *session.ResponderConfig
+	rules []*responderRule
+	mutex sync.Mutex
+}
+
+// responderRule 已编译的应答规则
+type responderRule struct {
+	session.ResponderRule
+	pattern []byte         // exact/prefix/hex的匹配字节
+	mask    []bool         // hex模式下为true的字节为通配
+	regex   *regexp.Regexp // regex模式
+	reply   []replySegment // 应答内容
+	hits    int            // 触发次数
+}
+
+// replySegment 应答模板片段，field为空时为固定内容
+type replySegment struct {
+	literal []byte
+	field   string
+}
+
+// compileResponder 编译自动应答配置
+func compileResponder(cfg *session.ResponderConfig) (*responder, error) {
+	r := &responder{cfg: cfg}
+	if cfg == nil {
+		return r, nil
+	}
+
+	for i, rule := range cfg.Rules {
+		compiled, err := compileResponderRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("规则%d(%s)无效: %v", i+1, responderRuleLabel(rule), err)
+		}
+		r.rules = append(r.rules, compiled)
+	}
+	return r, nil
+}
+
+// compileResponderRule 编译单条应答规则
+func compileResponderRule(rule session.ResponderRule) (*responderRule, error) {
+	compiled := &responderRule{ResponderRule: rule}
+
+	if rule.Delay < 0 {
+		return nil, fmt.Errorf("应答延迟不能为负数")
+	}
+
+	var err error
+	switch rule.MatchType {
+	case MatchExact, MatchPrefix:
+		compiled.pattern, err = DecodePayload(rule.Pattern, rule.PatternIsHex)
+		if err == nil && len(compiled.pattern) == 0 {
+			err = fmt.Errorf("匹配内容不能为空")
+		}
+	case MatchHex:
+		compiled.pattern, compiled.mask, err = parseHexPattern(rule.Pattern)
+	case MatchRegex:
+		compiled.regex, err = regexp.Compile(rule.Pattern)
+	default:
+		err = fmt.Errorf("不支持的匹配方式: %s", rule.MatchType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	compiled.reply, err = parseReplyTemplate(rule.Reply, rule.ReplyIsHex, rule.Template)
+	if err != nil {
+		return nil, err
+	}
+	return compiled, nil
+}
+
+// parseHexPattern 解析十六进制匹配模式，例如 "01 03 ?? ?? 00 01"
+func parseHexPattern(pattern string) ([]byte, []bool, error) {
+	clean := strings.Join(strings.Fields(pattern), "")
+	if clean == "" {
+		return nil, nil, fmt.Errorf("匹配内容不能为空")
+	}
+	if len(clean)%2 != 0 {
+		return nil, nil, fmt.Errorf("十六进制模式长度必须为偶数")
+	}
+
+	data := make([]byte, len(clean)/2)
+	mask := make([]bool, len(clean)/2)
+	for i := range data {
+		token := clean[i*2 : i*2+2]
+		if token == "??" {
+			mask[i] = true
+			continue
+		}
+		b, err := hex.DecodeString(token)
+		if err != nil {
+			return nil, nil, fmt.Errorf("十六进制模式格式错误: %s", token)
+		}
+		data[i] = b[0]
+	}
+	return data, mask, nil
+}
+
+// parseReplyTemplate 解析应答内容，固定内容按isHex解码，{{...}}为模板字段
+func parseReplyTemplate(reply string, isHex, template bool) ([]replySegment, error) {
+	if !template {
+		data, err := DecodePayload(reply, isHex)
+		if err != nil {
+			return nil, err
+		}
+		return []replySegment{{literal: data}}, nil
+	}
+
+	var segments []replySegment
+	for reply != "" {
+		start := strings.Index(reply, "{{")
+		literal := reply
+		if start >= 0 {
+			literal = reply[:start]
+		}
+		if literal != "" {
+			data, err := DecodePayload(literal, isHex)
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, replySegment{literal: data})
+		}
+		if start < 0 {
+			break
+		}
+
+		end := strings.Index(reply[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("模板字段缺少结束符: %s", reply[start:])
+		}
+		field := strings.TrimSpace(reply[start+2 : start+end])
+		if err := validateReplyField(field); err != nil {
+			return nil, err
+		}
+		segments = append(segments, replySegment{field: field})
+		reply = reply[start+end+2:]
+	}
+	return segments, nil
+}
+
+// validateReplyField 校验模板字段
+func validateReplyField(field string) error {
+	switch {
+	case strings.HasPrefix(field, "$"):
+		if index, err := strconv.Atoi(field[1:]); err != nil || index < 0 {
+			return fmt.Errorf("无效的分组引用: %s", field)
+		}
+	case field == "counter", field == "timestamp", field == "time":
+	case strings.HasPrefix(field, "counter:"):
+		switch field {
+		case "counter:1", "counter:2", "counter:4":
+		default:
+			return fmt.Errorf("计数器字节数只能为1、2、4: %s", field)
+		}
+	default:
+		return fmt.Errorf("不支持的模板字段: %s", field)
+	}
+	return nil
+}
+
+// responderRuleLabel 规则在记录中的标记，优先使用名称
+func responderRuleLabel(rule session.ResponderRule) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return rule.ID
+}
+
+// label 规则在记录中的标记
+func (rule *responderRule) label() string {
+	return responderRuleLabel(rule.ResponderRule)
+}
+
+// match 匹配收到的数据，返回是否匹配及分组，$0为整体
+func (rule *responderRule) match(data []byte) (bool, [][]byte) {
+	switch rule.MatchType {
+	case MatchExact:
+		return bytes.Equal(data, rule.pattern), [][]byte{data}
+	case MatchPrefix:
+		if !bytes.HasPrefix(data, rule.pattern) {
+			return false, nil
+		}
+		return true, [][]byte{data, data[len(rule.pattern):]}
+	case MatchHex:
+		if len(data) != len(rule.pattern) {
+			return false, nil
+		}
+		groups := [][]byte{data}
+		for i := range data {
+			if rule.mask[i] {
+				if i == 0 || !rule.mask[i-1] {
+					groups = append(groups, nil)
+				}
+				groups[len(groups)-1] = data[i-len(groups[len(groups)-1]) : i+1]
+				continue
+			}
+			if data[i] != rule.pattern[i] {
+				return false, nil
+			}
+		}
+		return true, groups
+	case MatchRegex:
+		groups := rule.regex.FindSubmatch(data)
+		return groups != nil, groups
+	}
+	return false, nil
+}
+
+// render 生成应答内容
+func (rule *responderRule) render(groups [][]byte, hits int) ([]byte, error) {
+	var reply []byte
+	for _, segment := range rule.reply {
+		if segment.field == "" {
+			reply = append(reply, segment.literal...)
+			continue
+		}
+
+		switch field := segment.field; {
+		case strings.HasPrefix(field, "$"):
+			index, _ := strconv.Atoi(field[1:])
+			if index >= len(groups) {
+				return nil, fmt.Errorf("分组 %s 不存在", field)
+			}
+			reply = append(reply, groups[index]...)
+		case field == "counter":
+			reply = strconv.AppendInt(reply, int64(hits), 10)
+		case field == "counter:1":
+			reply = append(reply, byte(hits))
+		case field == "counter:2":
+			reply = binary.BigEndian.AppendUint16(reply, uint16(hits))
+		case field == "counter:4":
+			reply = binary.BigEndian.AppendUint32(reply, uint32(hits))
+		case field == "timestamp":
+			reply = strconv.AppendInt(reply, time.Now().UnixMilli(), 10)
+		case field == "time":
+			reply = time.Now().AppendFormat(reply, "15:04:05")
+		}
+	}
+	return reply, nil
+}
+
+// reply 按顺序匹配规则，返回第一条匹配的规则及应答内容
+func (r *responder) reply(data []byte) (*responderRule, []byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, rule := range r.rules {
+		if !rule.Enabled {
+			continue
+		}
+		ok, groups := rule.match(data)
+		if !ok {
+			continue
+		}
+
+		rule.hits++
+		reply, err := rule.render(groups, rule.hits)
+		return rule, reply, err
+	}
+	return nil, nil, nil
+}
+
+// currentResponder 获取会话当前配置对应的应答器，配置变更时重新编译
+func (s *Session) currentResponder() *responder {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cfg := s.Info.Responder
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	if s.responder == nil || s.responder.cfg != cfg {
+		compiled, err := compileResponder(cfg)
+		if err != nil {
+			log.Printf("会话 %s 自动应答配置无效，不应答: %v", s.Info.SessionID, err)
+			compiled = &responder{cfg: cfg}
+		}
+		s.responder = compiled
+	}
+	return s.responder
+}
+
+// respond 按自动应答规则应答收到的消息，应答发往消息的来源
+func (s *Session) respond(record session.MessageRecord) {
+	r := s.currentResponder()
+	if r == nil {
+		return
+	}
+
+	rule, reply, err := r.reply(record.Raw)
+	if rule == nil {
+		return
+	}
+	if err == nil {
+		reply, err = appendSessionChecksum(s, reply)
+	}
+	if err != nil {
+		log.Printf("会话 %s 规则 %s 生成应答失败: %v", s.Info.SessionID, rule.label(), err)
+		return
+	}
+
+	sessionID, isHex, label := s.Info.SessionID, rule.ReplyIsHex, rule.label()
+	send := func() {
+		if _, err := GlobalSessionManager.sendBytes(sessionID, reply, isHex, record.RemoteAddr, label); err != nil {
+			log.Printf("会话 %s 规则 %s 自动应答失败: %v", sessionID, label, err)
+		}
+	}
+
+	if rule.Delay > 0 {
+		time.AfterFunc(time.Duration(rule.Delay)*time.Millisecond, send)
+		return
+	}
+	send()
+}
@@ -0,0 +1,295 @@
+package core
+
+import (
+	"encoding/binary"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// registerAPI 注册脚本可调用的session表，Lua字符串即原始字节
+//
+//	session.id / session.name / session.type
+//	session.status()                     当前连接状态
+//	session.send(data [, target])        发送原始字节，按会话配置追加校验
+//	session.sendHex(hex [, target])      发送十六进制字符串
+//	session.log(...)                     输出日志，print同样输出到会话日志
+//	session.set(name, value)             设置会话变量(字符串/数字/布尔，nil删除)
+//	session.get(name)                    读取会话变量
+//	session.after(ms, fn)                延迟执行，返回定时器ID
+//	session.every(ms, fn)                周期执行，返回定时器ID
+//	session.cancel(id)                   取消定时器
+//	session.hex(data)                    字节转十六进制字符串
+//	session.fromHex(hex)                 十六进制字符串转字节
+//	session.checksum(algorithm, data)    计算校验值
+//	session.readUint(data, pos, size [, littleEndian])  读取无符号整数，pos从1开始
+//	session.packUint(value, size [, littleEndian])      整数打包为字节
+func (e *scriptEngine) registerAPI() {
+	L := e.state
+	api := L.NewTable()
+
+	L.SetField(api, "id", lua.LString(e.sess.Info.SessionID))
+	L.SetField(api, "name", lua.LString(e.sess.Info.Name))
+	L.SetField(api, "type", lua.LString(e.sess.Info.Type))
+
+	functions := map[string]lua.LGFunction{
+		"status":   e.luaStatus,
+		"send":     e.luaSend,
+		"sendHex":  e.luaSendHex,
+		"log":      e.luaLog,
+		"set":      e.luaSet,
+		"get":      e.luaGet,
+		"after":    e.luaAfter,
+		"every":    e.luaEvery,
+		"cancel":   e.luaCancel,
+		"hex":      luaHex,
+		"fromHex":  luaFromHex,
+		"checksum": luaChecksum,
+		"readUint": luaReadUint,
+		"packUint": luaPackUint,
+	}
+	for name, fn := range functions {
+		L.SetField(api, name, L.NewFunction(fn))
+	}
+
+	L.SetGlobal("session", api)
+	L.SetGlobal("print", L.NewFunction(e.luaLog))
+}
+
+func (e *scriptEngine) luaStatus(L *lua.LState) int {
+	L.Push(lua.LString(e.sess.Info.Status))
+	return 1
+}
+
+func (e *scriptEngine) luaSend(L *lua.LState) int {
+	return e.send(L, []byte(L.CheckString(1)), false)
+}
+
+func (e *scriptEngine) luaSendHex(L *lua.LState) int {
+	data, err := DecodePayload(L.CheckString(1), true)
+	if err != nil {
+		L.ArgError(1, err.Error())
+		return 0
+	}
+	return e.send(L, data, true)
+}
+
+// send 发送数据，失败时返回nil和错误信息
+func (e *scriptEngine) send(L *lua.LState, data []byte, isHex bool) int {
+	target := L.OptString(2, "")
+
+	data, err := appendSessionChecksum(e.sess, data)
+	if err == nil {
+		e.sending.Store(true)
+		_, err = GlobalSessionManager.SendBytes(e.sess.Info.SessionID, data, isHex, target)
+		e.sending.Store(false)
+	}
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	L.Push(lua.LTrue)
+	return 1
+}
+
+func (e *scriptEngine) luaLog(L *lua.LState) int {
+	parts := make([]string, 0, L.GetTop())
+	for i := 1; i <= L.GetTop(); i++ {
+		parts = append(parts, L.ToStringMeta(L.Get(i)).String())
+	}
+	e.emitLog("info", strings.Join(parts, " "))
+	return 0
+}
+
+func (e *scriptEngine) luaSet(L *lua.LState) int {
+	name := L.CheckString(1)
+	value := L.Get(2)
+
+	var converted any
+	switch v := value.(type) {
+	case lua.LString:
+		converted = string(v)
+	case lua.LNumber:
+		converted = float64(v)
+	case lua.LBool:
+		converted = bool(v)
+	case *lua.LNilType:
+	default:
+		L.ArgError(2, "只支持字符串、数字和布尔值")
+		return 0
+	}
+
+	e.mutex.Lock()
+	if converted == nil {
+		delete(e.vars, name)
+	} else {
+		e.vars[name] = converted
+	}
+	e.mutex.Unlock()
+	return 0
+}
+
+func (e *scriptEngine) luaGet(L *lua.LState) int {
+	e.mutex.Lock()
+	value := e.vars[L.CheckString(1)]
+	e.mutex.Unlock()
+
+	switch v := value.(type) {
+	case string:
+		L.Push(lua.LString(v))
+	case float64:
+		L.Push(lua.LNumber(v))
+	case bool:
+		L.Push(lua.LBool(v))
+	default:
+		L.Push(lua.LNil)
+	}
+	return 1
+}
+
+func (e *scriptEngine) luaAfter(L *lua.LState) int {
+	return e.addTimer(L, false)
+}
+
+func (e *scriptEngine) luaEvery(L *lua.LState) int {
+	return e.addTimer(L, true)
+}
+
+// addTimer 添加定时器，回调在事件循环中执行
+func (e *scriptEngine) addTimer(L *lua.LState, repeat bool) int {
+	ms := L.CheckInt(1)
+	fn := L.CheckFunction(2)
+	if ms < 1 {
+		L.ArgError(1, "间隔必须大于0")
+		return 0
+	}
+
+	e.nextTimer++
+	id := e.nextTimer
+	delay := time.Duration(ms) * time.Millisecond
+
+	t := &scriptTimer{fn: fn}
+	if repeat {
+		t.interval = delay
+	}
+	t.timer = time.AfterFunc(delay, func() {
+		e.post(func() { e.fireTimer(id) })
+	})
+	e.timers[id] = t
+
+	L.Push(lua.LNumber(id))
+	return 1
+}
+
+// fireTimer 执行定时器回调，周期定时器重新计时
+func (e *scriptEngine) fireTimer(id int) {
+	t, ok := e.timers[id]
+	if !ok {
+		return
+	}
+	if t.interval == 0 {
+		delete(e.timers, id)
+	}
+
+	e.call(t.fn)
+
+	if current, ok := e.timers[id]; ok && current == t && t.interval > 0 {
+		t.timer.Reset(t.interval)
+	}
+}
+
+func (e *scriptEngine) luaCancel(L *lua.LState) int {
+	id := L.CheckInt(1)
+	if t, ok := e.timers[id]; ok {
+		t.timer.Stop()
+		delete(e.timers, id)
+	}
+	return 0
+}
+
+func luaHex(L *lua.LState) int {
+	L.Push(lua.LString(EncodeHex([]byte(L.CheckString(1)))))
+	return 1
+}
+
+func luaFromHex(L *lua.LState) int {
+	data, err := DecodePayload(L.CheckString(1), true)
+	if err != nil {
+		L.ArgError(1, err.Error())
+		return 0
+	}
+	L.Push(lua.LString(data))
+	return 1
+}
+
+func luaChecksum(L *lua.LState) int {
+	sum, err := ComputeChecksum(L.CheckString(1), []byte(L.CheckString(2)))
+	if err != nil {
+		L.ArgError(1, err.Error())
+		return 0
+	}
+	L.Push(lua.LString(sum))
+	return 1
+}
+
+func luaReadUint(L *lua.LState) int {
+	data := L.CheckString(1)
+	pos := L.CheckInt(2)
+	size := L.CheckInt(3)
+	little := L.OptBool(4, false)
+
+	if size != 1 && size != 2 && size != 4 {
+		L.ArgError(3, "字节数只能为1、2、4")
+		return 0
+	}
+	if pos < 1 || pos-1+size > len(data) {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	field := []byte(data[pos-1 : pos-1+size])
+	var value uint32
+	switch {
+	case size == 1:
+		value = uint32(field[0])
+	case size == 2 && little:
+		value = uint32(binary.LittleEndian.Uint16(field))
+	case size == 2:
+		value = uint32(binary.BigEndian.Uint16(field))
+	case little:
+		value = binary.LittleEndian.Uint32(field)
+	default:
+		value = binary.BigEndian.Uint32(field)
+	}
+	L.Push(lua.LNumber(value))
+	return 1
+}
+
+func luaPackUint(L *lua.LState) int {
+	value := uint32(L.CheckNumber(1))
+	size := L.CheckInt(2)
+	little := L.OptBool(3, false)
+
+	var order binary.AppendByteOrder = binary.BigEndian
+	if little {
+		order = binary.LittleEndian
+	}
+
+	var data []byte
+	switch size {
+	case 1:
+		data = []byte{byte(value)}
+	case 2:
+		data = order.AppendUint16(nil, uint16(value))
+	case 4:
+		data = order.AppendUint32(nil, value)
+	default:
+		L.ArgError(2, "字节数只能为1、2、4")
+		return 0
+	}
+	L.Push(lua.LString(data))
+	return 1
+}
@@ -0,0 +1,351 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+// 脚本事件队列长度，队列满时丢弃事件
+const scriptQueueSize = 1024
+
+// ScriptManager 会话脚本管理器
+type ScriptManager struct{}
+
+var GlobalScriptManager = &ScriptManager{}
+
+// scriptEngine 运行单个会话脚本的Lua虚拟机，所有脚本代码在loop协程中执行
+type scriptEngine struct {
+	sess      *Session
+	state     *lua.LState
+	jobs      chan func()
+	stop      chan struct{}
+	cancel    context.CancelFunc
+	unobserve func()
+	sending   atomic.Bool // 正在发送脚本自身的数据，不触发onSend
+
+	timers    map[int]*scriptTimer // 仅在loop协程中访问
+	nextTimer int
+
+	startTime int64
+	lastError string
+	vars      map[string]any
+	mutex     sync.Mutex
+}
+
+// scriptTimer 脚本定时器
+type scriptTimer struct {
+	timer    *time.Timer
+	fn       *lua.LFunction
+	interval time.Duration // 0表示只执行一次
+}
+
+// SetScript 设置会话脚本，启用时立即重新加载
+func (m *ScriptManager) SetScript(sessionID string, cfg *session.ScriptConfig) (*session.ScriptState, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg != nil && cfg.Source != "" {
+		if err := checkScriptSyntax(cfg.Source); err != nil {
+			return nil, err
+		}
+	}
+
+	m.cancel(sess)
+
+	sess.mutex.Lock()
+	sess.Info.Script = cfg
+	sess.mutex.Unlock()
+	GlobalSessionManager.PersistSessions()
+
+	if cfg != nil && cfg.Enabled {
+		if err := m.start(sess); err != nil {
+			return nil, err
+		}
+	}
+	return m.GetState(sessionID)
+}
+
+// GetState 获取会话脚本运行状态
+func (m *ScriptManager) GetState(sessionID string) (*session.ScriptState, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sess.mutex.RLock()
+	engine := sess.script
+	sess.mutex.RUnlock()
+
+	if engine == nil {
+		return &session.ScriptState{Vars: map[string]any{}}, nil
+	}
+	return engine.snapshot(), nil
+}
+
+// StartAll 加载所有启用了脚本的会话，用于恢复会话后
+func (m *ScriptManager) StartAll() {
+	for _, info := range GlobalSessionManager.GetAllSessions() {
+		if info.Script == nil || !info.Script.Enabled {
+			continue
+		}
+		sess, err := GlobalSessionManager.GetSession(info.SessionID)
+		if err != nil {
+			continue
+		}
+		if err := m.start(sess); err != nil {
+			log.Printf("会话 %s 加载脚本失败: %v", info.SessionID, err)
+		}
+	}
+}
+
+// StopAll 停止所有会话脚本
+func (m *ScriptManager) StopAll() {
+	for _, info := range GlobalSessionManager.GetAllSessions() {
+		if sess, err := GlobalSessionManager.GetSession(info.SessionID); err == nil {
+			m.cancel(sess)
+		}
+	}
+}
+
+// start 启动会话脚本
+func (m *ScriptManager) start(sess *Session) error {
+	m.cancel(sess)
+
+	engine, err := newScriptEngine(sess, sess.Info.Script.Source)
+	if err != nil {
+		return err
+	}
+
+	sess.mutex.Lock()
+	sess.script = engine
+	sess.mutex.Unlock()
+
+	log.Printf("会话 %s 已加载脚本", sess.Info.SessionID)
+	return nil
+}
+
+// cancel 停止会话正在运行的脚本
+func (m *ScriptManager) cancel(sess *Session) {
+	sess.mutex.Lock()
+	engine := sess.script
+	sess.script = nil
+	sess.mutex.Unlock()
+
+	if engine != nil {
+		engine.close()
+	}
+}
+
+// notifyScriptStatus 会话连接状态变化时调用脚本钩子
+func (s *Session) notifyScriptStatus(status string) {
+	s.mutex.RLock()
+	engine := s.script
+	s.mutex.RUnlock()
+
+	if engine == nil {
+		return
+	}
+	switch status {
+	case "connected", "listening":
+		engine.post(func() { engine.hook("onConnect") })
+	case "disconnected":
+		engine.post(func() { engine.hook("onDisconnect") })
+	}
+}
+
+// checkScriptSyntax 检查脚本语法
+func checkScriptSyntax(source string) error {
+	state := lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer state.Close()
+
+	if _, err := state.LoadString(source); err != nil {
+		return fmt.Errorf("脚本语法错误: %v", err)
+	}
+	return nil
+}
+
+// newScriptEngine 创建脚本虚拟机，执行脚本主体并开始分发事件
+func newScriptEngine(sess *Session, source string) (*scriptEngine, error) {
+	state := lua.NewState(lua.Options{SkipOpenLibs: true})
+	openScriptLibs(state)
+
+	main, err := state.LoadString(source)
+	if err != nil {
+		state.Close()
+		return nil, fmt.Errorf("脚本语法错误: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	state.SetContext(ctx)
+
+	engine := &scriptEngine{
+		sess:      sess,
+		state:     state,
+		jobs:      make(chan func(), scriptQueueSize),
+		stop:      make(chan struct{}),
+		cancel:    cancel,
+		timers:    make(map[int]*scriptTimer),
+		startTime: time.Now().UnixMilli(),
+		vars:      make(map[string]any),
+	}
+	engine.registerAPI()
+	engine.unobserve = sess.observe(engine.observe)
+
+	go engine.loop()
+
+	engine.post(func() {
+		engine.call(main)
+
+		// 加载时会话已连接则立即调用onConnect
+		switch sess.Info.Status {
+		case "connected", "listening":
+			engine.hook("onConnect")
+		}
+	})
+	return engine, nil
+}
+
+// openScriptLibs 加载脚本可用的标准库，不包含io、debug和package，os仅保留time、clock与date
+func openScriptLibs(state *lua.LState) {
+	libs := []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+		{lua.OsLibName, lua.OpenOs},
+		{lua.CoroutineLibName, lua.OpenCoroutine},
+	}
+	for _, lib := range libs {
+		state.Push(state.NewFunction(lib.fn))
+		state.Push(lua.LString(lib.name))
+		state.Call(1, 0)
+	}
+
+	// 禁止读取执行磁盘上的脚本
+	for _, name := range []string{"dofile", "loadfile", "require"} {
+		state.SetGlobal(name, lua.LNil)
+	}
+
+	// os只保留时间函数，避免exit、execute、remove等操作进程和文件
+	full := state.GetGlobal(lua.OsLibName)
+	osLib := state.NewTable()
+	for _, name := range []string{"time", "clock", "date"} {
+		osLib.RawSetString(name, state.GetField(full, name))
+	}
+	state.SetGlobal(lua.OsLibName, osLib)
+}
+
+// loop 事件循环，退出时释放虚拟机
+func (e *scriptEngine) loop() {
+	defer func() {
+		for _, t := range e.timers {
+			t.timer.Stop()
+		}
+		e.state.Close()
+	}()
+
+	for {
+		select {
+		case <-e.stop:
+			return
+		case job := <-e.jobs:
+			job()
+		}
+	}
+}
+
+// post 将任务加入事件队列，不阻塞调用方
+func (e *scriptEngine) post(job func()) {
+	select {
+	case <-e.stop:
+	case e.jobs <- job:
+	default:
+		log.Printf("会话 %s 脚本事件队列已满，丢弃事件", e.sess.Info.SessionID)
+	}
+}
+
+// close 停止脚本，中断正在执行的代码
+func (e *scriptEngine) close() {
+	e.unobserve()
+	e.cancel()
+	close(e.stop)
+}
+
+// observe 会话消息订阅回调，转换为onReceive/onSend钩子
+func (e *scriptEngine) observe(record session.MessageRecord) {
+	name := "onReceive"
+	if record.Direction == "send" {
+		if e.sending.Load() {
+			return
+		}
+		name = "onSend"
+	}
+
+	data, addr := lua.LString(record.Raw), lua.LString(record.RemoteAddr)
+	e.post(func() { e.hook(name, data, addr) })
+}
+
+// hook 调用脚本中定义的全局钩子函数，未定义时忽略
+func (e *scriptEngine) hook(name string, args ...lua.LValue) {
+	if fn, ok := e.state.GetGlobal(name).(*lua.LFunction); ok {
+		e.call(fn, args...)
+	}
+}
+
+// call 在保护模式下调用Lua函数，错误记录为脚本错误
+func (e *scriptEngine) call(fn *lua.LFunction, args ...lua.LValue) {
+	err := e.state.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, args...)
+	if err == nil {
+		return
+	}
+
+	select {
+	case <-e.stop:
+		// 脚本已停止，中断导致的错误不再上报
+		return
+	default:
+	}
+
+	e.mutex.Lock()
+	e.lastError = err.Error()
+	e.mutex.Unlock()
+	e.emitLog("error", err.Error())
+}
+
+// emitLog 输出脚本日志
+func (e *scriptEngine) emitLog(level, message string) {
+	sessionID := e.sess.Info.SessionID
+	log.Printf("会话 %s 脚本[%s]: %s", sessionID, level, message)
+	if GlobalWebSocketManager != nil {
+		GlobalWebSocketManager.NotifyScriptLog(sessionID, level, message)
+	}
+}
+
+// snapshot 获取运行状态
+func (e *scriptEngine) snapshot() *session.ScriptState {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	vars := make(map[string]any, len(e.vars))
+	for k, v := range e.vars {
+		vars[k] = v
+	}
+	return &session.ScriptState{
+		Running:   true,
+		StartTime: e.startTime,
+		LastError: e.lastError,
+		Vars:      vars,
+	}
+}
@@ -25,8 +25,8 @@ func (sm *SerialManager) GetSerialPorts() ([]string, error) {
 	return ports, nil
 }
 
-// ConnectSerial 连接串口
-func (sm *SerialManager) ConnectSerial(sessionID string, portName string, baudRate, dataBits, stopBits int, parity string) error {
+// ConnectSerial 连接串口，未设置的参数使用默认值 9600/8/1/none
+func (sm *SerialManager) ConnectSerial(sessionID string, cfg session.SerialConfig) error {
 	sess, err := GlobalSessionManager.GetSession(sessionID)
 	if err != nil {
 		return fmt.Errorf("会话不存在: %v", err)
@@ -36,32 +36,127 @@ func (sm *SerialManager) ConnectSerial(sessionID string, portName string, baudRa
 		return fmt.Errorf("串口已连接")
 	}
 
-	// 设置串口参数
-	mode := &serial.Mode{
-		BaudRate: baudRate,
-		DataBits: dataBits,
-		StopBits: getStopBits(stopBits),
-		Parity:   getParity(parity),
+	if cfg.SerialPort == "" {
+		return fmt.Errorf("串口名称不能为空")
 	}
 
-	log.Printf("连接串口: %s, 波特率: %d, 数据位: %d, 停止位: %d, 校验: %s",
-		portName, baudRate, dataBits, stopBits, parity)
+	cfg = normalizeSerialConfig(cfg)
+	mode, err := buildSerialMode(cfg)
+	if err != nil {
+		return err
+	}
+
+	// 绑定了USB序列号时使用设备当前的路径
+	cfg.SerialPort = resolveUSBPort(cfg.SerialPort, cfg.USBSerialNumber)
+
+	// 指定了DTR/RTS初始状态时在打开串口时设置，未指定的线保持默认有效
+	if cfg.DTR != nil || cfg.RTS != nil {
+		mode.InitialStatusBits = &serial.ModemOutputBits{
+			DTR: cfg.DTR == nil || *cfg.DTR,
+			RTS: cfg.RTS == nil || *cfg.RTS,
+		}
+	}
+
+	log.Printf("连接串口: %s, 波特率: %d, 数据位: %d, 停止位: %s, 校验: %s",
+		cfg.SerialPort, cfg.BaudRate, cfg.DataBits, cfg.StopBits, cfg.Parity)
 
 	// 打开串口
-	port, err := serial.Open(portName, mode)
+	port, err := serial.Open(cfg.SerialPort, mode)
 	if err != nil {
 		return fmt.Errorf("打开串口失败: %v", err)
 	}
 
+	// 按流控方式设置RTS初始状态
+	if err := applyFlowControlLines(port, cfg); err != nil {
+		port.Close()
+		return err
+	}
+
 	// 保存连接 - serial.Port实现了io.ReadWriteCloser接口
 	sess.Connection = port
 	sess.IsActive = true
+	sess.serialLost = false
+
+	// 保存串口参数，用于自动重连
+	sess.Info.SerialConfig = cfg
 
-	log.Printf("串口 %s 连接成功", portName)
+	log.Printf("串口 %s 连接成功", cfg.SerialPort)
 
-	// 启动接收数据的goroutine
+	// 启动接收数据和监视调制解调器线的goroutine
 	go sm.handleSerialReceive(sess)
+	go sm.watchModemStatus(sess, port)
+
+	return nil
+}
+
+// SetSerialMode 修改串口参数，已连接时立即生效，串口名称不可修改
+func (sm *SerialManager) SetSerialMode(sessionID string, cfg session.SerialConfig) error {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return fmt.Errorf("会话不存在: %v", err)
+	}
+
+	if sess.Info.Type != "serial" {
+		return fmt.Errorf("会话 %s 不是串口会话", sessionID)
+	}
+
+	// 未指定的参数保持当前值
+	current := sess.Info.SerialConfig
+	cfg.SerialPort = current.SerialPort
+	cfg.USBSerialNumber = current.USBSerialNumber
+	if cfg.BaudRate == 0 {
+		cfg.BaudRate = current.BaudRate
+	}
+	if cfg.DataBits == 0 {
+		cfg.DataBits = current.DataBits
+	}
+	if cfg.StopBits == "" {
+		cfg.StopBits = current.StopBits
+	}
+	if cfg.Parity == "" {
+		cfg.Parity = current.Parity
+	}
+	if cfg.DTR == nil {
+		cfg.DTR = current.DTR
+	}
+	if cfg.RTS == nil {
+		cfg.RTS = current.RTS
+	}
+	if cfg.FlowControl == "" {
+		cfg.FlowControl = current.FlowControl
+	}
+	if cfg.FlowTimeout == 0 {
+		cfg.FlowTimeout = current.FlowTimeout
+	}
+	cfg = normalizeSerialConfig(cfg)
+	mode, err := buildSerialMode(cfg)
+	if err != nil {
+		return err
+	}
 
+	if port, ok := sess.Connection.(serial.Port); ok {
+		if err := port.SetMode(mode); err != nil {
+			return fmt.Errorf("修改串口参数失败: %v", err)
+		}
+		if cfg.DTR != nil {
+			if err := port.SetDTR(*cfg.DTR); err != nil {
+				return fmt.Errorf("设置DTR失败: %v", err)
+			}
+		}
+		if cfg.RTS != nil {
+			if err := port.SetRTS(*cfg.RTS); err != nil {
+				return fmt.Errorf("设置RTS失败: %v", err)
+			}
+		}
+		if err := applyFlowControlLines(port, cfg); err != nil {
+			return err
+		}
+		log.Printf("串口 %s 参数已修改: 波特率: %d, 数据位: %d, 停止位: %s, 校验: %s",
+			cfg.SerialPort, cfg.BaudRate, cfg.DataBits, cfg.StopBits, cfg.Parity)
+	}
+
+	sess.Info.SerialConfig = cfg
+	GlobalSessionManager.PersistSessions()
 	return nil
 }
 
@@ -72,21 +167,38 @@ func (sm *SerialManager) DisconnectSerial(sessionID string) error {
 		return fmt.Errorf("会话不存在: %v", err)
 	}
 
-	if sess.Connection == nil {
-		return fmt.Errorf("串口未连接")
-	}
+	// 主动断开时停止自动重连，串口已意外断开时同样可以断开以停止重连
+	sess.IsActive = false
+	GlobalReconnectManager.Cancel(sess)
 
 	// 关闭连接
-	sess.Connection.Close()
-	sess.Connection = nil
-	sess.IsActive = false
+	if sess.Connection != nil {
+		sess.Connection.Close()
+		sess.Connection = nil
+	}
 
 	log.Printf("串口连接已断开: %s", sessionID)
+	GlobalSessionManager.UpdateSessionStatus(sessionID, "disconnected")
 	return nil
 }
 
 // SendSerialData 发送串口数据
 func (sm *SerialManager) SendSerialData(sessionID, data string, isHex bool) (*session.MessageRecord, error) {
+	sendData, err := buildSendPayload(sessionID, data, isHex)
+	if err != nil {
+		return nil, err
+	}
+
+	return sm.SendSerialBytes(sessionID, sendData, isHex)
+}
+
+// SendSerialBytes 发送原始字节，isHex仅影响记录的默认显示方式
+func (sm *SerialManager) SendSerialBytes(sessionID string, sendData []byte, isHex bool) (*session.MessageRecord, error) {
+	return sm.sendSerialBytes(sessionID, sendData, isHex, "")
+}
+
+// sendSerialBytes 发送原始字节，rule为触发发送的自动应答规则
+func (sm *SerialManager) sendSerialBytes(sessionID string, sendData []byte, isHex bool, rule string) (*session.MessageRecord, error) {
 	sess, err := GlobalSessionManager.GetSession(sessionID)
 	if err != nil {
 		return nil, err
@@ -96,51 +208,18 @@ func (sm *SerialManager) SendSerialData(sessionID, data string, isHex bool) (*se
 		return nil, fmt.Errorf("串口未连接")
 	}
 
-	var sendData []byte
-	if isHex {
-		// 处理十六进制数据
-		// 简单实现：移除空格并转换
-		cleanHex := ""
-		for _, char := range data {
-			if char != ' ' && char != '\t' && char != '\n' && char != '\r' {
-				cleanHex += string(char)
-			}
-		}
-
-		// 转换十六进制字符串为字节
-		for i := 0; i < len(cleanHex); i += 2 {
-			if i+1 >= len(cleanHex) {
-				break
-			}
-			var b byte
-			fmt.Sscanf(cleanHex[i:i+2], "%02x", &b)
-			sendData = append(sendData, b)
-		}
-	} else {
-		sendData = []byte(data)
-	}
-
-	// 发送数据
-	_, err = sess.Connection.Write(sendData)
+	// 发送数据，按流控方式等待CTS或切换RTS
+	err = writeSerial(sess, sendData)
 	if err != nil {
 		return nil, fmt.Errorf("发送数据失败: %v", err)
 	}
 
-	// 创建消息记录
-	record := session.MessageRecord{
-		Direction:  "send",
-		Data:       data,
-		IsHex:      isHex,
-		Timestamp:  time.Now().UnixMilli(),
-		ByteLength: len(data),
-	}
-
 	// 记录发送的消息
-	sess.AddMessage("send", data, isHex)
+	record := sess.addMessage("send", sendData, isHex, "", rule)
 
 	// 通知WebSocket客户端
 	if GlobalWebSocketManager != nil {
-		GlobalWebSocketManager.NotifyTCPMessage(sessionID, "send", data, isHex, len(data))
+		GlobalWebSocketManager.NotifyMessageRecord(sessionID, record)
 	}
 
 	return &record, nil
@@ -154,6 +233,11 @@ func (sm *SerialManager) handleSerialReceive(sess *Session) {
 		}
 	}()
 
+	assembler := newSessionAssembler(sess, "")
+	defer func() {
+		assembler.Close()
+	}()
+
 	buffer := make([]byte, 1024)
 
 	for sess.IsActive && sess.Connection != nil {
@@ -169,48 +253,139 @@ func (sm *SerialManager) handleSerialReceive(sess *Session) {
 				continue
 			}
 			log.Printf("串口读取错误: %v", err)
+
+			// 非主动断开（例如设备拔出），清理连接并尝试自动重连
+			if sess.IsActive {
+				sess.IsActive = false
+				sess.serialLost = true
+				if sess.Connection != nil {
+					sess.Connection.Close()
+					sess.Connection = nil
+				}
+				GlobalSessionManager.UpdateSessionStatus(sess.Info.SessionID, "disconnected")
+				GlobalReconnectManager.Schedule(sess, err)
+			}
 			break
 		}
 
 		if n > 0 {
-			data := string(buffer[:n])
-			log.Printf("串口收到数据 [%s]: %s (%d字节)", sess.Info.SessionID, data, n)
-
-			// 记录接收的消息
-			sess.AddMessage("receive", data, false)
-
-			// 通知WebSocket客户端
-			if GlobalWebSocketManager != nil {
-				GlobalWebSocketManager.NotifyTCPMessage(sess.Info.SessionID, "receive", data, false, n)
+			if sess.divertReceive(buffer[:n]) {
+				continue
 			}
+			log.Printf("串口收到数据 [%s]: %s (%d字节)", sess.Info.SessionID, EncodeHex(buffer[:n]), n)
+
+			// 按分帧配置记录并通知WebSocket客户端
+			assembler = assembler.sync(sess, "")
+			assembler.Write(buffer[:n])
 		}
 	}
 
 	log.Printf("串口接收处理结束: %s", sess.Info.SessionID)
 }
 
+// CreateSerialSession 创建串口会话
+func (sm *SerialManager) CreateSerialSession(name string, cfg session.SerialConfig, isHex bool) (string, error) {
+	cfg = normalizeSerialConfig(cfg)
+	if _, err := buildSerialMode(cfg); err != nil {
+		return "", err
+	}
+
+	sessionID := fmt.Sprintf("serial_%d", time.Now().UnixNano())
+
+	info := session.SessionInfo{
+		SessionID:    sessionID,
+		Type:         "serial",
+		Name:         name,
+		Status:       "disconnected",
+		Protocol:     "serial",
+		IsHex:        isHex,
+		ConnectTime:  0,
+		SerialConfig: cfg,
+	}
+
+	GlobalSessionManager.CreateSession(info)
+	return sessionID, nil
+}
+
+// normalizeSerialConfig 为未设置的串口参数填充默认值
+func normalizeSerialConfig(cfg session.SerialConfig) session.SerialConfig {
+	if cfg.BaudRate == 0 {
+		cfg.BaudRate = 9600
+	}
+	if cfg.DataBits == 0 {
+		cfg.DataBits = 8
+	}
+	if cfg.StopBits == "" {
+		cfg.StopBits = "1"
+	}
+	if cfg.Parity == "" {
+		cfg.Parity = "none"
+	}
+	if cfg.FlowControl == "" {
+		cfg.FlowControl = FlowControlNone
+	}
+	return cfg
+}
+
+// buildSerialMode 校验串口参数并转换为serial.Mode
+func buildSerialMode(cfg session.SerialConfig) (*serial.Mode, error) {
+	if cfg.BaudRate < 0 {
+		return nil, fmt.Errorf("波特率无效: %d", cfg.BaudRate)
+	}
+	if cfg.DataBits < 5 || cfg.DataBits > 8 {
+		return nil, fmt.Errorf("数据位无效: %d", cfg.DataBits)
+	}
+
+	stopBits, err := getStopBits(cfg.StopBits)
+	if err != nil {
+		return nil, err
+	}
+	parity, err := getParity(cfg.Parity)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.FlowControl {
+	case FlowControlNone, FlowControlRTSCTS, FlowControlRS485:
+	default:
+		return nil, fmt.Errorf("流控方式无效: %s", cfg.FlowControl)
+	}
+
+	return &serial.Mode{
+		BaudRate: cfg.BaudRate,
+		DataBits: cfg.DataBits,
+		StopBits: stopBits,
+		Parity:   parity,
+	}, nil
+}
+
 // 辅助函数：转换停止位
-func getStopBits(stopBits int) serial.StopBits {
+func getStopBits(stopBits session.StopBits) (serial.StopBits, error) {
 	switch stopBits {
-	case 1:
-		return serial.OneStopBit
-	case 2:
-		return serial.TwoStopBits
+	case "1":
+		return serial.OneStopBit, nil
+	case "1.5":
+		return serial.OnePointFiveStopBits, nil
+	case "2":
+		return serial.TwoStopBits, nil
 	default:
-		return serial.OneStopBit
+		return serial.OneStopBit, fmt.Errorf("停止位无效: %s", stopBits)
 	}
 }
 
 // 辅助函数：转换校验位
-func getParity(parity string) serial.Parity {
+func getParity(parity string) (serial.Parity, error) {
 	switch parity {
+	case "none":
+		return serial.NoParity, nil
 	case "odd":
-		return serial.OddParity
+		return serial.OddParity, nil
 	case "even":
-		return serial.EvenParity
-	case "none":
-		fallthrough
+		return serial.EvenParity, nil
+	case "mark":
+		return serial.MarkParity, nil
+	case "space":
+		return serial.SpaceParity, nil
 	default:
-		return serial.NoParity
+		return serial.NoParity, fmt.Errorf("校验位无效: %s", parity)
 	}
 }
@@ -0,0 +1,205 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+	"go.bug.st/serial"
+)
+
+// 串口流控方式
+// go.bug.st/serial 不支持开启驱动层的CRTSCTS，RTS/CTS握手在每次写入时由程序完成
+const (
+	FlowControlNone   = "none"
+	FlowControlRTSCTS = "rtsCts" // 保持RTS有效，发送前等待CTS有效
+	FlowControlRS485  = "rs485"  // 空闲时RTS无效，发送期间拉高RTS切换收发方向
+)
+
+const (
+	// 调制解调器线轮询间隔
+	modemPollInterval = 100 * time.Millisecond
+	// 默认等待CTS超时（毫秒）
+	defaultFlowTimeout = 1000
+	// 默认Break持续时间（毫秒）
+	defaultBreakDuration = 250
+)
+
+// SetModemLines 设置DTR/RTS输出线，参数为空时不修改
+func (sm *SerialManager) SetModemLines(sessionID string, dtr, rts *bool) error {
+	sess, port, err := connectedSerialPort(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if dtr != nil {
+		if err := port.SetDTR(*dtr); err != nil {
+			return fmt.Errorf("设置DTR失败: %v", err)
+		}
+		value := *dtr
+		sess.Info.DTR = &value
+	}
+	if rts != nil {
+		if err := port.SetRTS(*rts); err != nil {
+			return fmt.Errorf("设置RTS失败: %v", err)
+		}
+		value := *rts
+		sess.Info.RTS = &value
+	}
+	return nil
+}
+
+// SendBreak 发送Break信号
+func (sm *SerialManager) SendBreak(sessionID string, durationMs int) error {
+	_, port, err := connectedSerialPort(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if durationMs <= 0 {
+		durationMs = defaultBreakDuration
+	}
+
+	log.Printf("串口 %s 发送Break信号 %dms", sessionID, durationMs)
+	if err := port.Break(time.Duration(durationMs) * time.Millisecond); err != nil {
+		return fmt.Errorf("发送Break信号失败: %v", err)
+	}
+	return nil
+}
+
+// GetModemStatus 读取CTS/DSR/RI/DCD输入线状态
+func (sm *SerialManager) GetModemStatus(sessionID string) (*session.ModemStatus, error) {
+	sess, port, err := connectedSerialPort(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	bits, err := port.GetModemStatusBits()
+	if err != nil {
+		return nil, fmt.Errorf("读取调制解调器状态失败: %v", err)
+	}
+
+	status := session.ModemStatus{CTS: bits.CTS, DSR: bits.DSR, RI: bits.RI, DCD: bits.DCD}
+	sess.Info.Modem = &status
+	return &status, nil
+}
+
+// watchModemStatus 轮询调制解调器输入线，变化时推送状态
+func (sm *SerialManager) watchModemStatus(sess *Session, port serial.Port) {
+	ticker := time.NewTicker(modemPollInterval)
+	defer ticker.Stop()
+
+	var last *session.ModemStatus
+	for range ticker.C {
+		if !sess.IsActive || sess.Connection != port {
+			return
+		}
+
+		bits, err := port.GetModemStatusBits()
+		if err != nil {
+			// 部分设备(如虚拟串口)不支持读取调制解调器状态
+			log.Printf("串口 %s 停止监视调制解调器状态: %v", sess.Info.SessionID, err)
+			return
+		}
+
+		status := session.ModemStatus{CTS: bits.CTS, DSR: bits.DSR, RI: bits.RI, DCD: bits.DCD}
+		if last != nil && *last == status {
+			continue
+		}
+		last = &status
+		sess.Info.Modem = &status
+
+		if GlobalWebSocketManager != nil {
+			GlobalWebSocketManager.NotifyModemStatus(sess.Info.SessionID, status)
+		}
+	}
+}
+
+// writeSerial 按会话流控方式写入数据
+func writeSerial(sess *Session, data []byte) error {
+	port, ok := sess.Connection.(serial.Port)
+	if !ok {
+		_, err := sess.Connection.Write(data)
+		return err
+	}
+
+	cfg := sess.Info.SerialConfig
+	switch cfg.FlowControl {
+	case FlowControlRTSCTS:
+		if err := waitForCTS(port, cfg.FlowTimeout); err != nil {
+			return err
+		}
+		_, err := port.Write(data)
+		return err
+
+	case FlowControlRS485:
+		if err := port.SetRTS(true); err != nil {
+			return fmt.Errorf("设置RTS失败: %v", err)
+		}
+		// 数据发送完毕后再切回接收方向
+		defer port.SetRTS(false)
+		if _, err := port.Write(data); err != nil {
+			return err
+		}
+		return port.Drain()
+
+	default:
+		_, err := port.Write(data)
+		return err
+	}
+}
+
+// waitForCTS 等待CTS有效
+func waitForCTS(port serial.Port, timeoutMs int) error {
+	if timeoutMs <= 0 {
+		timeoutMs = defaultFlowTimeout
+	}
+	deadline := time.Now().Add(time.Duration(timeoutMs) * time.Millisecond)
+
+	for {
+		bits, err := port.GetModemStatusBits()
+		if err != nil {
+			return fmt.Errorf("读取CTS失败: %v", err)
+		}
+		if bits.CTS {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待CTS超时")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// applyFlowControlLines 按流控方式设置RTS
+func applyFlowControlLines(port serial.Port, cfg session.SerialConfig) error {
+	var rts bool
+	switch cfg.FlowControl {
+	case FlowControlRTSCTS:
+		rts = true
+	case FlowControlRS485:
+		rts = false
+	default:
+		return nil
+	}
+
+	if err := port.SetRTS(rts); err != nil {
+		return fmt.Errorf("设置RTS失败: %v", err)
+	}
+	return nil
+}
+
+// connectedSerialPort 获取已连接的串口
+func connectedSerialPort(sessionID string) (*Session, serial.Port, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("会话不存在: %v", err)
+	}
+
+	port, ok := sess.Connection.(serial.Port)
+	if !ok {
+		return nil, nil, fmt.Errorf("串口未连接")
+	}
+	return sess, port, nil
+}
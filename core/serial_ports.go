@@ -0,0 +1,196 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	serialDto "github.com/zhoudm1743/Netser/dto/serial"
+	wsProtocol "github.com/zhoudm1743/Netser/dto/websocket"
+	"go.bug.st/serial"
+	"go.bug.st/serial/enumerator"
+)
+
+// 串口插拔检测间隔
+const portWatchInterval = time.Second
+
+// portWatcher 串口插拔监视器
+type portWatcher struct {
+	stop  chan struct{}
+	mutex sync.Mutex
+}
+
+var globalPortWatcher = &portWatcher{}
+
+// GetSerialPortDetails 获取串口详细信息，包含USB VID/PID、序列号及产品名称
+func (sm *SerialManager) GetSerialPortDetails() ([]serialDto.SerialPortInfo, error) {
+	names, err := serial.GetPortsList()
+	if err != nil {
+		return nil, fmt.Errorf("获取串口列表失败: %v", err)
+	}
+
+	// 部分系统不支持获取详细信息，此时仅返回串口名称
+	details := make(map[string]*enumerator.PortDetails)
+	list, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		log.Printf("获取串口详细信息失败: %v", err)
+	}
+	for _, port := range list {
+		if port.Name != "" {
+			details[port.Name] = port
+		}
+	}
+
+	ports := make([]serialDto.SerialPortInfo, 0, len(names))
+	for _, name := range names {
+		info := serialDto.SerialPortInfo{Name: name}
+		if port, ok := details[name]; ok {
+			info.IsUSB = port.IsUSB
+			info.VID = port.VID
+			info.PID = port.PID
+			info.SerialNumber = port.SerialNumber
+			info.Product = port.Product
+		}
+		ports = append(ports, info)
+	}
+	return ports, nil
+}
+
+// StartPortWatcher 启动串口插拔监视，推送port_added/port_removed事件
+func (sm *SerialManager) StartPortWatcher() {
+	globalPortWatcher.mutex.Lock()
+	defer globalPortWatcher.mutex.Unlock()
+
+	if globalPortWatcher.stop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	globalPortWatcher.stop = stop
+
+	go sm.watchPorts(stop)
+}
+
+// StopPortWatcher 停止串口插拔监视
+func (sm *SerialManager) StopPortWatcher() {
+	globalPortWatcher.mutex.Lock()
+	defer globalPortWatcher.mutex.Unlock()
+
+	if globalPortWatcher.stop != nil {
+		close(globalPortWatcher.stop)
+		globalPortWatcher.stop = nil
+	}
+}
+
+// watchPorts 定时比较串口列表，发现插拔时推送事件
+func (sm *SerialManager) watchPorts(stop chan struct{}) {
+	ticker := time.NewTicker(portWatchInterval)
+	defer ticker.Stop()
+
+	known, err := sm.portSnapshot()
+	if err != nil {
+		log.Printf("串口插拔监视初始化失败: %v", err)
+		known = make(map[string]serialDto.SerialPortInfo)
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		current, err := sm.portSnapshot()
+		if err != nil {
+			continue
+		}
+
+		for _, name := range sortedPortNames(known) {
+			if _, ok := current[name]; !ok {
+				log.Printf("串口已拔出: %s", name)
+				sm.notifyPortEvent(wsProtocol.MsgTypePortRemoved, known[name])
+			}
+		}
+		for _, name := range sortedPortNames(current) {
+			if _, ok := known[name]; !ok {
+				port := current[name]
+				log.Printf("串口已插入: %s", name)
+				sm.notifyPortEvent(wsProtocol.MsgTypePortAdded, port)
+				sm.rebindSessions(port)
+			}
+		}
+		known = current
+	}
+}
+
+// portSnapshot 获取当前串口列表 name -> info
+func (sm *SerialManager) portSnapshot() (map[string]serialDto.SerialPortInfo, error) {
+	ports, err := sm.GetSerialPortDetails()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := make(map[string]serialDto.SerialPortInfo, len(ports))
+	for _, port := range ports {
+		snapshot[port.Name] = port
+	}
+	return snapshot, nil
+}
+
+// notifyPortEvent 推送串口插拔事件
+func (sm *SerialManager) notifyPortEvent(msgType wsProtocol.MessageType, port serialDto.SerialPortInfo) {
+	if GlobalWebSocketManager != nil {
+		GlobalWebSocketManager.NotifyPortEvent(msgType, port)
+	}
+}
+
+// rebindSessions 绑定了USB序列号且意外断开的串口会话，在设备重新插入后立即重连
+func (sm *SerialManager) rebindSessions(port serialDto.SerialPortInfo) {
+	if port.SerialNumber == "" {
+		return
+	}
+
+	for _, info := range GlobalSessionManager.GetAllSessions() {
+		if info.Type != "serial" || info.USBSerialNumber != port.SerialNumber {
+			continue
+		}
+
+		sess, err := GlobalSessionManager.GetSession(info.SessionID)
+		if err != nil {
+			continue
+		}
+
+		if GlobalReconnectManager.TriggerNow(sess, port.Name) {
+			log.Printf("会话 %s 绑定的USB设备 %s 已重新插入: %s", info.SessionID, port.SerialNumber, port.Name)
+		}
+	}
+}
+
+// resolveUSBPort 按USB序列号查找设备当前的串口路径，未找到时返回原路径
+func resolveUSBPort(portName, serialNumber string) string {
+	if serialNumber == "" {
+		return portName
+	}
+
+	list, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		return portName
+	}
+	for _, port := range list {
+		if port.IsUSB && port.SerialNumber == serialNumber && port.Name != "" {
+			return port.Name
+		}
+	}
+	return portName
+}
+
+// sortedPortNames 按名称排序，保证事件顺序稳定
+func sortedPortNames(ports map[string]serialDto.SerialPortInfo) []string {
+	names := make([]string, 0, len(ports))
+	for name := range ports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
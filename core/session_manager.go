@@ -5,6 +5,7 @@ import (
 	"io"
 	"log"
 	"net"
+	"sort"
 	"sync"
 	"time"
 
@@ -19,20 +20,46 @@ type SessionManager struct {
 
 // Session 会话结构
 type Session struct {
-	Info       session.SessionInfo
-	Connection io.ReadWriteCloser // 支持TCP和串口连接
-	Listener   net.Listener       // 仅用于TCP服务端
-	IsActive   bool
-	CreatedAt  time.Time
-	mutex      sync.RWMutex
+	Info          session.SessionInfo
+	Connection    io.ReadWriteCloser // 支持TCP和串口连接
+	Listener      net.Listener       // 仅用于TCP服务端
+	PacketConn    net.PacketConn     // 仅用于UDP会话
+	RemoteAddr    net.Addr           // UDP默认发送目标地址
+	IsActive      bool
+	CreatedAt     time.Time
+	multicast     *multicastConn                      // 仅用于UDP组播会话
+	peers         map[string]*tcpPeer                 // TCP服务端已连接的客户端 address -> peer
+	reconnectStop chan struct{}                       // 正在进行的自动重连，关闭以取消
+	reconnectWake chan struct{}                       // 唤醒正在等待的自动重连立即重连
+	autoSend      *sessionTask                        // 正在运行的定时发送
+	replay        *replayTask                         // 正在运行的流量回放
+	fileSend      *sessionTask                        // 正在进行的文件发送
+	observers     map[int]func(session.MessageRecord) // 消息记录订阅者 id -> 回调
+	responder     *responder                          // 已编译的自动应答规则
+	script        *scriptEngine                       // 正在运行的会话脚本
+	xmodem        *xmodemTransfer                     // 正在进行的XMODEM/YMODEM传输，接收的数据转交给它
+	modbusPolls   map[string]*modbusPoll              // 正在运行的Modbus轮询 id -> 轮询
+	modbusSlave   *modbusSlave                        // 已载入的Modbus从站模拟器
+	modbusMutex   sync.Mutex                          // 串行化Modbus主站请求
+	modbusTxID    uint16                              // Modbus TCP事务ID，由modbusMutex保护
+	nextObserver  int                                 // 下一个订阅者ID
+	serialLost    bool                                // 串口意外断开(如设备拔出)，等待恢复
+	mutex         sync.RWMutex
 }
 
 var GlobalSessionManager = &SessionManager{
 	sessions: make(map[string]*Session),
 }
 
-// CreateSession 创建新会话
+// CreateSession 创建新会话并保存会话定义
 func (sm *SessionManager) CreateSession(info session.SessionInfo) *Session {
+	sess := sm.addSession(info)
+	sm.PersistSessions()
+	return sess
+}
+
+// addSession 添加会话到管理器
+func (sm *SessionManager) addSession(info session.SessionInfo) *Session {
 	sm.mutex.Lock()
 	defer sm.mutex.Unlock()
 
@@ -46,6 +73,61 @@ func (sm *SessionManager) CreateSession(info session.SessionInfo) *Session {
 	return sess
 }
 
+// PersistSessions 保存所有会话定义，重启后恢复
+func (sm *SessionManager) PersistSessions() {
+	sm.mutex.RLock()
+	sessions := make([]*Session, 0, len(sm.sessions))
+	for _, sess := range sm.sessions {
+		sessions = append(sessions, sess)
+	}
+	sm.mutex.RUnlock()
+
+	// 按创建顺序保存，恢复后保持原有顺序
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+
+	infos := make([]session.SessionInfo, 0, len(sessions))
+	for _, sess := range sessions {
+		infos = append(infos, persistentSessionInfo(sess.Info))
+	}
+
+	if err := GlobalConfigStore.SaveSessions(infos); err != nil {
+		log.Printf("保存会话配置失败: %v", err)
+	}
+}
+
+// RestoreSessions 恢复上次保存的会话定义，会话恢复为未连接状态
+func (sm *SessionManager) RestoreSessions() (int, error) {
+	infos, err := GlobalConfigStore.LoadSessions()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, info := range infos {
+		if info.SessionID == "" {
+			continue
+		}
+		sm.addSession(persistentSessionInfo(info))
+	}
+	return len(infos), nil
+}
+
+// persistentSessionInfo 去除会话信息中的运行时状态
+func persistentSessionInfo(info session.SessionInfo) session.SessionInfo {
+	info.Status = "disconnected"
+	info.ConnectTime = 0
+	info.ReconnectAttempts = 0
+	info.LastError = ""
+	info.TLSState = nil
+	info.Modem = nil
+	info.AutoSend = nil
+	info.Replay = nil
+	info.FileSend = nil
+	info.XModem = nil
+	return info
+}
+
 // GetSession 获取会话
 func (sm *SessionManager) GetSession(sessionID string) (*Session, error) {
 	sm.mutex.RLock()
@@ -58,8 +140,18 @@ func (sm *SessionManager) GetSession(sessionID string) (*Session, error) {
 	return sess, nil
 }
 
-// RemoveSession 移除会话
-func (sm *SessionManager) RemoveSession(sessionID string) error {
+// RemoveSession 移除会话，deleteHistory为true时同时删除历史记录
+func (sm *SessionManager) RemoveSession(sessionID string, deleteHistory bool) error {
+	if err := sm.removeSession(sessionID, deleteHistory); err != nil {
+		return err
+	}
+
+	sm.PersistSessions()
+	return nil
+}
+
+// removeSession 关闭会话连接并从管理器中移除
+func (sm *SessionManager) removeSession(sessionID string, deleteHistory bool) error {
 	sm.mutex.Lock()
 	defer sm.mutex.Unlock()
 
@@ -68,19 +160,35 @@ func (sm *SessionManager) RemoveSession(sessionID string) error {
 		return fmt.Errorf("会话不存在: %s", sessionID)
 	}
 
-	// 关闭连接
+	// 停止自动重连并关闭连接
+	GlobalReconnectManager.Cancel(sess)
+	autoSendTasks.cancel(sess)
+	replayTasks.cancel(sess)
+	fileSendTasks.cancel(sess)
+	xmodemTasks.cancel(sess)
+	GlobalModbusMasterManager.cancelPolls(sess)
+	GlobalScriptManager.cancel(sess)
 	if sess.Connection != nil {
 		sess.Connection.Close()
 	}
 	if sess.Listener != nil {
 		sess.Listener.Close()
 	}
+	if sess.PacketConn != nil {
+		sess.PacketConn.Close()
+	}
+	sess.closePeers()
 
 	delete(sm.sessions, sessionID)
 
-	// 清理会话数据库
+	// 关闭会话数据库，按需删除历史记录
 	if GlobalMessageDBManager != nil {
-		err := GlobalMessageDBManager.CloseSessionDB(sessionID)
+		var err error
+		if deleteHistory {
+			err = GlobalMessageDBManager.DeleteSessionDB(sessionID)
+		} else {
+			err = GlobalMessageDBManager.CloseSessionDB(sessionID)
+		}
 		if err != nil {
 			log.Printf("清理会话数据库失败: %v", err)
 		}
@@ -112,37 +220,277 @@ func (sm *SessionManager) UpdateSessionStatus(sessionID, status string) error {
 	}
 
 	sess.Info.Status = status
+	sess.notifyScriptStatus(status)
 
 	// 通知WebSocket客户端状态变化
 	if GlobalWebSocketManager != nil {
-		GlobalWebSocketManager.NotifySessionStatus(sessionID, status)
+		GlobalWebSocketManager.NotifySessionStatus(sessionID, status, sess.Info.ReconnectAttempts, sess.Info.LastError)
+	}
+
+	return nil
+}
+
+// SendData 按会话类型发送数据，target为UDP目标地址或TCP服务端客户端地址(可选)
+func (sm *SessionManager) SendData(sessionID, data string, isHex bool, target string) (*session.MessageRecord, error) {
+	sess, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch sess.Info.Type {
+	case "tcpClient", "tcpServer", "tlsClient", "tlsServer":
+		return GlobalTCPManager.SendTCPDataTo(sessionID, data, isHex, target)
+	case "udpClient", "udpServer", "udpMulticast":
+		return GlobalUDPManager.SendUDPData(sessionID, data, isHex, target)
+	case "serial":
+		return GlobalSerialManager.SendSerialData(sessionID, data, isHex)
+	default:
+		return nil, fmt.Errorf("不支持的会话类型: %s", sess.Info.Type)
+	}
+}
+
+// SendBytes 按会话类型发送原始字节，不追加校验
+func (sm *SessionManager) SendBytes(sessionID string, data []byte, isHex bool, target string) (*session.MessageRecord, error) {
+	return sm.sendBytes(sessionID, data, isHex, target, "")
+}
+
+// sendBytes 按会话类型发送原始字节，rule为触发发送的自动应答规则
+func (sm *SessionManager) sendBytes(sessionID string, data []byte, isHex bool, target, rule string) (*session.MessageRecord, error) {
+	sess, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch sess.Info.Type {
+	case "tcpClient", "tcpServer", "tlsClient", "tlsServer":
+		return GlobalTCPManager.sendTCPBytes(sessionID, data, isHex, target, rule)
+	case "udpClient", "udpServer", "udpMulticast":
+		return GlobalUDPManager.sendUDPBytes(sessionID, data, isHex, target, rule)
+	case "serial":
+		return GlobalSerialManager.sendSerialBytes(sessionID, data, isHex, rule)
+	default:
+		return nil, fmt.Errorf("不支持的会话类型: %s", sess.Info.Type)
+	}
+}
+
+// SetFraming 设置会话的接收分帧配置，连接中的会话立即生效
+func (sm *SessionManager) SetFraming(sessionID string, cfg *session.FramingConfig) error {
+	sess, err := sm.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := NewFramer(cfg); err != nil {
+		return err
+	}
+
+	sess.Info.Framing = cfg
+	sm.PersistSessions()
+	return nil
+}
+
+// SetChecksum 设置会话的校验配置
+func (sm *SessionManager) SetChecksum(sessionID string, cfg *session.ChecksumConfig) error {
+	sess, err := sm.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if err := ValidateChecksumConfig(cfg); err != nil {
+		return err
 	}
 
+	sess.Info.Checksum = cfg
+	sm.PersistSessions()
 	return nil
 }
 
-// AddMessage 添加消息记录
-func (s *Session) AddMessage(direction, data string, isHex bool) {
+// SetResponder 设置会话的自动应答配置，未指定ID的规则按序号生成
+func (sm *SessionManager) SetResponder(sessionID string, cfg *session.ResponderConfig) error {
+	sess, err := sm.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if cfg != nil {
+		for i := range cfg.Rules {
+			if cfg.Rules[i].ID == "" {
+				cfg.Rules[i].ID = fmt.Sprintf("rule%d", i+1)
+			}
+		}
+	}
+	if _, err := compileResponder(cfg); err != nil {
+		return err
+	}
+
+	sess.mutex.Lock()
+	sess.Info.Responder = cfg
+	sess.mutex.Unlock()
+
+	sm.PersistSessions()
+	return nil
+}
+
+// isFramed 接收数据是否按完整报文记录：UDP数据报天然成帧，流式会话需配置分帧
+func (s *Session) isFramed() bool {
+	switch s.Info.Type {
+	case "udpClient", "udpServer", "udpMulticast":
+		return true
+	}
+	return s.Info.Framing != nil && s.Info.Framing.Mode != "" && s.Info.Framing.Mode != FramingNone
+}
+
+// addPeer 添加TCP服务端客户端
+func (s *Session) addPeer(peer *tcpPeer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.peers == nil {
+		s.peers = make(map[string]*tcpPeer)
+	}
+	s.peers[peer.Address] = peer
+}
+
+// removePeer 移除TCP服务端客户端，返回剩余客户端数量
+func (s *Session) removePeer(peer *tcpPeer) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if current, exists := s.peers[peer.Address]; exists && current == peer {
+		delete(s.peers, peer.Address)
+	}
+	return len(s.peers)
+}
+
+// getPeer 根据地址获取TCP服务端客户端
+func (s *Session) getPeer(address string) *tcpPeer {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.peers[address]
+}
+
+// listPeers 获取TCP服务端所有客户端（按连接时间排序）
+func (s *Session) listPeers() []*tcpPeer {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	peers := make([]*tcpPeer, 0, len(s.peers))
+	for _, peer := range s.peers {
+		peers = append(peers, peer)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].ConnectTime < peers[j].ConnectTime
+	})
+	return peers
+}
+
+// closePeers 关闭TCP服务端所有客户端连接
+func (s *Session) closePeers() {
+	for _, peer := range s.listPeers() {
+		peer.Conn.Close()
+	}
+}
+
+// AddRawMessage 添加原始字节消息记录，返回存储的记录
+func (s *Session) AddRawMessage(direction string, raw []byte, isHex bool, remoteAddr string) session.MessageRecord {
+	return s.addMessage(direction, raw, isHex, remoteAddr, "")
+}
+
+// addMessage 添加消息记录，rule为触发发送的自动应答规则
+func (s *Session) addMessage(direction string, raw []byte, isHex bool, remoteAddr, rule string) session.MessageRecord {
 	record := session.MessageRecord{
 		Direction:  direction,
-		Data:       data,
 		IsHex:      isHex,
 		Timestamp:  time.Now().UnixMilli(),
-		ByteLength: len(data),
+		ByteLength: len(raw),
+		RemoteAddr: remoteAddr,
+		LocalAddr:  s.localAddr(remoteAddr),
+		Raw:        raw,
+		Rule:       rule,
+	}
+	record = RenderRecord(record, PayloadFormatAuto)
+
+	// 校验接收到的帧
+	if cfg := s.Info.Checksum; direction == "receive" && checksumEnabled(cfg) && cfg.VerifyOnReceive && s.isFramed() {
+		ok, expected, err := VerifyChecksum(cfg, raw)
+		if err != nil {
+			log.Printf("校验接收数据失败: %v", err)
+		} else if ok {
+			record.ChecksumStatus = ChecksumStatusOK
+		} else {
+			record.ChecksumStatus = ChecksumStatusMismatch
+			record.ChecksumExpected = EncodeHex(expected)
+		}
 	}
 
 	// 存储到数据库
-	log.Printf("存储消息到数据库: 会话=%s, 方向=%s, 数据=%s", s.Info.SessionID, direction, data)
+	log.Printf("存储消息到数据库: 会话=%s, 方向=%s, 数据=%s", s.Info.SessionID, direction, EncodeHex(raw))
 	err := StoreMessageToDB(s.Info.SessionID, record)
 	if err != nil {
 		log.Printf("存储消息到数据库失败: %v", err)
 	} else {
 		log.Printf("消息存储成功")
 	}
+
+	s.notifyObservers(record)
+	return record
+}
+
+// observe 订阅会话的消息记录，返回取消订阅函数；fn在收发数据的协程中调用，不应阻塞
+func (s *Session) observe(fn func(record session.MessageRecord)) func() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.observers == nil {
+		s.observers = make(map[int]func(session.MessageRecord))
+	}
+	id := s.nextObserver
+	s.nextObserver++
+	s.observers[id] = fn
+
+	return func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		delete(s.observers, id)
+	}
 }
 
-// GetMessages 获取消息记录
-func (s *Session) GetMessages(limit, offset int) []session.MessageRecord {
+// notifyObservers 通知消息订阅者
+func (s *Session) notifyObservers(record session.MessageRecord) {
+	s.mutex.RLock()
+	observers := make([]func(session.MessageRecord), 0, len(s.observers))
+	for _, fn := range s.observers {
+		observers = append(observers, fn)
+	}
+	s.mutex.RUnlock()
+
+	for _, fn := range observers {
+		fn(record)
+	}
+}
+
+// localAddr 获取与对端通信使用的本端地址，串口会话返回空
+func (s *Session) localAddr(remoteAddr string) string {
+	if remoteAddr != "" {
+		if peer := s.getPeer(remoteAddr); peer != nil {
+			return peer.Conn.LocalAddr().String()
+		}
+	}
+	if conn, ok := s.Connection.(net.Conn); ok {
+		return conn.LocalAddr().String()
+	}
+	if s.PacketConn != nil {
+		return s.PacketConn.LocalAddr().String()
+	}
+	if s.Listener != nil {
+		return s.Listener.Addr().String()
+	}
+	return ""
+}
+
+// GetMessages 获取消息记录，按format渲染
+func (s *Session) GetMessages(limit, offset int, format string) []session.MessageRecord {
 	log.Printf("获取消息: 会话=%s, limit=%d, offset=%d", s.Info.SessionID, limit, offset)
 	messages, err := GetMessagesFromDB(s.Info.SessionID, limit, offset)
 	if err != nil {
@@ -150,6 +498,10 @@ func (s *Session) GetMessages(limit, offset int) []session.MessageRecord {
 		return []session.MessageRecord{}
 	}
 	log.Printf("从数据库获取到 %d 条消息", len(messages))
+
+	for i := range messages {
+		messages[i] = ClientRecord(RenderRecord(messages[i], format))
+	}
 	return messages
 }
 
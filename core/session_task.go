@@ -0,0 +1,125 @@
+package core
+
+// sessionTask 会话后台任务的停止信号，由具体任务嵌入
+type sessionTask struct {
+	stop chan struct{} // 关闭以停止任务
+}
+
+func newSessionTask() sessionTask {
+	return sessionTask{stop: make(chan struct{})}
+}
+
+// base 返回嵌入的后台任务
+func (t *sessionTask) base() *sessionTask {
+	return t
+}
+
+// sessionTaskHandle 嵌入了sessionTask的任务指针
+type sessionTaskHandle interface {
+	comparable
+	base() *sessionTask
+}
+
+// taskSlot 一类会话后台任务：同一会话同时只运行一个，启动新任务时停止旧任务，
+// 旧任务结束时不再覆盖新任务的状态
+type taskSlot[T sessionTaskHandle, S any] struct {
+	task  func(sess *Session) *T  // 会话上正在运行的任务
+	state func(sess *Session) **S // 会话信息中的任务状态
+	halt  func(state *S)          // 任务被停止时修改状态
+}
+
+// start 停止会话上正在运行的同类任务，登记新任务及其初始状态
+func (ts *taskSlot[T, S]) start(sess *Session, task T, state *S) {
+	sess.mutex.Lock()
+	defer sess.mutex.Unlock()
+
+	ts.cancelLocked(sess)
+	*ts.task(sess) = task
+	*ts.state(sess) = state
+}
+
+// cancel 停止会话上正在运行的任务
+func (ts *taskSlot[T, S]) cancel(sess *Session) {
+	sess.mutex.Lock()
+	defer sess.mutex.Unlock()
+
+	ts.cancelLocked(sess)
+}
+
+// cancelLocked 停止正在运行的任务，调用方需持有会话锁
+func (ts *taskSlot[T, S]) cancelLocked(sess *Session) {
+	var none T
+	task := ts.task(sess)
+	if *task == none {
+		return
+	}
+
+	close((*task).base().stop)
+	*task = none
+	if current := *ts.state(sess); current != nil {
+		state := *current
+		ts.halt(&state)
+		*ts.state(sess) = &state
+	}
+}
+
+// running 会话上是否有正在运行的任务
+func (ts *taskSlot[T, S]) running(sess *Session) bool {
+	sess.mutex.RLock()
+	defer sess.mutex.RUnlock()
+
+	var none T
+	return *ts.task(sess) != none
+}
+
+// stop 停止会话的任务并返回其状态
+func (ts *taskSlot[T, S]) stop(sessionID string) (*S, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ts.cancel(sess)
+	return ts.get(sessionID)
+}
+
+// get 获取会话的任务状态，未启动过时返回空状态
+func (ts *taskSlot[T, S]) get(sessionID string) (*S, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sess.mutex.RLock()
+	defer sess.mutex.RUnlock()
+
+	current := *ts.state(sess)
+	if current == nil {
+		return new(S), nil
+	}
+	state := *current
+	return &state, nil
+}
+
+// publish 任务仍在运行时更新会话信息中的状态快照
+func (ts *taskSlot[T, S]) publish(sess *Session, task T, snapshot S) {
+	sess.mutex.Lock()
+	defer sess.mutex.Unlock()
+
+	if *ts.task(sess) == task {
+		*ts.state(sess) = &snapshot
+	}
+}
+
+// finish 任务结束时写入最终状态，已被新任务替换时不再覆盖
+func (ts *taskSlot[T, S]) finish(sess *Session, task T, state S) {
+	sess.mutex.Lock()
+	defer sess.mutex.Unlock()
+
+	var none T
+	current := ts.task(sess)
+	if *current == task || *current == none {
+		*current = none
+		*ts.state(sess) = &state
+	}
+}
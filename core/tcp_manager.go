@@ -1,14 +1,16 @@
 package core
 
 import (
-	"encoding/hex"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
-	"strings"
+	"strconv"
 	"time"
 
 	"github.com/zhoudm1743/Netser/dto/session"
+	"github.com/zhoudm1743/Netser/dto/tcp"
 )
 
 // TCPManager TCP连接管理器
@@ -16,27 +18,53 @@ type TCPManager struct{}
 
 var GlobalTCPManager = &TCPManager{}
 
+// tcpPeer TCP服务端已接受的客户端连接
+type tcpPeer struct {
+	Address     string
+	Conn        net.Conn
+	ConnectTime int64
+	TLS         *session.TLSState // 仅TLS服务端
+}
+
+// isServerSession 是否为多客户端的TCP/TLS服务端会话
+func isServerSession(sess *Session) bool {
+	return sess.Info.Type == "tcpServer" || sess.Info.Type == "tlsServer"
+}
+
 // ConnectTCP TCP客户端连接
 func (tm *TCPManager) ConnectTCP(sessionID, host string, port int, timeout int) error {
+	return tm.connectTCP(sessionID, host, port, timeout, true)
+}
+
+// connectTCP 建立TCP客户端连接，notify为false时不更新连接状态(由自动重连统一更新)
+func (tm *TCPManager) connectTCP(sessionID, host string, port int, timeout int, notify bool) error {
 	sess, err := GlobalSessionManager.GetSession(sessionID)
 	if err != nil {
 		return err
 	}
 
 	// 更新状态为连接中
-	GlobalSessionManager.UpdateSessionStatus(sessionID, "connecting")
+	if notify {
+		GlobalSessionManager.UpdateSessionStatus(sessionID, "connecting")
+	}
 
 	// 建立连接
-	address := fmt.Sprintf("%s:%d", host, port)
+	address := net.JoinHostPort(host, strconv.Itoa(port))
 	conn, err := net.DialTimeout("tcp", address, time.Duration(timeout)*time.Second)
 	if err != nil {
-		GlobalSessionManager.UpdateSessionStatus(sessionID, "disconnected")
+		if notify {
+			GlobalSessionManager.UpdateSessionStatus(sessionID, "disconnected")
+		}
 		return fmt.Errorf("连接失败: %v", err)
 	}
 
 	sess.Connection = conn
+	sess.Info.Host = host
+	sess.Info.Port = port
 	sess.IsActive = true
-	GlobalSessionManager.UpdateSessionStatus(sessionID, "connected")
+	if notify {
+		GlobalSessionManager.UpdateSessionStatus(sessionID, "connected")
+	}
 
 	// 启动接收数据的协程
 	go tm.handleTCPReceive(sess)
@@ -86,6 +114,7 @@ func (tm *TCPManager) DisconnectTCP(sessionID string) error {
 	}
 
 	sess.IsActive = false
+	GlobalReconnectManager.Cancel(sess)
 
 	// 关闭连接
 	if sess.Connection != nil {
@@ -99,31 +128,48 @@ func (tm *TCPManager) DisconnectTCP(sessionID string) error {
 		sess.Listener = nil
 	}
 
+	// 关闭所有客户端连接
+	sess.closePeers()
+
 	GlobalSessionManager.UpdateSessionStatus(sessionID, "disconnected")
 	return nil
 }
 
 // SendTCPData 发送TCP数据
+// 服务端会话发送给所有已连接的客户端
 func (tm *TCPManager) SendTCPData(sessionID, data string, isHex bool) (*session.MessageRecord, error) {
+	return tm.SendTCPDataTo(sessionID, data, isHex, "")
+}
+
+// SendTCPDataTo 发送TCP数据到指定客户端
+// target为空时，服务端会话广播给所有客户端；客户端会话忽略target
+func (tm *TCPManager) SendTCPDataTo(sessionID, data string, isHex bool, target string) (*session.MessageRecord, error) {
+	sendData, err := buildSendPayload(sessionID, data, isHex)
+	if err != nil {
+		return nil, err
+	}
+
+	return tm.SendTCPBytes(sessionID, sendData, isHex, target)
+}
+
+// SendTCPBytes 发送原始字节，isHex仅影响记录的默认显示方式
+func (tm *TCPManager) SendTCPBytes(sessionID string, sendData []byte, isHex bool, target string) (*session.MessageRecord, error) {
+	return tm.sendTCPBytes(sessionID, sendData, isHex, target, "")
+}
+
+// sendTCPBytes 发送原始字节，rule为触发发送的自动应答规则
+func (tm *TCPManager) sendTCPBytes(sessionID string, sendData []byte, isHex bool, target, rule string) (*session.MessageRecord, error) {
 	sess, err := GlobalSessionManager.GetSession(sessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	if sess.Connection == nil {
-		return nil, fmt.Errorf("连接未建立")
+	if isServerSession(sess) {
+		return tm.sendToPeers(sess, sendData, isHex, target, rule)
 	}
 
-	var sendData []byte
-	if isHex {
-		// 处理十六进制数据
-		cleanHex := strings.ReplaceAll(data, " ", "")
-		sendData, err = hex.DecodeString(cleanHex)
-		if err != nil {
-			return nil, fmt.Errorf("十六进制数据格式错误: %v", err)
-		}
-	} else {
-		sendData = []byte(data)
+	if sess.Connection == nil {
+		return nil, fmt.Errorf("连接未建立")
 	}
 
 	// 发送数据
@@ -132,35 +178,82 @@ func (tm *TCPManager) SendTCPData(sessionID, data string, isHex bool) (*session.
 		return nil, fmt.Errorf("发送数据失败: %v", err)
 	}
 
-	// 创建消息记录
-	record := session.MessageRecord{
-		Direction:  "send",
-		Data:       data,
-		IsHex:      isHex,
-		Timestamp:  time.Now().UnixMilli(),
-		ByteLength: len(data),
-	}
-
 	// 记录发送的消息
-	sess.AddMessage("send", data, isHex)
+	record := sess.addMessage("send", sendData, isHex, "", rule)
 
 	// 通知WebSocket客户端
 	if GlobalWebSocketManager != nil {
-		GlobalWebSocketManager.NotifyTCPMessage(sessionID, "send", data, isHex, len(data))
+		GlobalWebSocketManager.NotifyMessageRecord(sessionID, record)
 	}
 
 	return &record, nil
 }
 
+// sendToPeers 服务端向一个或全部客户端发送数据，每个客户端单独记录
+func (tm *TCPManager) sendToPeers(sess *Session, sendData []byte, isHex bool, target, rule string) (*session.MessageRecord, error) {
+	var peers []*tcpPeer
+	if target != "" {
+		peer := sess.getPeer(target)
+		if peer == nil {
+			return nil, fmt.Errorf("客户端不存在: %s", target)
+		}
+		peers = append(peers, peer)
+	} else {
+		peers = sess.listPeers()
+	}
+
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("没有已连接的客户端")
+	}
+
+	var record *session.MessageRecord
+	var lastErr error
+	for _, peer := range peers {
+		if _, err := peer.Conn.Write(sendData); err != nil {
+			fmt.Printf("发送数据到 %s 失败: %v\n", peer.Address, err)
+			lastErr = err
+			continue
+		}
+
+		peerRecord := sess.addMessage("send", sendData, isHex, peer.Address, rule)
+		record = &peerRecord
+
+		if GlobalWebSocketManager != nil {
+			GlobalWebSocketManager.NotifyMessageRecord(sess.Info.SessionID, peerRecord)
+		}
+	}
+
+	if record == nil {
+		return nil, fmt.Errorf("发送数据失败: %v", lastErr)
+	}
+
+	return record, nil
+}
+
 // handleTCPReceive 处理TCP接收数据
 func (tm *TCPManager) handleTCPReceive(sess *Session) {
+	var readErr error
 	defer func() {
+		// IsActive仍为true说明不是主动断开
+		unexpected := sess.IsActive
 		if sess.Connection != nil {
 			sess.Connection.Close()
 			sess.Connection = nil
 		}
 		sess.IsActive = false
 		GlobalSessionManager.UpdateSessionStatus(sess.Info.SessionID, "disconnected")
+
+		if unexpected {
+			if readErr == nil {
+				readErr = fmt.Errorf("连接已断开")
+			}
+			GlobalReconnectManager.Schedule(sess, readErr)
+		}
+	}()
+
+	assembler := newSessionAssembler(sess, "")
+	defer func() {
+		assembler.Close()
 	}()
 
 	buffer := make([]byte, 4096)
@@ -182,17 +275,17 @@ func (tm *TCPManager) handleTCPReceive(sess *Session) {
 			if err != io.EOF {
 				fmt.Printf("读取数据错误: %v\n", err)
 			}
+			readErr = err
 			break
 		}
 
 		if n > 0 {
-			data := string(buffer[:n])
-			sess.AddMessage("receive", data, false)
-
-			// 通知WebSocket客户端
-			if GlobalWebSocketManager != nil {
-				GlobalWebSocketManager.NotifyTCPMessage(sess.Info.SessionID, "receive", data, false, len(data))
+			if sess.divertReceive(buffer[:n]) {
+				continue
 			}
+			// 按分帧配置记录并通知WebSocket客户端
+			assembler = assembler.sync(sess, "")
+			assembler.Write(buffer[:n])
 		}
 	}
 }
@@ -204,6 +297,7 @@ func (tm *TCPManager) handleTCPAccept(sess *Session) {
 			sess.Listener.Close()
 			sess.Listener = nil
 		}
+		sess.closePeers()
 		sess.IsActive = false
 		GlobalSessionManager.UpdateSessionStatus(sess.Info.SessionID, "disconnected")
 	}()
@@ -221,17 +315,119 @@ func (tm *TCPManager) handleTCPAccept(sess *Session) {
 			break
 		}
 
-		// 如果已有连接，关闭旧连接
-		if sess.Connection != nil {
-			sess.Connection.Close()
+		// 启动处理这个连接的协程
+		go tm.servePeer(sess, conn)
+	}
+}
+
+// servePeer 完成TLS握手（如有）后登记客户端并开始接收数据
+func (tm *TCPManager) servePeer(sess *Session, conn net.Conn) {
+	peer := &tcpPeer{
+		Address:     conn.RemoteAddr().String(),
+		Conn:        conn,
+		ConnectTime: time.Now().UnixMilli(),
+	}
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		tlsConn.SetDeadline(time.Now().Add(10 * time.Second))
+		if err := tlsConn.Handshake(); err != nil {
+			fmt.Printf("客户端 %s TLS握手失败: %v\n", peer.Address, err)
+			conn.Close()
+			return
+		}
+		tlsConn.SetDeadline(time.Time{})
+
+		state := DescribeTLSState(tlsConn.ConnectionState())
+		peer.TLS = &state
+	}
+
+	sess.addPeer(peer)
+	GlobalSessionManager.UpdateSessionStatus(sess.Info.SessionID, "connected")
+
+	if GlobalWebSocketManager != nil {
+		GlobalWebSocketManager.NotifyPeerStatus(sess.Info.SessionID, peer.Address, "connected", peer.TLS)
+	}
+
+	tm.handlePeerReceive(sess, peer)
+}
+
+// handlePeerReceive 处理服务端单个客户端的接收数据
+func (tm *TCPManager) handlePeerReceive(sess *Session, peer *tcpPeer) {
+	defer func() {
+		peer.Conn.Close()
+		remaining := sess.removePeer(peer)
+
+		if GlobalWebSocketManager != nil {
+			GlobalWebSocketManager.NotifyPeerStatus(sess.Info.SessionID, peer.Address, "disconnected", nil)
+		}
+
+		// 最后一个客户端断开后恢复为监听状态
+		if remaining == 0 && sess.IsActive {
+			GlobalSessionManager.UpdateSessionStatus(sess.Info.SessionID, "listening")
+		}
+	}()
+
+	assembler := newSessionAssembler(sess, peer.Address)
+	defer func() {
+		assembler.Close()
+	}()
+
+	buffer := make([]byte, 4096)
+	for sess.IsActive {
+		peer.Conn.SetReadDeadline(time.Now().Add(1 * time.Second))
+
+		n, err := peer.Conn.Read(buffer)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue // 超时继续循环
+			}
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				fmt.Printf("读取客户端 %s 数据错误: %v\n", peer.Address, err)
+			}
+			break
 		}
 
-		sess.Connection = conn
-		GlobalSessionManager.UpdateSessionStatus(sess.Info.SessionID, "connected")
+		if n > 0 {
+			// 按分帧配置记录并通知WebSocket客户端
+			assembler = assembler.sync(sess, peer.Address)
+			assembler.Write(buffer[:n])
+		}
+	}
+}
 
-		// 启动处理这个连接的接收数据协程
-		go tm.handleTCPReceive(sess)
+// GetPeers 获取服务端会话的已连接客户端列表
+func (tm *TCPManager) GetPeers(sessionID string) ([]tcp.TCPPeerInfo, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	peers := sess.listPeers()
+	result := make([]tcp.TCPPeerInfo, 0, len(peers))
+	for _, peer := range peers {
+		result = append(result, tcp.TCPPeerInfo{
+			Address:     peer.Address,
+			ConnectTime: peer.ConnectTime,
+			TLS:         peer.TLS,
+		})
 	}
+	return result, nil
+}
+
+// KickPeer 断开服务端会话的指定客户端
+func (tm *TCPManager) KickPeer(sessionID, address string) error {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	peer := sess.getPeer(address)
+	if peer == nil {
+		return fmt.Errorf("客户端不存在: %s", address)
+	}
+
+	// 关闭连接后由接收协程负责清理
+	return peer.Conn.Close()
 }
 
 // CreateTCPSession 创建TCP会话
@@ -0,0 +1,264 @@
+package core
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+// TLSManager TLS连接管理器，收发复用TCPManager
+type TLSManager struct{}
+
+var GlobalTLSManager = &TLSManager{}
+
+// ConnectTLS TLS客户端连接
+func (lm *TLSManager) ConnectTLS(sessionID, host string, port int, timeout int, cfg *session.TLSConfig) error {
+	return lm.connectTLS(sessionID, host, port, timeout, cfg, true)
+}
+
+// connectTLS 建立TLS客户端连接，notify为false时不更新连接状态(由自动重连统一更新)
+func (lm *TLSManager) connectTLS(sessionID, host string, port int, timeout int, cfg *session.TLSConfig, notify bool) error {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	// 未指定配置时使用创建会话时保存的配置
+	if cfg == nil {
+		cfg = sess.Info.TLS
+	}
+
+	tlsConfig, err := buildTLSConfig(cfg, false)
+	if err != nil {
+		return err
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+
+	if notify {
+		GlobalSessionManager.UpdateSessionStatus(sessionID, "connecting")
+	}
+
+	dialer := &net.Dialer{Timeout: time.Duration(timeout) * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, strconv.Itoa(port)), tlsConfig)
+	if err != nil {
+		if notify {
+			GlobalSessionManager.UpdateSessionStatus(sessionID, "disconnected")
+		}
+		return fmt.Errorf("TLS连接失败: %v", err)
+	}
+
+	state := DescribeTLSState(conn.ConnectionState())
+	sess.Info.TLSState = &state
+	sess.Info.Host = host
+	sess.Info.Port = port
+	sess.Connection = conn
+	sess.IsActive = true
+	if notify {
+		GlobalSessionManager.UpdateSessionStatus(sessionID, "connected")
+	}
+
+	go GlobalTCPManager.handleTCPReceive(sess)
+
+	return nil
+}
+
+// ListenTLS TLS服务端监听
+func (lm *TLSManager) ListenTLS(sessionID string, port int, cfg *session.TLSConfig) error {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if cfg == nil {
+		cfg = sess.Info.TLS
+	}
+
+	tlsConfig, err := buildTLSConfig(cfg, true)
+	if err != nil {
+		return err
+	}
+
+	GlobalSessionManager.UpdateSessionStatus(sessionID, "connecting")
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		GlobalSessionManager.UpdateSessionStatus(sessionID, "disconnected")
+		return fmt.Errorf("监听失败: %v", err)
+	}
+
+	sess.Listener = tls.NewListener(listener, tlsConfig)
+	sess.Info.TLSState = nil
+	sess.IsActive = true
+	GlobalSessionManager.UpdateSessionStatus(sessionID, "listening")
+
+	go GlobalTCPManager.handleTCPAccept(sess)
+
+	return nil
+}
+
+// CreateTLSSession 创建TLS会话
+func (lm *TLSManager) CreateTLSSession(name, sessionType, host string, port int, isHex bool, cfg *session.TLSConfig) (string, error) {
+	sessionID := fmt.Sprintf("tls_%d", time.Now().UnixNano())
+
+	if cfg == nil {
+		cfg = &session.TLSConfig{}
+	}
+
+	info := session.SessionInfo{
+		SessionID:   sessionID,
+		Type:        sessionType,
+		Name:        name,
+		Status:      "disconnected",
+		Host:        host,
+		Port:        port,
+		Protocol:    "tls",
+		IsHex:       isHex,
+		ConnectTime: 0,
+		TLS:         cfg,
+	}
+
+	GlobalSessionManager.CreateSession(info)
+	return sessionID, nil
+}
+
+// DescribeTLSState 提取握手协商结果与对端证书链
+func DescribeTLSState(cs tls.ConnectionState) session.TLSState {
+	state := session.TLSState{
+		Version:          tls.VersionName(cs.Version),
+		CipherSuite:      tls.CipherSuiteName(cs.CipherSuite),
+		NegotiatedALPN:   cs.NegotiatedProtocol,
+		ServerName:       cs.ServerName,
+		PeerCertificates: make([]session.CertificateInfo, 0, len(cs.PeerCertificates)),
+	}
+
+	for _, cert := range cs.PeerCertificates {
+		state.PeerCertificates = append(state.PeerCertificates, DescribeCertificate(cert))
+	}
+	return state
+}
+
+// DescribeCertificate 提取证书摘要
+func DescribeCertificate(cert *x509.Certificate) session.CertificateInfo {
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	info := session.CertificateInfo{
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: cert.SerialNumber.Text(16),
+		NotBefore:    cert.NotBefore.UnixMilli(),
+		NotAfter:     cert.NotAfter.UnixMilli(),
+		DNSNames:     cert.DNSNames,
+		IPAddresses:  make([]string, 0, len(cert.IPAddresses)),
+		SHA256:       hex.EncodeToString(fingerprint[:]),
+	}
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	return info
+}
+
+// buildTLSConfig 根据会话配置构建tls.Config
+func buildTLSConfig(cfg *session.TLSConfig, isServer bool) (*tls.Config, error) {
+	if cfg == nil {
+		cfg = &session.TLSConfig{}
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		NextProtos:         cfg.ALPN,
+	}
+
+	// 使用内置证书时解析为文件路径
+	certFile, keyFile, caFile := cfg.CertFile, cfg.KeyFile, cfg.CAFile
+	if cfg.CertName != "" {
+		info, err := GlobalCertManager.GetCert(cfg.CertName)
+		if err != nil {
+			return nil, err
+		}
+		certFile, keyFile = info.CertFile, info.KeyFile
+	}
+	if cfg.CAName != "" {
+		info, err := GlobalCertManager.GetCert(cfg.CAName)
+		if err != nil {
+			return nil, err
+		}
+		caFile = info.CertFile
+	}
+
+	var err error
+	if tlsConfig.MinVersion, err = parseTLSVersion(cfg.MinVersion); err != nil {
+		return nil, err
+	}
+	if tlsConfig.MaxVersion, err = parseTLSVersion(cfg.MaxVersion); err != nil {
+		return nil, err
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载证书失败: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	} else if isServer {
+		return nil, fmt.Errorf("TLS服务端必须配置证书和私钥")
+	}
+
+	var pool *x509.CertPool
+	if caFile != "" {
+		pemData, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取CA证书失败: %v", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("CA证书文件中没有有效的PEM证书")
+		}
+	}
+
+	if isServer {
+		tlsConfig.ClientCAs = pool
+		switch {
+		case cfg.RequireClientCert && cfg.InsecureSkipVerify:
+			tlsConfig.ClientAuth = tls.RequireAnyClientCert
+		case cfg.RequireClientCert:
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		case pool != nil:
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		default:
+			tlsConfig.ClientAuth = tls.RequestClientCert
+		}
+	} else {
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// parseTLSVersion 解析TLS版本号，为空时使用默认值
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("不支持的TLS版本: %s", version)
+	}
+}
@@ -0,0 +1,242 @@
+package core
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+// UDPManager UDP连接管理器
+type UDPManager struct{}
+
+var GlobalUDPManager = &UDPManager{}
+
+// ConnectUDP UDP客户端连接（绑定默认远端地址）
+func (um *UDPManager) ConnectUDP(sessionID, host string, port int) error {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	GlobalSessionManager.UpdateSessionStatus(sessionID, "connecting")
+
+	remoteAddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		GlobalSessionManager.UpdateSessionStatus(sessionID, "disconnected")
+		return fmt.Errorf("解析地址失败: %v", err)
+	}
+
+	conn, err := net.DialUDP("udp", nil, remoteAddr)
+	if err != nil {
+		GlobalSessionManager.UpdateSessionStatus(sessionID, "disconnected")
+		return fmt.Errorf("连接失败: %v", err)
+	}
+
+	sess.PacketConn = conn
+	sess.RemoteAddr = remoteAddr
+	sess.IsActive = true
+	GlobalSessionManager.UpdateSessionStatus(sessionID, "connected")
+
+	go um.handleUDPReceive(sess)
+
+	return nil
+}
+
+// ListenUDP UDP服务端监听
+func (um *UDPManager) ListenUDP(sessionID string, port int) error {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	GlobalSessionManager.UpdateSessionStatus(sessionID, "connecting")
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: port})
+	if err != nil {
+		GlobalSessionManager.UpdateSessionStatus(sessionID, "disconnected")
+		return fmt.Errorf("监听失败: %v", err)
+	}
+
+	sess.PacketConn = conn
+	sess.RemoteAddr = nil
+	sess.IsActive = true
+	GlobalSessionManager.UpdateSessionStatus(sessionID, "listening")
+
+	go um.handleUDPReceive(sess)
+
+	return nil
+}
+
+// DisconnectUDP 断开UDP会话
+func (um *UDPManager) DisconnectUDP(sessionID string) error {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	sess.mutex.Lock()
+	sess.IsActive = false
+	if sess.PacketConn != nil {
+		sess.PacketConn.Close()
+		sess.PacketConn = nil
+	}
+	sess.RemoteAddr = nil
+	sess.multicast = nil
+	sess.mutex.Unlock()
+
+	GlobalSessionManager.UpdateSessionStatus(sessionID, "disconnected")
+	return nil
+}
+
+// SendUDPData 发送UDP数据报
+// target为空时，客户端发送到连接地址，服务端发送到最近一个对端地址
+func (um *UDPManager) SendUDPData(sessionID, data string, isHex bool, target string) (*session.MessageRecord, error) {
+	sendData, err := buildSendPayload(sessionID, data, isHex)
+	if err != nil {
+		return nil, err
+	}
+
+	return um.SendUDPBytes(sessionID, sendData, isHex, target)
+}
+
+// SendUDPBytes 发送原始字节数据报
+func (um *UDPManager) SendUDPBytes(sessionID string, sendData []byte, isHex bool, target string) (*session.MessageRecord, error) {
+	return um.sendUDPBytes(sessionID, sendData, isHex, target, "")
+}
+
+// sendUDPBytes 发送原始字节数据报，rule为触发发送的自动应答规则
+func (um *UDPManager) sendUDPBytes(sessionID string, sendData []byte, isHex bool, target, rule string) (*session.MessageRecord, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if sess.PacketConn == nil {
+		return nil, fmt.Errorf("连接未建立")
+	}
+
+	conn, ok := sess.PacketConn.(*net.UDPConn)
+	if !ok {
+		return nil, fmt.Errorf("不支持的UDP连接类型")
+	}
+
+	var remoteAddr net.Addr
+	var targetAddr *net.UDPAddr
+	if target != "" {
+		targetAddr, err = net.ResolveUDPAddr("udp", target)
+		if err != nil {
+			return nil, fmt.Errorf("目标地址格式错误: %v", err)
+		}
+		remoteAddr = targetAddr
+	} else {
+		remoteAddr = sess.RemoteAddr
+	}
+
+	if remoteAddr == nil {
+		return nil, fmt.Errorf("没有可发送的目标地址")
+	}
+
+	// 已连接的UDP套接字只能使用Write发送到连接的对端
+	if connected, ok := conn.RemoteAddr().(*net.UDPAddr); ok && connected != nil {
+		if targetAddr != nil && !sameUDPAddr(targetAddr, connected) {
+			return nil, fmt.Errorf("UDP客户端只能发送到 %s", connected)
+		}
+		remoteAddr = connected
+		_, err = conn.Write(sendData)
+	} else {
+		_, err = conn.WriteTo(sendData, remoteAddr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("发送数据失败: %v", err)
+	}
+
+	record := sess.addMessage("send", sendData, isHex, remoteAddr.String(), rule)
+
+	if GlobalWebSocketManager != nil {
+		GlobalWebSocketManager.NotifyMessageRecord(sessionID, record)
+	}
+
+	return &record, nil
+}
+
+// sameUDPAddr 判断两个UDP地址是否相同
+func sameUDPAddr(a, b *net.UDPAddr) bool {
+	return a.Port == b.Port && a.IP.Equal(b.IP)
+}
+
+// handleUDPReceive 处理UDP接收数据报，会话已换用新的套接字时只关闭自己读取的套接字
+func (um *UDPManager) handleUDPReceive(sess *Session) {
+	conn := sess.PacketConn
+	defer func() {
+		conn.Close()
+
+		sess.mutex.Lock()
+		current := sess.PacketConn == conn
+		if current {
+			sess.PacketConn = nil
+			sess.multicast = nil
+			sess.IsActive = false
+		}
+		sess.mutex.Unlock()
+
+		// 主动断开时DisconnectUDP已清理并通知
+		if current {
+			GlobalSessionManager.UpdateSessionStatus(sess.Info.SessionID, "disconnected")
+		}
+	}()
+
+	buffer := make([]byte, 65535)
+	for sess.IsActive && sess.PacketConn == conn {
+		conn.SetReadDeadline(time.Now().Add(1 * time.Second))
+
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue
+			}
+			if sess.IsActive && sess.PacketConn == conn {
+				fmt.Printf("读取UDP数据错误: %v\n", err)
+			}
+			break
+		}
+
+		// 服务端记住最近的对端地址，用于默认回复
+		if sess.Info.Type == "udpServer" {
+			sess.RemoteAddr = addr
+		}
+
+		if n > 0 {
+			raw := append([]byte(nil), buffer[:n]...)
+			record := sess.AddRawMessage("receive", raw, sess.Info.IsHex, addr.String())
+
+			if GlobalWebSocketManager != nil {
+				GlobalWebSocketManager.NotifyMessageRecord(sess.Info.SessionID, record)
+			}
+
+			sess.respond(record)
+		}
+	}
+}
+
+// CreateUDPSession 创建UDP会话
+func (um *UDPManager) CreateUDPSession(name, sessionType, host string, port int, isHex bool) (string, error) {
+	sessionID := fmt.Sprintf("udp_%d", time.Now().UnixNano())
+
+	info := session.SessionInfo{
+		SessionID:   sessionID,
+		Type:        sessionType,
+		Name:        name,
+		Status:      "disconnected",
+		Host:        host,
+		Port:        port,
+		Protocol:    "udp",
+		IsHex:       isHex,
+		ConnectTime: 0,
+	}
+
+	GlobalSessionManager.CreateSession(info)
+	return sessionID, nil
+}
@@ -193,6 +193,9 @@ func (c *WSClient) handleSubscribe(message *wsProtocol.BaseMessage) {
 	}
 	c.Manager.sessions[sessionID][c.ID] = true
 	c.Subscriptions[sessionID] = true
+	c.mutex.Lock()
+	c.Formats[sessionID] = subscribeData.Format
+	c.mutex.Unlock()
 	c.Manager.mutex.Unlock()
 
 	// 发送订阅成功响应
@@ -244,6 +247,9 @@ func (c *WSClient) handleUnsubscribe(message *wsProtocol.BaseMessage) {
 		}
 	}
 	delete(c.Subscriptions, sessionID)
+	c.mutex.Lock()
+	delete(c.Formats, sessionID)
+	c.mutex.Unlock()
 	c.Manager.mutex.Unlock()
 
 	// 发送取消订阅成功响应
@@ -264,6 +270,14 @@ func (c *WSClient) handleUnsubscribe(message *wsProtocol.BaseMessage) {
 	log.Printf("客户端 %s 取消订阅会话 %s", c.ID, sessionID)
 }
 
+// subscriptionFormat 获取订阅会话的消息渲染格式
+func (c *WSClient) subscriptionFormat(sessionID string) string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.Formats[sessionID]
+}
+
 // handlePing 处理心跳消息
 func (c *WSClient) handlePing(message *wsProtocol.BaseMessage) {
 	// 发送心跳响应
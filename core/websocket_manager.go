@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/zhoudm1743/Netser/dto/modbus"
+	serialDto "github.com/zhoudm1743/Netser/dto/serial"
+	"github.com/zhoudm1743/Netser/dto/session"
 	wsProtocol "github.com/zhoudm1743/Netser/dto/websocket"
 )
 
@@ -34,6 +37,7 @@ type WSClient struct {
 	Manager       *WebSocketManager // 管理器引用
 	LastPing      time.Time         // 最后心跳时间
 	Subscriptions map[string]bool   // 订阅的会话列表
+	Formats       map[string]string // 订阅会话的消息渲染格式 sessionId -> format
 	mutex         sync.RWMutex      // 读写锁
 }
 
@@ -192,6 +196,7 @@ func (wm *WebSocketManager) handleWebSocket(w http.ResponseWriter, r *http.Reque
 		Manager:       wm,
 		LastPing:      time.Now(),
 		Subscriptions: make(map[string]bool),
+		Formats:       make(map[string]string),
 	}
 
 	wm.mutex.Lock()
@@ -207,77 +212,349 @@ func (wm *WebSocketManager) handleWebSocket(w http.ResponseWriter, r *http.Reque
 
 // BroadcastToSession 向订阅特定会话的客户端广播消息
 func (wm *WebSocketManager) BroadcastToSession(sessionID string, message []byte) {
+	for _, client := range wm.sessionClients(sessionID) {
+		wm.sendToClient(client, message)
+	}
+}
+
+// BroadcastToAll 向所有已连接的客户端广播消息
+func (wm *WebSocketManager) BroadcastToAll(message []byte) {
 	wm.mutex.RLock()
+	clients := make([]*WSClient, 0, len(wm.clients))
+	for _, client := range wm.clients {
+		clients = append(clients, client)
+	}
+	wm.mutex.RUnlock()
+
+	for _, client := range clients {
+		wm.sendToClient(client, message)
+	}
+}
+
+// sessionClients 获取订阅特定会话的客户端列表
+func (wm *WebSocketManager) sessionClients(sessionID string) []*WSClient {
+	wm.mutex.RLock()
+	defer wm.mutex.RUnlock()
+
 	clientsMap, exists := wm.sessions[sessionID]
 	if !exists {
-		wm.mutex.RUnlock()
-		return
+		return nil
 	}
 
 	// 复制客户端列表避免长时间锁定
-	clientIDs := make([]string, 0, len(clientsMap))
+	clients := make([]*WSClient, 0, len(clientsMap))
 	for clientID := range clientsMap {
-		clientIDs = append(clientIDs, clientID)
+		if client, exists := wm.clients[clientID]; exists {
+			clients = append(clients, client)
+		}
 	}
-	wm.mutex.RUnlock()
+	return clients
+}
 
-	// 向所有订阅的客户端发送消息
-	for _, clientID := range clientIDs {
-		wm.mutex.RLock()
-		client, exists := wm.clients[clientID]
-		wm.mutex.RUnlock()
-
-		if exists {
-			select {
-			case client.Send <- message:
-			default:
-				// 发送通道满，异步关闭客户端避免死锁
-				log.Printf("客户端 %s 发送通道满，关闭连接", clientID)
-				go wm.removeClient(client)
-			}
+// sendToClient 向单个客户端发送消息
+func (wm *WebSocketManager) sendToClient(client *WSClient, message []byte) {
+	select {
+	case client.Send <- message:
+	default:
+		// 发送通道满，异步关闭客户端避免死锁
+		log.Printf("客户端 %s 发送通道满，关闭连接", client.ID)
+		go wm.removeClient(client)
+	}
+}
+
+// NotifyMessageRecord 推送消息记录，按每个客户端订阅时指定的格式渲染
+func (wm *WebSocketManager) NotifyMessageRecord(sessionID string, record session.MessageRecord) {
+	// 按渲染格式分组，每种格式只序列化一次
+	groups := make(map[string][]*WSClient)
+	for _, client := range wm.sessionClients(sessionID) {
+		format := client.subscriptionFormat(sessionID)
+		groups[format] = append(groups[format], client)
+	}
+
+	for format, clients := range groups {
+		rendered := RenderRecord(record, format)
+		msgData := wsProtocol.TCPMessageData{
+			SessionID:  sessionID,
+			Direction:  rendered.Direction,
+			Content:    rendered.Data,
+			IsHex:      rendered.IsHex,
+			ByteLength: rendered.ByteLength,
+			Timestamp:  rendered.Timestamp,
+			RemoteAddr: rendered.RemoteAddr,
+			Hex:        rendered.Hex,
+			Text:       rendered.Text,
+			Rule:       rendered.Rule,
+
+			ChecksumStatus:   rendered.ChecksumStatus,
+			ChecksumExpected: rendered.ChecksumExpected,
+		}
+		if rendered.Transfer != nil {
+			msgData.File = rendered.Transfer.File
+		}
+
+		message := wsProtocol.NewBaseMessage(wsProtocol.MsgTypeTCPMessage, msgData)
+		jsonData, err := message.ToJSON()
+		if err != nil {
+			log.Printf("序列化TCP消息失败: %v", err)
+			continue
+		}
+
+		for _, client := range clients {
+			wm.sendToClient(client, []byte(jsonData))
 		}
 	}
 }
 
-// NotifyTCPMessage 通知TCP消息
-func (wm *WebSocketManager) NotifyTCPMessage(sessionID, direction, content string, isHex bool, byteLength int) {
-	msgData := wsProtocol.TCPMessageData{
+// NotifySessionStatus 通知会话状态变化
+func (wm *WebSocketManager) NotifySessionStatus(sessionID, status string, attempt int, lastError string) {
+	msgData := wsProtocol.SessionStatusData{
+		SessionID: sessionID,
+		Status:    status,
+		Timestamp: time.Now().UnixMilli(),
+		Attempt:   attempt,
+		LastError: lastError,
+	}
+
+	message := wsProtocol.NewBaseMessage(wsProtocol.MsgTypeSessionStatus, msgData)
+	jsonData, err := message.ToJSON()
+	if err != nil {
+		log.Printf("序列化会话状态消息失败: %v", err)
+		return
+	}
+
+	wm.BroadcastToSession(sessionID, []byte(jsonData))
+}
+
+// NotifyPeerStatus 通知TCP服务端客户端连接状态变化
+func (wm *WebSocketManager) NotifyPeerStatus(sessionID, address, status string, tlsState *session.TLSState) {
+	msgData := wsProtocol.PeerStatusData{
+		SessionID: sessionID,
+		Address:   address,
+		Status:    status,
+		TLS:       tlsState,
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	message := wsProtocol.NewBaseMessage(wsProtocol.MsgTypePeerStatus, msgData)
+	jsonData, err := message.ToJSON()
+	if err != nil {
+		log.Printf("序列化客户端状态消息失败: %v", err)
+		return
+	}
+
+	wm.BroadcastToSession(sessionID, []byte(jsonData))
+}
+
+// NotifyAutoSend 通知定时发送进度
+func (wm *WebSocketManager) NotifyAutoSend(sessionID string, state session.AutoSendState) {
+	msgData := wsProtocol.AutoSendData{
+		SessionID: sessionID,
+		Running:   state.Running,
+		Sent:      state.Sent,
+		Failed:    state.Failed,
+		Count:     state.Count,
+		LastError: state.LastError,
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	message := wsProtocol.NewBaseMessage(wsProtocol.MsgTypeAutoSend, msgData)
+	jsonData, err := message.ToJSON()
+	if err != nil {
+		log.Printf("序列化定时发送消息失败: %v", err)
+		return
+	}
+
+	wm.BroadcastToSession(sessionID, []byte(jsonData))
+}
+
+// NotifyReplay 通知流量回放进度，mismatch为本次新增的不匹配记录(可选)
+func (wm *WebSocketManager) NotifyReplay(sessionID string, state session.ReplayState, mismatch *session.ReplayMismatch) {
+	msgData := wsProtocol.ReplayData{
 		SessionID:  sessionID,
-		Direction:  direction,
-		Content:    content,
-		IsHex:      isHex,
-		ByteLength: byteLength,
+		Running:    state.Running,
+		Total:      state.Total,
+		Sent:       state.Sent,
+		Failed:     state.Failed,
+		Matched:    state.Matched,
+		Mismatched: state.Mismatched,
+		LastError:  state.LastError,
 		Timestamp:  time.Now().UnixMilli(),
 	}
+	if mismatch != nil {
+		msgData.Expected = mismatch.Expected
+		msgData.Actual = mismatch.Actual
+	}
 
-	message := wsProtocol.NewBaseMessage(wsProtocol.MsgTypeTCPMessage, msgData)
+	message := wsProtocol.NewBaseMessage(wsProtocol.MsgTypeReplay, msgData)
 	jsonData, err := message.ToJSON()
 	if err != nil {
-		log.Printf("序列化TCP消息失败: %v", err)
+		log.Printf("序列化回放消息失败: %v", err)
 		return
 	}
 
 	wm.BroadcastToSession(sessionID, []byte(jsonData))
 }
 
-// NotifySessionStatus 通知会话状态变化
-func (wm *WebSocketManager) NotifySessionStatus(sessionID, status string) {
-	msgData := wsProtocol.SessionStatusData{
+// NotifyFileSend 通知发送文件进度
+func (wm *WebSocketManager) NotifyFileSend(sessionID string, state session.FileSendState) {
+	msgData := wsProtocol.FileSendData{
+		SessionID:  sessionID,
+		Running:    state.Running,
+		Path:       state.Path,
+		Size:       state.Size,
+		Sent:       state.Sent,
+		Chunks:     state.Chunks,
+		Throughput: state.Throughput,
+		Canceled:   state.Canceled,
+		LastError:  state.LastError,
+		Timestamp:  time.Now().UnixMilli(),
+	}
+
+	message := wsProtocol.NewBaseMessage(wsProtocol.MsgTypeFileSend, msgData)
+	jsonData, err := message.ToJSON()
+	if err != nil {
+		log.Printf("序列化发送文件消息失败: %v", err)
+		return
+	}
+
+	wm.BroadcastToSession(sessionID, []byte(jsonData))
+}
+
+// NotifyXModem 通知XMODEM/YMODEM传输进度
+func (wm *WebSocketManager) NotifyXModem(sessionID string, state session.XModemState) {
+	msgData := wsProtocol.XModemData{
+		SessionID:  sessionID,
+		Running:    state.Running,
+		Protocol:   state.Protocol,
+		Direction:  state.Direction,
+		File:       state.File,
+		Size:       state.Size,
+		Bytes:      state.Bytes,
+		Blocks:     state.Blocks,
+		RetryCount: state.RetryCount,
+		Throughput: state.Throughput,
+		Canceled:   state.Canceled,
+		LastError:  state.LastError,
+		Timestamp:  time.Now().UnixMilli(),
+	}
+
+	message := wsProtocol.NewBaseMessage(wsProtocol.MsgTypeXModem, msgData)
+	jsonData, err := message.ToJSON()
+	if err != nil {
+		log.Printf("序列化XMODEM传输消息失败: %v", err)
+		return
+	}
+
+	wm.BroadcastToSession(sessionID, []byte(jsonData))
+}
+
+// NotifyModbusPoll 通知Modbus轮询结果
+func (wm *WebSocketManager) NotifyModbusPoll(sessionID string, state modbus.ModbusPollState) {
+	msgData := wsProtocol.ModbusPollData{
 		SessionID: sessionID,
-		Status:    status,
+		PollID:    state.ID,
+		Name:      state.Name,
+		Running:   state.Running,
+		Polls:     state.Polls,
+		Failed:    state.Failed,
 		Timestamp: time.Now().UnixMilli(),
 	}
+	if state.LastResult != nil {
+		msgData.Result = state.LastResult
+	}
 
-	message := wsProtocol.NewBaseMessage(wsProtocol.MsgTypeSessionStatus, msgData)
+	message := wsProtocol.NewBaseMessage(wsProtocol.MsgTypeModbusPoll, msgData)
 	jsonData, err := message.ToJSON()
 	if err != nil {
-		log.Printf("序列化会话状态消息失败: %v", err)
+		log.Printf("序列化Modbus轮询消息失败: %v", err)
 		return
 	}
 
 	wm.BroadcastToSession(sessionID, []byte(jsonData))
 }
 
+// NotifyModbusSlave 通知Modbus从站数据变化
+func (wm *WebSocketManager) NotifyModbusSlave(sessionID, table string, address int, values []int) {
+	msgData := wsProtocol.ModbusSlaveData{
+		SessionID: sessionID,
+		Table:     table,
+		Address:   address,
+		Values:    values,
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	message := wsProtocol.NewBaseMessage(wsProtocol.MsgTypeModbusSlave, msgData)
+	jsonData, err := message.ToJSON()
+	if err != nil {
+		log.Printf("序列化Modbus从站消息失败: %v", err)
+		return
+	}
+
+	wm.BroadcastToSession(sessionID, []byte(jsonData))
+}
+
+// NotifyScriptLog 推送会话脚本输出的日志
+func (wm *WebSocketManager) NotifyScriptLog(sessionID, level, message string) {
+	msgData := wsProtocol.ScriptLogData{
+		SessionID: sessionID,
+		Level:     level,
+		Message:   message,
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	msg := wsProtocol.NewBaseMessage(wsProtocol.MsgTypeScriptLog, msgData)
+	jsonData, err := msg.ToJSON()
+	if err != nil {
+		log.Printf("序列化脚本日志失败: %v", err)
+		return
+	}
+
+	wm.BroadcastToSession(sessionID, []byte(jsonData))
+}
+
+// NotifyModemStatus 通知串口调制解调器线状态变化
+func (wm *WebSocketManager) NotifyModemStatus(sessionID string, status session.ModemStatus) {
+	msgData := wsProtocol.ModemStatusData{
+		SessionID: sessionID,
+		CTS:       status.CTS,
+		DSR:       status.DSR,
+		RI:        status.RI,
+		DCD:       status.DCD,
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	message := wsProtocol.NewBaseMessage(wsProtocol.MsgTypeModemStatus, msgData)
+	jsonData, err := message.ToJSON()
+	if err != nil {
+		log.Printf("序列化调制解调器状态消息失败: %v", err)
+		return
+	}
+
+	wm.BroadcastToSession(sessionID, []byte(jsonData))
+}
+
+// NotifyPortEvent 通知串口插拔事件，推送给所有客户端
+func (wm *WebSocketManager) NotifyPortEvent(msgType wsProtocol.MessageType, port serialDto.SerialPortInfo) {
+	msgData := wsProtocol.PortEventData{
+		Name:         port.Name,
+		IsUSB:        port.IsUSB,
+		VID:          port.VID,
+		PID:          port.PID,
+		SerialNumber: port.SerialNumber,
+		Product:      port.Product,
+		Timestamp:    time.Now().UnixMilli(),
+	}
+
+	message := wsProtocol.NewBaseMessage(msgType, msgData)
+	jsonData, err := message.ToJSON()
+	if err != nil {
+		log.Printf("序列化串口事件消息失败: %v", err)
+		return
+	}
+
+	wm.BroadcastToAll([]byte(jsonData))
+}
+
 // removeClient 移除客户端
 func (wm *WebSocketManager) removeClient(client *WSClient) {
 	wm.mutex.Lock()
@@ -0,0 +1,584 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+// XMODEM/YMODEM协议
+const (
+	ProtocolXModem   = "xmodem"   // XMODEM-CRC，128字节块
+	ProtocolXModem1K = "xmodem1k" // XMODEM-1K，1024字节块
+	ProtocolYModem   = "ymodem"   // YMODEM批量传输，1024字节块
+)
+
+// XMODEM控制字符
+const (
+	xmodemSOH = 0x01 // 128字节数据包头
+	xmodemSTX = 0x02 // 1024字节数据包头
+	xmodemEOT = 0x04 // 传输结束
+	xmodemACK = 0x06 // 确认
+	xmodemNAK = 0x15 // 否认，接收方以NAK发起时使用累加和校验
+	xmodemCAN = 0x18 // 取消
+	xmodemCRC = 'C'  // 接收方以C发起时使用CRC校验
+	xmodemSUB = 0x1A // 填充字节
+)
+
+const (
+	// 等待对方发起传输的时间
+	xmodemHandshakeTimeout = 60 * time.Second
+	// 接收方发起传输时重发C的间隔
+	xmodemHandshakeInterval = 3 * time.Second
+	// 发送方不响应C时，改发NAK使用累加和校验前发送C的次数
+	xmodemCRCAttempts = 3
+	// 出错后清空输入时等待线路空闲的时间
+	xmodemPurgeTimeout = 500 * time.Millisecond
+)
+
+var (
+	errXModemTimeout   = errors.New("等待超时")
+	errXModemCanceled  = errors.New("传输已取消")
+	errXModemRemote    = errors.New("对方取消传输")
+	errXModemBadPacket = errors.New("数据包错误")
+)
+
+// xmodemPort 传输协议使用的收发通道
+type xmodemPort interface {
+	readByte(timeout time.Duration) (byte, error)
+	write(data []byte) error
+}
+
+// xmodemEngine XMODEM/YMODEM协议状态机
+type xmodemEngine struct {
+	port     xmodemPort
+	timeout  time.Duration
+	retries  int
+	crc      bool                 // 是否使用CRC16校验，否则为累加和
+	fallback bool                 // 接收方发起时发送方不响应C是否改用累加和校验
+	state    *session.XModemState // 传输进度
+	notify   func()               // 进度变化时调用
+}
+
+// send 发送文件
+func (e *xmodemEngine) send(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("读取文件信息失败: %v", err)
+	}
+	e.state.File = filepath.Base(path)
+	e.state.Size = info.Size()
+
+	if err := e.waitStart(xmodemHandshakeTimeout); err != nil {
+		return err
+	}
+
+	blockSize := 128
+	if e.state.Protocol != ProtocolXModem {
+		blockSize = 1024
+	}
+
+	if e.state.Protocol == ProtocolYModem {
+		if err := e.sendBlock(0, ymodemHeader(e.state.File, info.Size(), info.ModTime())); err != nil {
+			return err
+		}
+		// 接收方确认文件头后重新发起数据传输
+		if err := e.waitStart(e.timeout * time.Duration(e.retries)); err != nil {
+			return err
+		}
+	}
+
+	if err := e.sendData(file, blockSize); err != nil {
+		return err
+	}
+
+	if e.state.Protocol == ProtocolYModem {
+		// 空文件头结束批量传输
+		if err := e.waitStart(e.timeout * time.Duration(e.retries)); err != nil {
+			return err
+		}
+		if err := e.sendBlock(0, make([]byte, 128)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendData 分块发送文件内容并以EOT结束
+func (e *xmodemEngine) sendData(r io.Reader, blockSize int) error {
+	buffer := make([]byte, blockSize)
+	num := byte(1)
+	for {
+		n, err := io.ReadFull(r, buffer)
+		if n == 0 {
+			if err != io.EOF {
+				return fmt.Errorf("读取文件失败: %v", err)
+			}
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("读取文件失败: %v", err)
+		}
+
+		// 剩余数据不超过128字节时使用短包减少填充
+		size := blockSize
+		if n <= 128 {
+			size = 128
+		}
+		block := bytes.Repeat([]byte{xmodemSUB}, size)
+		copy(block, buffer[:n])
+
+		if err := e.sendBlock(num, block); err != nil {
+			return err
+		}
+		num++
+		e.state.Bytes += int64(n)
+		e.state.Blocks++
+		e.notify()
+	}
+
+	return e.sendEOT()
+}
+
+// waitStart 等待接收方发起传输，C为CRC模式，NAK为累加和模式
+func (e *xmodemEngine) waitStart(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return fmt.Errorf("等待接收方发起传输超时")
+		}
+
+		b, err := e.port.readByte(wait)
+		if err == errXModemTimeout {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		switch b {
+		case xmodemCRC:
+			e.crc = true
+			return nil
+		case xmodemNAK:
+			e.crc = false
+			return nil
+		case xmodemCAN:
+			if e.remoteCanceled() {
+				return errXModemRemote
+			}
+		}
+	}
+}
+
+// sendBlock 发送数据包并等待确认，超时或NAK时重发
+func (e *xmodemEngine) sendBlock(num byte, data []byte) error {
+	packet := e.buildPacket(num, data)
+	for attempt := 0; attempt <= e.retries; attempt++ {
+		if attempt > 0 {
+			e.state.RetryCount++
+		}
+		if err := e.port.write(packet); err != nil {
+			return err
+		}
+
+		b, err := e.readReply()
+		if err == errXModemTimeout {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if b == xmodemACK {
+			return nil
+		}
+	}
+
+	e.abort()
+	return fmt.Errorf("数据包 %d 重试次数超过 %d 次", num, e.retries)
+}
+
+// sendEOT 发送传输结束，部分接收方会先NAK第一个EOT
+func (e *xmodemEngine) sendEOT() error {
+	for attempt := 0; attempt <= e.retries; attempt++ {
+		if err := e.port.write([]byte{xmodemEOT}); err != nil {
+			return err
+		}
+
+		b, err := e.readReply()
+		if err == errXModemTimeout {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if b == xmodemACK {
+			return nil
+		}
+	}
+	return fmt.Errorf("等待传输结束确认超时")
+}
+
+// readReply 读取接收方的ACK/NAK，忽略其他字节
+func (e *xmodemEngine) readReply() (byte, error) {
+	for {
+		b, err := e.port.readByte(e.timeout)
+		if err != nil {
+			return 0, err
+		}
+
+		switch b {
+		case xmodemACK, xmodemNAK:
+			return b, nil
+		case xmodemCAN:
+			if e.remoteCanceled() {
+				return 0, errXModemRemote
+			}
+		}
+	}
+}
+
+// buildPacket 组装数据包：包头、序号、序号反码、数据、校验
+func (e *xmodemEngine) buildPacket(num byte, data []byte) []byte {
+	header := byte(xmodemSOH)
+	if len(data) == 1024 {
+		header = xmodemSTX
+	}
+
+	packet := make([]byte, 0, len(data)+5)
+	packet = append(packet, header, num, ^num)
+	packet = append(packet, data...)
+	if e.crc {
+		return binary.BigEndian.AppendUint16(packet, crc16XModem(data))
+	}
+	var sum byte
+	for _, b := range data {
+		sum += b
+	}
+	return append(packet, sum)
+}
+
+// receive 接收文件，xmodem保存到文件，ymodem按文件头的文件名保存到目录
+func (e *xmodemEngine) receive(path string) error {
+	e.crc = true
+
+	if e.state.Protocol != ProtocolYModem {
+		// XMODEM发送方可能只支持累加和校验，YMODEM始终使用CRC
+		e.fallback = true
+		e.state.File = filepath.Base(path)
+		return e.receiveFile(path, -1, false)
+	}
+
+	for {
+		name, size, err := e.receiveHeader()
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			// 空文件头表示批量传输结束
+			return nil
+		}
+
+		// 批量传输时大小和进度按全部文件累计
+		e.state.File = name
+		e.state.Size += max(size, 0)
+		e.notify()
+		if err := e.receiveFile(filepath.Join(path, name), size, true); err != nil {
+			return err
+		}
+	}
+}
+
+// receiveHeader 接收YMODEM文件头，返回文件名和大小(未知时为-1)
+func (e *xmodemEngine) receiveHeader() (string, int64, error) {
+	var data []byte
+	for {
+		header, num, packet, err := e.nextPacket(xmodemCRC, int(xmodemHandshakeTimeout/xmodemHandshakeInterval))
+		if err != nil {
+			return "", 0, err
+		}
+		if err := e.port.write([]byte{xmodemACK}); err != nil {
+			return "", 0, err
+		}
+		// 上一个文件的EOT确认丢失时发送方会重发EOT
+		if header == xmodemEOT {
+			continue
+		}
+		if num != 0 {
+			e.abort()
+			return "", 0, fmt.Errorf("期望文件头，收到数据包 %d", num)
+		}
+		data = packet
+		break
+	}
+
+	fields := bytes.SplitN(data, []byte{0}, 2)
+	name := filepath.Base(string(fields[0]))
+	if len(fields[0]) == 0 {
+		return "", 0, nil
+	}
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		e.abort()
+		return "", 0, fmt.Errorf("文件名无效: %q", fields[0])
+	}
+
+	size := int64(-1)
+	if len(fields) > 1 {
+		info := strings.Fields(string(bytes.TrimRight(fields[1], "\x00")))
+		if len(info) > 0 {
+			if n, err := strconv.ParseInt(info[0], 10, 64); err == nil {
+				size = n
+			}
+		}
+	}
+	return name, size, nil
+}
+
+// receiveFile 接收数据包直到EOT，size大于等于0时截断到文件大小，否则去除末尾的填充字节
+func (e *xmodemEngine) receiveFile(path string, size int64, nakFirstEOT bool) error {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !e.state.Overwrite {
+		flag |= os.O_EXCL
+	}
+	file, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		e.abort()
+		if os.IsExist(err) {
+			return fmt.Errorf("文件已存在: %s", path)
+		}
+		return fmt.Errorf("创建文件失败: %v", err)
+	}
+	defer file.Close()
+
+	expected := byte(1)
+	received := e.state.Bytes
+	var last []byte // 上一个数据包，xmodem在收到EOT后去除填充再写入
+	var written int64
+	first, eotReceived := true, false
+
+	for {
+		poll, attempts := byte(0), e.retries
+		if first {
+			poll, attempts = xmodemCRC, int(xmodemHandshakeTimeout/xmodemHandshakeInterval)
+		}
+
+		header, num, data, err := e.nextPacket(poll, attempts)
+		if err != nil {
+			return err
+		}
+		first = false
+
+		if header == xmodemEOT {
+			if nakFirstEOT && !eotReceived {
+				eotReceived = true
+				if err := e.port.write([]byte{xmodemNAK}); err != nil {
+					return err
+				}
+				continue
+			}
+			if last != nil {
+				data := bytes.TrimRight(last, "\x1a")
+				if _, err := file.Write(data); err != nil {
+					e.abort()
+					return fmt.Errorf("写入文件失败: %v", err)
+				}
+				e.state.Bytes -= int64(len(last) - len(data))
+			}
+			if err := e.port.write([]byte{xmodemACK}); err != nil {
+				return err
+			}
+			break
+		}
+
+		switch num {
+		case expected:
+			if size >= 0 {
+				if remain := size - written; int64(len(data)) > remain {
+					data = data[:max(remain, 0)]
+				}
+				e.state.Bytes += int64(len(data))
+			} else {
+				e.state.Bytes += int64(len(data))
+				data, last = last, data
+			}
+			if _, err := file.Write(data); err != nil {
+				e.abort()
+				return fmt.Errorf("写入文件失败: %v", err)
+			}
+			written += int64(len(data))
+			expected++
+			e.state.Blocks++
+			e.notify()
+		case expected - 1:
+			// 确认丢失导致的重发
+		default:
+			e.abort()
+			return fmt.Errorf("数据包序号错误，期望 %d，收到 %d", expected, num)
+		}
+
+		if err := e.port.write([]byte{xmodemACK}); err != nil {
+			return err
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("保存文件失败: %v", err)
+	}
+	if size < 0 {
+		e.state.Size += e.state.Bytes - received
+	}
+	e.state.Files = append(e.state.Files, path)
+	return nil
+}
+
+// nextPacket 等待下一个数据包或EOT；poll不为0时为发起阶段，每次超时重发poll，否则超时后发送NAK
+func (e *xmodemEngine) nextPacket(poll byte, attempts int) (byte, byte, []byte, error) {
+	timeout := e.timeout
+	if poll != 0 {
+		timeout = xmodemHandshakeInterval
+	}
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			e.state.RetryCount++
+		}
+		if poll == xmodemCRC && e.fallback && attempt == xmodemCRCAttempts {
+			poll = xmodemNAK
+			e.crc = false
+		}
+		if poll != 0 {
+			if err := e.port.write([]byte{poll}); err != nil {
+				return 0, 0, nil, err
+			}
+		}
+
+		header, num, data, err := e.readPacket(timeout)
+		if err == nil {
+			return header, num, data, nil
+		}
+		if err != errXModemTimeout && !errors.Is(err, errXModemBadPacket) {
+			return 0, 0, nil, err
+		}
+
+		// 数据包错误时等待线路空闲后请求重发
+		e.purge()
+		if poll == 0 {
+			if err := e.port.write([]byte{xmodemNAK}); err != nil {
+				return 0, 0, nil, err
+			}
+		}
+	}
+
+	e.abort()
+	return 0, 0, nil, fmt.Errorf("等待数据包超时，已重试 %d 次", attempts)
+}
+
+// readPacket 读取一个数据包，返回包头、序号和数据；EOT时只返回包头
+func (e *xmodemEngine) readPacket(timeout time.Duration) (byte, byte, []byte, error) {
+	for {
+		header, err := e.port.readByte(timeout)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+
+		size := 0
+		switch header {
+		case xmodemSOH:
+			size = 128
+		case xmodemSTX:
+			size = 1024
+		case xmodemEOT:
+			return header, 0, nil, nil
+		case xmodemCAN:
+			if e.remoteCanceled() {
+				return 0, 0, nil, errXModemRemote
+			}
+			continue
+		default:
+			// 忽略数据包之间的杂散字节
+			continue
+		}
+
+		checkSize := 1
+		if e.crc {
+			checkSize = 2
+		}
+		body := make([]byte, 2+size+checkSize)
+		for i := range body {
+			if body[i], err = e.port.readByte(e.timeout); err != nil {
+				if err == errXModemTimeout {
+					return 0, 0, nil, fmt.Errorf("%w: 数据包不完整", errXModemBadPacket)
+				}
+				return 0, 0, nil, err
+			}
+		}
+
+		num, data := body[0], body[2:2+size]
+		if body[1] != ^num {
+			return 0, 0, nil, fmt.Errorf("%w: 序号校验失败", errXModemBadPacket)
+		}
+		if e.crc {
+			if binary.BigEndian.Uint16(body[2+size:]) != crc16XModem(data) {
+				return 0, 0, nil, fmt.Errorf("%w: CRC错误", errXModemBadPacket)
+			}
+		} else {
+			var sum byte
+			for _, b := range data {
+				sum += b
+			}
+			if body[2+size] != sum {
+				return 0, 0, nil, fmt.Errorf("%w: 校验和错误", errXModemBadPacket)
+			}
+		}
+		return header, num, data, nil
+	}
+}
+
+// purge 丢弃输入直到线路空闲，等待时间不超过应答超时的一半以免丢弃对方的重发
+func (e *xmodemEngine) purge() {
+	idle := min(xmodemPurgeTimeout, e.timeout/2)
+	for {
+		if _, err := e.port.readByte(idle); err != nil {
+			return
+		}
+	}
+}
+
+// remoteCanceled 收到CAN后紧接着再收到CAN表示对方取消传输
+func (e *xmodemEngine) remoteCanceled() bool {
+	b, err := e.port.readByte(time.Second)
+	return err == nil && b == xmodemCAN
+}
+
+// abort 通知对方取消传输
+func (e *xmodemEngine) abort() {
+	e.port.write(bytes.Repeat([]byte{xmodemCAN}, 5))
+}
+
+// ymodemHeader YMODEM文件头：文件名、十进制大小和八进制修改时间
+func ymodemHeader(name string, size int64, modTime time.Time) []byte {
+	info := fmt.Sprintf("%s\x00%d %o", name, size, modTime.Unix())
+	blockSize := 128
+	if len(info) >= 128 {
+		blockSize = 1024
+	}
+	block := make([]byte, blockSize)
+	copy(block, info)
+	return block
+}
@@ -0,0 +1,291 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zhoudm1743/Netser/dto/session"
+)
+
+const (
+	// 等待应答或数据包默认超时（毫秒）
+	defaultXModemTimeout = 10000
+	// 每个数据包默认最大重试次数
+	defaultXModemRetries = 10
+	// 缓存的未处理接收数据块数
+	xmodemReceiveBuffer = 64
+)
+
+// XModemManager XMODEM/YMODEM文件传输管理器
+type XModemManager struct{}
+
+var GlobalXModemManager = &XModemManager{}
+
+// xmodemTransfer 会话上正在进行的传输，会话接收协程收到的数据转交给它而不记录
+type xmodemTransfer struct {
+	sessionTask
+	sess    *Session
+	rx      chan []byte
+	pending []byte
+	done    chan struct{} // 传输结束后关闭
+}
+
+// xmodemTasks 会话上正在进行的传输
+var xmodemTasks = &taskSlot[*xmodemTransfer, session.XModemState]{
+	task:  func(sess *Session) **xmodemTransfer { return &sess.xmodem },
+	state: func(sess *Session) **session.XModemState { return &sess.Info.XModem },
+	halt: func(state *session.XModemState) {
+		state.Running = false
+		state.Canceled = true
+	},
+}
+
+// readByte 读取一个字节
+func (t *xmodemTransfer) readByte(timeout time.Duration) (byte, error) {
+	if len(t.pending) == 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case <-t.stop:
+			return 0, errXModemCanceled
+		case data := <-t.rx:
+			t.pending = data
+		case <-timer.C:
+			return 0, errXModemTimeout
+		}
+	}
+
+	b := t.pending[0]
+	t.pending = t.pending[1:]
+	return b, nil
+}
+
+// write 写入会话连接
+func (t *xmodemTransfer) write(data []byte) error {
+	return t.sess.writeRaw(data, "")
+}
+
+// feed 转交收到的数据，传输结束后丢弃
+func (t *xmodemTransfer) feed(data []byte) {
+	select {
+	case t.rx <- bytes.Clone(data):
+	case <-t.done:
+	}
+}
+
+// Start 开始在会话上发送或接收文件，已在传输时替换为新的传输
+func (xm *XModemManager) Start(sessionID string, cfg session.XModemConfig) (*session.XModemState, error) {
+	sess, err := GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch sess.Info.Type {
+	case "serial", "tcpClient", "tlsClient":
+	default:
+		return nil, fmt.Errorf("XMODEM/YMODEM仅支持串口和TCP客户端会话")
+	}
+	if sess.Connection == nil {
+		return nil, fmt.Errorf("连接未建立")
+	}
+
+	switch cfg.Protocol {
+	case ProtocolXModem, ProtocolXModem1K, ProtocolYModem:
+	default:
+		return nil, fmt.Errorf("不支持的传输协议: %s", cfg.Protocol)
+	}
+	if cfg.Timeout < 0 {
+		return nil, fmt.Errorf("超时不能为负数")
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultXModemTimeout
+	}
+	if cfg.Retries < 0 {
+		return nil, fmt.Errorf("重试次数不能为负数")
+	}
+	if cfg.Retries == 0 {
+		cfg.Retries = defaultXModemRetries
+	}
+
+	state := &session.XModemState{
+		Running:   true,
+		StartTime: time.Now().UnixMilli(),
+	}
+
+	switch cfg.Direction {
+	case "send":
+		info, err := os.Stat(cfg.Path)
+		if err != nil {
+			return nil, fmt.Errorf("读取文件信息失败: %v", err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("%s 是目录", cfg.Path)
+		}
+		state.File = filepath.Base(cfg.Path)
+		state.Size = info.Size()
+	case "receive":
+		if cfg.Path, err = xmodemReceivePath(cfg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("传输方向错误: %s", cfg.Direction)
+	}
+	state.XModemConfig = cfg
+
+	if fileSendTasks.running(sess) {
+		return nil, fmt.Errorf("会话正在发送文件")
+	}
+
+	transfer := &xmodemTransfer{
+		sessionTask: newSessionTask(),
+		sess:        sess,
+		rx:          make(chan []byte, xmodemReceiveBuffer),
+		done:        make(chan struct{}),
+	}
+	xmodemTasks.start(sess, transfer, state)
+
+	log.Printf("会话 %s 开始%s %s: %s", sessionID, xmodemDirectionLabel(cfg.Direction), strings.ToUpper(cfg.Protocol), cfg.Path)
+	go xm.run(transfer, *state)
+
+	return state, nil
+}
+
+// Stop 取消会话正在进行的传输
+func (xm *XModemManager) Stop(sessionID string) (*session.XModemState, error) {
+	return xmodemTasks.stop(sessionID)
+}
+
+// GetState 获取会话的传输状态，未传输过时返回空状态
+func (xm *XModemManager) GetState(sessionID string) (*session.XModemState, error) {
+	return xmodemTasks.get(sessionID)
+}
+
+// run 执行传输，结束时在历史中写入一条汇总记录
+func (xm *XModemManager) run(transfer *xmodemTransfer, state session.XModemState) {
+	sess := transfer.sess
+	sessionID := sess.Info.SessionID
+	start := time.Now()
+
+	engine := &xmodemEngine{
+		port:    transfer,
+		timeout: time.Duration(state.Timeout) * time.Millisecond,
+		retries: state.Retries,
+		state:   &state,
+	}
+
+	// 按间隔推送进度
+	lastNotify := start
+	engine.notify = func() {
+		if time.Since(lastNotify) < fileSendNotifyInterval {
+			return
+		}
+		lastNotify = time.Now()
+		state.Throughput = transferThroughput(state.Bytes, time.Since(start))
+		xmodemTasks.publish(sess, transfer, state)
+		if GlobalWebSocketManager != nil {
+			GlobalWebSocketManager.NotifyXModem(sessionID, state)
+		}
+	}
+
+	var err error
+	if state.Direction == "send" {
+		err = engine.send(state.Path)
+	} else {
+		err = engine.receive(state.Path)
+	}
+
+	switch err {
+	case nil:
+	case errXModemCanceled:
+		state.Canceled = true
+		engine.abort()
+	default:
+		state.LastError = err.Error()
+	}
+
+	state.Running = false
+	state.EndTime = time.Now().UnixMilli()
+	state.Throughput = transferThroughput(state.Bytes, time.Since(start))
+	xmodemTasks.finish(sess, transfer, state)
+	close(transfer.done)
+
+	file := state.File
+	if len(state.Files) > 1 {
+		names := make([]string, len(state.Files))
+		for i, path := range state.Files {
+			names[i] = filepath.Base(path)
+		}
+		file = strings.Join(names, ", ")
+	}
+
+	log.Printf("会话 %s %s结束，已传输 %d 字节，%d 块，重传 %d 次", sessionID, xmodemDirectionLabel(state.Direction), state.Bytes, state.Blocks, state.RetryCount)
+	record := sess.addTransferRecord(state.Direction, "", session.TransferSummary{
+		Protocol:   state.Protocol,
+		File:       file,
+		Size:       state.Size,
+		Sent:       state.Bytes,
+		Chunks:     state.Blocks,
+		Duration:   time.Since(start).Milliseconds(),
+		Throughput: state.Throughput,
+		Status:     transferStatus(state.Canceled, state.LastError),
+		Error:      state.LastError,
+	})
+	if GlobalWebSocketManager != nil {
+		GlobalWebSocketManager.NotifyMessageRecord(sessionID, record)
+		GlobalWebSocketManager.NotifyXModem(sessionID, state)
+	}
+}
+
+// divertReceive 传输进行中时把收到的数据转交给传输协议，返回是否已转交
+func (s *Session) divertReceive(data []byte) bool {
+	s.mutex.RLock()
+	transfer := s.xmodem
+	s.mutex.RUnlock()
+
+	if transfer == nil {
+		return false
+	}
+	transfer.feed(data)
+	return true
+}
+
+// xmodemReceivePath 接收保存位置，xmodem为文件，ymodem为目录
+func xmodemReceivePath(cfg session.XModemConfig) (string, error) {
+	path := cfg.Path
+	if path == "" {
+		dir, err := AppDataDir("received")
+		if err != nil {
+			return "", err
+		}
+		path = dir
+	}
+
+	if cfg.Protocol == ProtocolYModem {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return "", fmt.Errorf("创建保存目录失败: %v", err)
+		}
+		return path, nil
+	}
+
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, fmt.Sprintf("xmodem_%s.bin", time.Now().Format("20060102_150405")))
+	}
+	if _, err := os.Stat(path); err == nil && !cfg.Overwrite {
+		return "", fmt.Errorf("文件已存在: %s", path)
+	}
+	return path, nil
+}
+
+// xmodemDirectionLabel 传输方向描述
+func xmodemDirectionLabel(direction string) string {
+	if direction == "receive" {
+		return "接收文件"
+	}
+	return "发送文件"
+}
@@ -0,0 +1,46 @@
+package cert
+
+// CertCreateCARequest 创建本地CA请求
+type CertCreateCARequest struct {
+	Name         string `json:"name"`         // 证书名称(唯一，仅允许字母、数字、-、_、.)
+	CommonName   string `json:"commonName"`   // CA通用名称
+	Organization string `json:"organization"` // 组织名称
+	ValidDays    int    `json:"validDays"`    // 有效天数
+}
+
+// CertIssueRequest 签发证书请求
+type CertIssueRequest struct {
+	Name        string   `json:"name"`        // 证书名称(唯一)
+	CAName      string   `json:"caName"`      // 签发CA名称
+	Kind        string   `json:"kind"`        // 证书类型: "server" 或 "client"
+	CommonName  string   `json:"commonName"`  // 通用名称
+	DNSNames    []string `json:"dnsNames"`    // DNS SAN，服务端证书未指定SAN时使用通用名称
+	IPAddresses []string `json:"ipAddresses"` // IP SAN
+	ValidDays   int      `json:"validDays"`   // 有效天数
+}
+
+// CertDeleteRequest 删除证书请求
+type CertDeleteRequest struct {
+	Name string `json:"name"` // 证书名称
+}
+
+// CertInfo 证书信息
+type CertInfo struct {
+	Name        string   `json:"name"`        // 证书名称
+	Kind        string   `json:"kind"`        // 证书类型: "ca", "server", "client"
+	CAName      string   `json:"caName"`      // 签发CA名称(CA证书为空)
+	CommonName  string   `json:"commonName"`  // 通用名称
+	DNSNames    []string `json:"dnsNames"`    // DNS SAN
+	IPAddresses []string `json:"ipAddresses"` // IP SAN
+	NotBefore   int64    `json:"notBefore"`   // 生效时间（毫秒）
+	NotAfter    int64    `json:"notAfter"`    // 过期时间（毫秒）
+	SHA256      string   `json:"sha256"`      // SHA-256指纹
+	CertFile    string   `json:"certFile"`    // 证书文件路径
+	KeyFile     string   `json:"keyFile"`     // 私钥文件路径
+	CreatedAt   int64    `json:"createdAt"`   // 创建时间（毫秒）
+}
+
+// CertListResponse 证书列表响应
+type CertListResponse struct {
+	Certs []CertInfo `json:"certs"` // 证书列表
+}
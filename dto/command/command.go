@@ -0,0 +1,72 @@
+package command
+
+import "github.com/zhoudm1743/Netser/dto/session"
+
+// CommandEntry 快捷指令
+type CommandEntry struct {
+	ID          string                  `json:"id"`                 // 指令ID，为空时自动生成
+	Name        string                  `json:"name"`               // 指令名称
+	Folder      string                  `json:"folder"`             // 所属分组，多级分组用/分隔，为空时位于根目录
+	SessionID   string                  `json:"sessionId"`          // 所属会话ID，为空时所有会话共享
+	Payload     string                  `json:"payload"`            // 指令内容
+	IsHex       bool                    `json:"isHex"`              // 指令内容是否为十六进制
+	LineEnding  string                  `json:"lineEnding"`         // 行尾: ""(无), "cr", "lf", "crlf"
+	Checksum    *session.ChecksumConfig `json:"checksum,omitempty"` // 追加的校验，为空时使用会话的发送校验配置
+	Description string                  `json:"description"`        // 说明
+	CreatedAt   int64                   `json:"createdAt"`          // 创建时间（毫秒）
+	UpdatedAt   int64                   `json:"updatedAt"`          // 修改时间（毫秒）
+}
+
+// CommandListRequest 获取指令列表请求
+type CommandListRequest struct {
+	SessionID string `json:"sessionId"` // 会话ID，不为空时只返回共享指令和该会话的指令
+	Folder    string `json:"folder"`    // 分组，不为空时只返回该分组及其子分组的指令
+}
+
+// CommandListResponse 指令列表响应
+type CommandListResponse struct {
+	Commands []CommandEntry `json:"commands"` // 指令列表
+	Folders  []string       `json:"folders"`  // 所有分组
+}
+
+// CommandDeleteRequest 删除指令请求
+type CommandDeleteRequest struct {
+	ID string `json:"id"` // 指令ID
+}
+
+// CommandSendRequest 发送指令请求
+type CommandSendRequest struct {
+	SessionID string `json:"sessionId"` // 会话ID
+	CommandID string `json:"commandId"` // 指令ID
+	Target    string `json:"target"`    // UDP目标地址或TCP服务端客户端地址(可选)
+}
+
+// CommandExportRequest 导出指令请求
+type CommandExportRequest struct {
+	Path   string   `json:"path"`   // 导出文件路径，为空时保存到应用数据目录的exports下
+	Folder string   `json:"folder"` // 只导出该分组及其子分组(可选)
+	IDs    []string `json:"ids"`    // 只导出指定的指令(可选)
+}
+
+// CommandExportResponse 导出指令响应
+type CommandExportResponse struct {
+	Path  string `json:"path"`  // 导出文件路径
+	Count int    `json:"count"` // 导出的指令数
+}
+
+// CommandImportRequest 导入指令请求
+type CommandImportRequest struct {
+	Path    string `json:"path"`    // 导入文件路径
+	Replace bool   `json:"replace"` // 是否替换现有指令库，否则按ID合并
+}
+
+// CommandImportResponse 导入指令响应
+type CommandImportResponse struct {
+	Count int `json:"count"` // 导入的指令数
+}
+
+// CommandFile 指令库文件内容，同时用于导入导出
+type CommandFile struct {
+	Version  int            `json:"version"`  // 文件版本
+	Commands []CommandEntry `json:"commands"` // 指令列表
+}
@@ -0,0 +1,125 @@
+package modbus
+
+// ModbusRequest Modbus主站请求
+type ModbusRequest struct {
+	Mode     string `json:"mode"`     // 传输模式: "rtu", "ascii", "tcp"，为空时串口使用rtu、TCP使用tcp
+	SlaveID  int    `json:"slaveId"`  // 从站地址，RTU/ASCII模式下0为广播(仅写入)
+	Function int    `json:"function"` // 功能码: 1读线圈, 2读离散输入, 3读保持寄存器, 4读输入寄存器, 5写单个线圈, 6写单个寄存器, 15写多个线圈, 16写多个寄存器
+	Address  int    `json:"address"`  // 起始地址(0-65535)
+	Quantity int    `json:"quantity"` // 读取数量，写入时按values长度
+	Values   []int  `json:"values"`   // 写入值，线圈为0或1，寄存器为0-65535
+	Timeout  int    `json:"timeout"`  // 等待响应超时（毫秒），0使用默认值1000
+}
+
+// ModbusMasterRequest 执行Modbus主站请求
+type ModbusMasterRequest struct {
+	SessionID string `json:"sessionId"` // 会话ID
+	ModbusRequest
+}
+
+// ModbusValue 地址和值
+type ModbusValue struct {
+	Address int `json:"address"` // 地址
+	Value   int `json:"value"`   // 值，线圈为0或1
+}
+
+// ModbusResult Modbus请求结果
+type ModbusResult struct {
+	SlaveID       int           `json:"slaveId"`                 // 从站地址
+	Function      int           `json:"function"`                // 功能码
+	Address       int           `json:"address"`                 // 起始地址
+	Quantity      int           `json:"quantity"`                // 数量
+	Values        []ModbusValue `json:"values"`                  // 读取的值或从站确认写入的值
+	Exception     int           `json:"exception,omitempty"`     // 从站返回的异常码
+	ExceptionText string        `json:"exceptionText,omitempty"` // 异常码说明
+	Error         string        `json:"error,omitempty"`         // 超时、校验失败等错误
+	Request       string        `json:"request"`                 // 请求帧，ASCII模式为帧文本，其他模式为十六进制
+	Response      string        `json:"response"`                // 响应帧，超时时为已收到的数据
+	Elapsed       int64         `json:"elapsed"`                 // 响应耗时（毫秒）
+	Timestamp     int64         `json:"timestamp"`               // 时间戳（毫秒）
+}
+
+// ModbusPollConfig Modbus轮询配置
+type ModbusPollConfig struct {
+	ID       string `json:"id"`       // 轮询ID，为空时自动生成，已存在时替换
+	Name     string `json:"name"`     // 名称
+	Interval int    `json:"interval"` // 轮询间隔（毫秒）
+	Count    int    `json:"count"`    // 轮询次数，0表示不限
+	ModbusRequest
+}
+
+// ModbusPollState Modbus轮询状态
+type ModbusPollState struct {
+	ModbusPollConfig
+	Running    bool          `json:"running"`              // 是否正在运行
+	Polls      int           `json:"polls"`                // 已轮询次数
+	Failed     int           `json:"failed"`               // 失败次数(超时、异常响应等)
+	LastResult *ModbusResult `json:"lastResult,omitempty"` // 最近一次请求结果
+	StartTime  int64         `json:"startTime"`            // 开始时间（毫秒）
+}
+
+// ModbusPollRequest 启动Modbus轮询请求
+type ModbusPollRequest struct {
+	SessionID string `json:"sessionId"` // 会话ID
+	ModbusPollConfig
+}
+
+// ModbusPollStopRequest 停止Modbus轮询请求
+type ModbusPollStopRequest struct {
+	SessionID string `json:"sessionId"` // 会话ID
+	ID        string `json:"id"`        // 轮询ID，为空时停止会话的全部轮询
+}
+
+// ModbusTable 从站一类数据的地址范围和值
+type ModbusTable struct {
+	Start  int   `json:"start"`  // 起始地址
+	Values []int `json:"values"` // 从起始地址开始的值，长度即数量；线圈为0或1，寄存器为0-65535
+}
+
+// ModbusRegisterMap 从站数据表
+type ModbusRegisterMap struct {
+	Coils            ModbusTable `json:"coils"`            // 线圈(可读写)
+	DiscreteInputs   ModbusTable `json:"discreteInputs"`   // 离散输入(主站只读)
+	HoldingRegisters ModbusTable `json:"holdingRegisters"` // 保持寄存器(可读写)
+	InputRegisters   ModbusTable `json:"inputRegisters"`   // 输入寄存器(主站只读)
+}
+
+// ModbusExceptionRule 异常注入规则，请求的功能码和地址范围匹配时返回异常
+type ModbusExceptionRule struct {
+	Function  int `json:"function"`  // 功能码，0匹配全部
+	Address   int `json:"address"`   // 起始地址
+	Count     int `json:"count"`     // 地址数量，0匹配全部地址
+	Exception int `json:"exception"` // 返回的异常码，0表示不响应(模拟超时)
+}
+
+// ModbusSlaveConfig Modbus从站模拟配置
+type ModbusSlaveConfig struct {
+	Enabled    bool                  `json:"enabled"`    // 是否启用
+	Mode       string                `json:"mode"`       // 传输模式: "rtu", "ascii", "tcp"，为空时串口使用rtu、TCP服务端使用tcp
+	SlaveID    int                   `json:"slaveId"`    // 从站地址，0表示响应所有地址
+	Delay      int                   `json:"delay"`      // 应答延迟（毫秒）
+	Registers  ModbusRegisterMap     `json:"registers"`  // 数据表初始值，启用或修改配置时载入
+	Exceptions []ModbusExceptionRule `json:"exceptions"` // 异常注入规则，按顺序匹配
+}
+
+// ModbusSlaveState Modbus从站模拟状态
+type ModbusSlaveState struct {
+	Config     *ModbusSlaveConfig `json:"config"`     // 配置，为空时未配置
+	Registers  ModbusRegisterMap  `json:"registers"`  // 当前数据
+	Requests   int                `json:"requests"`   // 已处理的请求数
+	Exceptions int                `json:"exceptions"` // 已返回的异常响应数
+}
+
+// ModbusSlaveRequest 设置Modbus从站模拟请求
+type ModbusSlaveRequest struct {
+	SessionID string             `json:"sessionId"` // 会话ID
+	Slave     *ModbusSlaveConfig `json:"slave"`     // 从站配置，为空时关闭
+}
+
+// ModbusSlaveWriteRequest 修改Modbus从站数据请求
+type ModbusSlaveWriteRequest struct {
+	SessionID string `json:"sessionId"` // 会话ID
+	Table     string `json:"table"`     // 数据表: "coils", "discreteInputs", "holdingRegisters", "inputRegisters"
+	Address   int    `json:"address"`   // 起始地址
+	Values    []int  `json:"values"`    // 写入的值
+}
@@ -0,0 +1,17 @@
+package serial
+
+// SerialPortInfo 串口详细信息
+type SerialPortInfo struct {
+	Name         string `json:"name"`         // 串口名称 (例如: "COM1", "/dev/ttyUSB0")
+	IsUSB        bool   `json:"isUsb"`        // 是否为USB串口
+	VID          string `json:"vid"`          // USB厂商ID
+	PID          string `json:"pid"`          // USB产品ID
+	SerialNumber string `json:"serialNumber"` // USB序列号
+	Product      string `json:"product"`      // 产品名称(依操作系统而定，可能为空)
+}
+
+// SerialPortListResponse 串口列表响应
+type SerialPortListResponse struct {
+	Ports   []string         `json:"ports"`   // 串口名称列表
+	Details []SerialPortInfo `json:"details"` // 串口详细信息
+}
@@ -1,5 +1,12 @@
 package session
 
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/zhoudm1743/Netser/dto/modbus"
+)
+
 // SessionListRequest 会话列表请求
 type SessionListRequest struct {
 	Type string `json:"type"` // 会话类型: "tcp", "udp", "serial", "all"
@@ -13,7 +20,7 @@ type SessionListResponse struct {
 // SessionInfo 会话信息
 type SessionInfo struct {
 	SessionID   string `json:"sessionId"`   // 会话ID
-	Type        string `json:"type"`        // 会话类型: "tcp", "udp", "serial"
+	Type        string `json:"type"`        // 会话类型: "tcpClient", "tcpServer", "udpClient", "udpServer", "udpMulticast", "tlsClient", "tlsServer", "serial"
 	Name        string `json:"name"`        // 会话名称
 	Status      string `json:"status"`      // 状态
 	Host        string `json:"host"`        // 主机地址(对于TCP/UDP客户端)
@@ -23,16 +30,411 @@ type SessionInfo struct {
 	ConnectTime int64  `json:"connectTime"` // 连接时间
 
 	// 串口相关字段
-	SerialPort string `json:"serialPort"` // 串口名称 (例如: "COM1", "/dev/ttyUSB0")
-	BaudRate   int    `json:"baudRate"`   // 波特率
-	DataBits   int    `json:"dataBits"`   // 数据位
-	StopBits   int    `json:"stopBits"`   // 停止位
-	Parity     string `json:"parity"`     // 奇偶校验: "none", "odd", "even"
+	SerialConfig
+
+	Modem *ModemStatus `json:"modem,omitempty"` // 最近一次读取的调制解调器输入线状态
+
+	// UDP组播/广播相关字段
+	MulticastGroup string `json:"multicastGroup"` // 组播组地址 (例如: "239.255.0.1", "ff02::1")，为空时仅用于广播
+	Interface      string `json:"interface"`      // 网络接口名称，为空时由系统选择
+	TTL            int    `json:"ttl"`            // 组播TTL/跳数限制，0表示系统默认
+	Loopback       bool   `json:"loopback"`       // 是否接收本机发出的组播数据
+
+	// 分帧配置，为空时按每次读取的数据记录
+	Framing *FramingConfig `json:"framing,omitempty"`
+
+	// 校验配置，为空时不追加/校验
+	Checksum *ChecksumConfig `json:"checksum,omitempty"`
+
+	// 自动应答配置，为空时不应答
+	Responder *ResponderConfig `json:"responder,omitempty"`
+
+	// 会话脚本，为空时不加载
+	Script *ScriptConfig `json:"script,omitempty"`
+
+	// Modbus从站模拟配置，为空时不模拟(仅串口和TCP服务端)
+	ModbusSlave *modbus.ModbusSlaveConfig `json:"modbusSlave,omitempty"`
+
+	// 自动重连相关字段
+	Reconnect         *ReconnectPolicy `json:"reconnect,omitempty"` // 重连策略(仅客户端会话)
+	ReconnectAttempts int              `json:"reconnectAttempts"`   // 当前重连尝试次数
+	LastError         string           `json:"lastError"`           // 最近一次连接错误
+
+	// TLS相关字段
+	TLS      *TLSConfig `json:"tls,omitempty"`      // TLS配置(仅tlsClient/tlsServer)
+	TLSState *TLSState  `json:"tlsState,omitempty"` // TLS客户端握手协商结果，TLS服务端的结果见各客户端
+
+	// 定时发送状态，为空时表示未启动过
+	AutoSend *AutoSendState `json:"autoSend,omitempty"`
+
+	// 流量回放状态，为空时表示未启动过
+	Replay *ReplayState `json:"replay,omitempty"`
+
+	// 发送文件状态，为空时表示未发送过
+	FileSend *FileSendState `json:"fileSend,omitempty"`
+
+	// XMODEM/YMODEM传输状态，为空时表示未传输过
+	XModem *XModemState `json:"xmodem,omitempty"`
+}
+
+// SerialConfig 串口参数
+type SerialConfig struct {
+	SerialPort string   `json:"serialPort"`    // 串口名称 (例如: "COM1", "/dev/ttyUSB0")
+	BaudRate   int      `json:"baudRate"`      // 波特率，支持任意值
+	DataBits   int      `json:"dataBits"`      // 数据位: 5, 6, 7, 8
+	StopBits   StopBits `json:"stopBits"`      // 停止位: "1", "1.5", "2"
+	Parity     string   `json:"parity"`        // 奇偶校验: "none", "odd", "even", "mark", "space"
+	DTR        *bool    `json:"dtr,omitempty"` // DTR状态，为空时使用系统默认(有效)
+	RTS        *bool    `json:"rts,omitempty"` // RTS状态，为空时使用系统默认(有效)
+
+	USBSerialNumber string `json:"usbSerialNumber"` // 绑定的USB序列号，设备以新路径重新插入时自动重连
+
+	FlowControl string `json:"flowControl"` // 流控: "none", "rtsCts"(发送前等待CTS), "rs485"(发送时拉高RTS)
+	FlowTimeout int    `json:"flowTimeout"` // 等待CTS超时（毫秒），默认1000
+}
+
+// StopBits 停止位，JSON中可以是数字(1, 1.5, 2)或字符串("1", "1.5", "2")，输出为字符串
+type StopBits string
+
+// UnmarshalJSON 兼容数字形式的停止位
+func (sb *StopBits) UnmarshalJSON(data []byte) error {
+	var value json.Number
+	if err := json.Unmarshal(data, &value); err == nil {
+		*sb = StopBits(value)
+	} else {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		*sb = StopBits(text)
+	}
+
+	// 数值统一为最简形式，如1.0为"1"
+	if f, err := strconv.ParseFloat(string(*sb), 64); err == nil {
+		*sb = StopBits(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	return nil
+}
+
+// ModemStatus 串口调制解调器输入线状态
+type ModemStatus struct {
+	CTS bool `json:"cts"` // 清除发送
+	DSR bool `json:"dsr"` // 数据设备就绪
+	RI  bool `json:"ri"`  // 振铃指示
+	DCD bool `json:"dcd"` // 载波检测
+}
+
+// SerialLinesRequest 设置DTR/RTS请求
+type SerialLinesRequest struct {
+	SessionID string `json:"sessionId"`     // 会话ID
+	DTR       *bool  `json:"dtr,omitempty"` // DTR状态，为空时不修改
+	RTS       *bool  `json:"rts,omitempty"` // RTS状态，为空时不修改
+}
+
+// SerialBreakRequest 发送Break信号请求
+type SerialBreakRequest struct {
+	SessionID string `json:"sessionId"` // 会话ID
+	Duration  int    `json:"duration"`  // 持续时间（毫秒），默认250
+}
+
+// SerialModeRequest 修改串口参数请求
+type SerialModeRequest struct {
+	SessionID string `json:"sessionId"` // 会话ID
+	SerialConfig
+}
+
+// FramingConfig 接收数据分帧配置
+type FramingConfig struct {
+	Mode             string `json:"mode"`             // 分帧模式: "none", "delimiter", "fixed", "lengthField", "stxEtx", "idle"
+	Delimiter        string `json:"delimiter"`        // 分隔符 (例如: "\r\n" 或十六进制 "0D 0A")
+	DelimiterIsHex   bool   `json:"delimiterIsHex"`   // 分隔符是否为十六进制
+	KeepDelimiter    bool   `json:"keepDelimiter"`    // 帧中是否保留分隔符
+	FixedLength      int    `json:"fixedLength"`      // 固定帧长度
+	LengthOffset     int    `json:"lengthOffset"`     // 长度字段偏移
+	LengthSize       int    `json:"lengthSize"`       // 长度字段字节数: 1, 2, 4
+	LengthAdjustment int    `json:"lengthAdjustment"` // 长度调整值，帧长度 = 偏移 + 字段大小 + 字段值 + 调整值
+	LittleEndian     bool   `json:"littleEndian"`     // 长度字段是否为小端序
+	STX              string `json:"stx"`              // 起始符十六进制，默认 "02"
+	ETX              string `json:"etx"`              // 结束符十六进制，默认 "03"
+	IdleTimeout      int    `json:"idleTimeout"`      // 空闲超时（毫秒），idle模式下为帧间隔，其他模式下用于输出残留数据
+	MaxFrameLength   int    `json:"maxFrameLength"`   // 最大帧长度，默认65536
+}
+
+// SessionFramingRequest 设置分帧配置请求
+type SessionFramingRequest struct {
+	SessionID string         `json:"sessionId"` // 会话ID
+	Framing   *FramingConfig `json:"framing"`   // 分帧配置，为空时关闭分帧
+}
+
+// ChecksumConfig 校验配置
+type ChecksumConfig struct {
+	Algorithm       string `json:"algorithm"`       // 校验算法: "none", "crc16Modbus", "crc16Ccitt", "crc32", "xor", "sum8", "lrc"
+	AppendOnSend    bool   `json:"appendOnSend"`    // 发送时自动追加校验值
+	VerifyOnReceive bool   `json:"verifyOnReceive"` // 接收时校验帧末尾的校验值
+	StartOffset     int    `json:"startOffset"`     // 校验起始偏移，跳过帧头若干字节
+	SwapBytes       bool   `json:"swapBytes"`       // 交换多字节校验值的字节序
+}
+
+// SessionChecksumRequest 设置校验配置请求
+type SessionChecksumRequest struct {
+	SessionID string          `json:"sessionId"` // 会话ID
+	Checksum  *ChecksumConfig `json:"checksum"`  // 校验配置，为空时关闭
+}
+
+// ChecksumCalcRequest 计算校验值请求
+type ChecksumCalcRequest struct {
+	Data      string `json:"data"`      // 数据
+	IsHex     bool   `json:"isHex"`     // 是否为十六进制
+	Algorithm string `json:"algorithm"` // 校验算法
+}
+
+// ReconnectPolicy 自动重连策略
+type ReconnectPolicy struct {
+	Enabled      bool    `json:"enabled"`      // 是否启用
+	InitialDelay int     `json:"initialDelay"` // 首次重连延迟（毫秒）
+	MaxDelay     int     `json:"maxDelay"`     // 最大重连延迟（毫秒）
+	Factor       float64 `json:"factor"`       // 指数退避倍数
+	MaxAttempts  int     `json:"maxAttempts"`  // 最大尝试次数，0表示不限
+}
+
+// SessionReconnectRequest 设置重连策略请求
+type SessionReconnectRequest struct {
+	SessionID string           `json:"sessionId"` // 会话ID
+	Policy    *ReconnectPolicy `json:"policy"`    // 重连策略，为空时关闭
+}
+
+// ResponderRule 自动应答规则
+type ResponderRule struct {
+	ID           string `json:"id"`           // 规则ID，为空时按序号生成
+	Name         string `json:"name"`         // 规则名称，记录中优先使用名称标记
+	Enabled      bool   `json:"enabled"`      // 是否启用
+	MatchType    string `json:"matchType"`    // 匹配方式: "exact", "prefix", "hex"(??为单字节通配), "regex"
+	Pattern      string `json:"pattern"`      // 匹配内容
+	PatternIsHex bool   `json:"patternIsHex"` // exact/prefix的匹配内容是否为十六进制
+	Reply        string `json:"reply"`        // 应答内容
+	ReplyIsHex   bool   `json:"replyIsHex"`   // 应答内容是否为十六进制
+	Template     bool   `json:"template"`     // 应答内容是否为模板，支持{{$0}}、{{$1}}、{{counter}}、{{counter:2}}、{{timestamp}}、{{time}}
+	Delay        int    `json:"delay"`        // 延迟应答（毫秒）
+}
+
+// ResponderConfig 自动应答配置，收到的每条消息按顺序匹配，第一条匹配的规则应答
+type ResponderConfig struct {
+	Enabled bool            `json:"enabled"` // 是否启用
+	Rules   []ResponderRule `json:"rules"`   // 应答规则
+}
+
+// SessionResponderRequest 设置自动应答请求
+type SessionResponderRequest struct {
+	SessionID string           `json:"sessionId"` // 会话ID
+	Responder *ResponderConfig `json:"responder"` // 自动应答配置，为空时关闭
+}
+
+// ScriptConfig 会话脚本配置(Lua)
+type ScriptConfig struct {
+	Enabled bool   `json:"enabled"` // 是否启用
+	Source  string `json:"source"`  // 脚本源码，可定义onConnect、onDisconnect、onReceive、onSend钩子
+}
+
+// ScriptState 会话脚本运行状态
+type ScriptState struct {
+	Running   bool           `json:"running"`   // 是否正在运行
+	StartTime int64          `json:"startTime"` // 加载时间（毫秒）
+	LastError string         `json:"lastError"` // 最近一次脚本错误
+	Vars      map[string]any `json:"vars"`      // 脚本设置的会话变量
+}
+
+// SessionScriptRequest 设置会话脚本请求
+type SessionScriptRequest struct {
+	SessionID string        `json:"sessionId"` // 会话ID
+	Script    *ScriptConfig `json:"script"`    // 脚本配置，为空时卸载脚本
+}
+
+// AutoSendConfig 定时发送配置
+type AutoSendConfig struct {
+	Data     string `json:"data"`     // 发送内容
+	IsHex    bool   `json:"isHex"`    // 是否为十六进制
+	Interval int    `json:"interval"` // 发送间隔（毫秒）
+	Count    int    `json:"count"`    // 发送次数，0表示不限
+	Target   string `json:"target"`   // UDP目标地址或TCP服务端客户端地址(可选)
+}
+
+// AutoSendState 定时发送状态
+type AutoSendState struct {
+	AutoSendConfig
+	Running   bool   `json:"running"`   // 是否正在运行
+	Sent      int    `json:"sent"`      // 已成功发送次数
+	Failed    int    `json:"failed"`    // 发送失败次数
+	StartTime int64  `json:"startTime"` // 启动时间（毫秒）
+	LastError string `json:"lastError"` // 最近一次发送错误
+}
+
+// AutoSendRequest 启动定时发送请求
+type AutoSendRequest struct {
+	SessionID string `json:"sessionId"` // 会话ID
+	AutoSendConfig
+}
+
+// ReplayConfig 流量回放配置
+type ReplayConfig struct {
+	SourceSessionID string  `json:"sourceSessionId"` // 回放来源会话的历史记录，为空时使用File
+	File            string  `json:"file"`            // 导入文件: 导出的.csv/.jsonl或.pcap/.pcapng抓包文件
+	Speed           float64 `json:"speed"`           // 回放速度倍数，按原始消息间隔/速度等待，默认1
+	Step            bool    `json:"step"`            // 单步模式，每次调用replay_step发送下一条
+	WaitResponse    bool    `json:"waitResponse"`    // 发送后等待录制中的应答并比对
+	ResponseTimeout int     `json:"responseTimeout"` // 等待应答超时（毫秒），默认1000
+	Target          string  `json:"target"`          // UDP目标地址或TCP服务端客户端地址(可选)
+}
+
+// ReplayMismatch 回放应答不匹配记录
+type ReplayMismatch struct {
+	Index    int    `json:"index"`    // 发送消息序号(从0开始)
+	Expected string `json:"expected"` // 录制的应答(十六进制)
+	Actual   string `json:"actual"`   // 实际收到的应答(十六进制)
+	Timeout  bool   `json:"timeout"`  // 是否等待超时
+}
+
+// ReplayState 流量回放状态
+type ReplayState struct {
+	ReplayConfig
+	Running    bool             `json:"running"`    // 是否正在运行
+	Total      int              `json:"total"`      // 待发送消息数
+	Sent       int              `json:"sent"`       // 已发送消息数
+	Failed     int              `json:"failed"`     // 发送失败次数
+	Matched    int              `json:"matched"`    // 应答匹配次数
+	Mismatched int              `json:"mismatched"` // 应答不匹配次数
+	Mismatches []ReplayMismatch `json:"mismatches"` // 不匹配明细（最多保留100条）
+	StartTime  int64            `json:"startTime"`  // 启动时间（毫秒）
+	LastError  string           `json:"lastError"`  // 最近一次错误
+}
+
+// ReplayRequest 启动流量回放请求
+type ReplayRequest struct {
+	SessionID string `json:"sessionId"` // 回放目标会话ID
+	ReplayConfig
+}
+
+// FileSendConfig 发送文件配置
+type FileSendConfig struct {
+	Path       string `json:"path"`       // 本地文件路径
+	ChunkSize  int    `json:"chunkSize"`  // 每块字节数，0使用默认值1024
+	Delay      int    `json:"delay"`      // 块间延迟（毫秒）
+	Ack        string `json:"ack"`        // 每块发送后等待的应答字节(十六进制，例如"06")，为空时不等待
+	AckTimeout int    `json:"ackTimeout"` // 等待应答超时（毫秒），0使用默认值1000
+	Target     string `json:"target"`     // TCP服务端客户端地址(可选)
+}
+
+// FileSendState 发送文件状态
+type FileSendState struct {
+	FileSendConfig
+	Running    bool    `json:"running"`    // 是否正在发送
+	Size       int64   `json:"size"`       // 文件大小
+	Sent       int64   `json:"sent"`       // 已发送字节数
+	Chunks     int     `json:"chunks"`     // 已发送块数
+	Throughput float64 `json:"throughput"` // 平均速率（字节/秒）
+	Canceled   bool    `json:"canceled"`   // 是否被取消
+	StartTime  int64   `json:"startTime"`  // 开始时间（毫秒）
+	EndTime    int64   `json:"endTime"`    // 结束时间（毫秒）
+	LastError  string  `json:"lastError"`  // 发送失败原因
+}
+
+// FileSendRequest 发送文件请求
+type FileSendRequest struct {
+	SessionID string `json:"sessionId"` // 会话ID
+	FileSendConfig
+}
+
+// XModemConfig XMODEM/YMODEM文件传输配置
+type XModemConfig struct {
+	Protocol  string `json:"protocol"`  // 协议: "xmodem"(CRC，128字节块), "xmodem1k"(CRC，1K块), "ymodem"(1K块，批量)
+	Direction string `json:"direction"` // 方向: "send" 或 "receive"
+	Path      string `json:"path"`      // 发送的文件；接收时xmodem为保存文件、ymodem为保存目录，为空时保存到应用数据目录的received下
+	Timeout   int    `json:"timeout"`   // 等待应答或数据包超时（毫秒），0使用默认值10000
+	Retries   int    `json:"retries"`   // 每个数据包最大重试次数，0使用默认值10
+	Overwrite bool   `json:"overwrite"` // 接收时是否覆盖已存在的文件
+}
+
+// XModemState XMODEM/YMODEM文件传输状态
+type XModemState struct {
+	XModemConfig
+	Running    bool     `json:"running"`    // 是否正在传输
+	File       string   `json:"file"`       // 当前传输的文件名
+	Files      []string `json:"files"`      // 已接收完成的文件路径
+	Size       int64    `json:"size"`       // 文件大小，批量传输时为总大小
+	Bytes      int64    `json:"bytes"`      // 已传输字节数，批量传输时累计
+	Blocks     int      `json:"blocks"`     // 已传输数据包数
+	RetryCount int      `json:"retryCount"` // 累计重传次数
+	Throughput float64  `json:"throughput"` // 平均速率（字节/秒）
+	Canceled   bool     `json:"canceled"`   // 是否被取消
+	StartTime  int64    `json:"startTime"`  // 开始时间（毫秒）
+	EndTime    int64    `json:"endTime"`    // 结束时间（毫秒）
+	LastError  string   `json:"lastError"`  // 传输失败原因
+}
+
+// XModemRequest 启动XMODEM/YMODEM文件传输请求
+type XModemRequest struct {
+	SessionID string `json:"sessionId"` // 会话ID
+	XModemConfig
+}
+
+// TransferSummary 文件传输汇总，记录在历史中代替逐块记录
+type TransferSummary struct {
+	Protocol   string  `json:"protocol,omitempty"` // 传输协议，直接发送文件时为空
+	File       string  `json:"file"`               // 文件名
+	Size       int64   `json:"size"`               // 文件大小
+	Sent       int64   `json:"sent"`               // 已传输字节数
+	Chunks     int     `json:"chunks"`             // 已传输块数
+	Duration   int64   `json:"duration"`           // 耗时（毫秒）
+	Throughput float64 `json:"throughput"`         // 平均速率（字节/秒）
+	Status     string  `json:"status"`             // 结果: "completed", "canceled", "failed"
+	Error      string  `json:"error,omitempty"`
+}
+
+// TLSConfig TLS会话配置
+type TLSConfig struct {
+	ServerName         string   `json:"serverName"`         // SNI，客户端为空时使用主机地址
+	CAName             string   `json:"caName"`             // 内置CA名称，优先于CAFile
+	CAFile             string   `json:"caFile"`             // 自定义CA证书文件(PEM)，客户端校验服务端/服务端校验客户端
+	CertName           string   `json:"certName"`           // 内置证书名称，优先于CertFile/KeyFile
+	CertFile           string   `json:"certFile"`           // 证书文件(PEM)，服务端必填，客户端用于双向认证
+	KeyFile            string   `json:"keyFile"`            // 私钥文件(PEM)
+	InsecureSkipVerify bool     `json:"insecureSkipVerify"` // 跳过证书校验
+	RequireClientCert  bool     `json:"requireClientCert"`  // 服务端要求客户端证书(双向认证)
+	MinVersion         string   `json:"minVersion"`         // 最低版本: "1.0", "1.1", "1.2", "1.3"
+	MaxVersion         string   `json:"maxVersion"`         // 最高版本
+	ALPN               []string `json:"alpn"`               // ALPN协议列表 (例如: "h2", "http/1.1")
+}
+
+// TLSState TLS握手协商结果
+type TLSState struct {
+	Version          string            `json:"version"`          // 协商版本
+	CipherSuite      string            `json:"cipherSuite"`      // 协商加密套件
+	NegotiatedALPN   string            `json:"negotiatedAlpn"`   // 协商的ALPN协议
+	ServerName       string            `json:"serverName"`       // SNI
+	PeerCertificates []CertificateInfo `json:"peerCertificates"` // 对端证书链
+}
+
+// CertificateInfo 证书摘要信息
+type CertificateInfo struct {
+	Subject      string   `json:"subject"`      // 主题
+	Issuer       string   `json:"issuer"`       // 颁发者
+	SerialNumber string   `json:"serialNumber"` // 序列号
+	NotBefore    int64    `json:"notBefore"`    // 生效时间（毫秒）
+	NotAfter     int64    `json:"notAfter"`     // 过期时间（毫秒）
+	DNSNames     []string `json:"dnsNames"`     // DNS SAN
+	IPAddresses  []string `json:"ipAddresses"`  // IP SAN
+	SHA256       string   `json:"sha256"`       // SHA-256指纹
 }
 
 // SessionRemoveRequest 移除会话请求
 type SessionRemoveRequest struct {
-	SessionID string `json:"sessionId"` // 会话ID
+	SessionID     string `json:"sessionId"`               // 会话ID
+	DeleteHistory *bool  `json:"deleteHistory,omitempty"` // 是否删除历史记录，为空时按保留策略
+}
+
+// RetentionPolicy 历史记录保留策略，默认全部保留
+type RetentionPolicy struct {
+	DeleteHistoryOnRemove bool `json:"deleteHistoryOnRemove"` // 移除会话时删除其历史记录
+	ClearHistoryOnExit    bool `json:"clearHistoryOnExit"`    // 退出程序时清空所有历史记录
 }
 
 // SessionRenameRequest 重命名会话请求
@@ -46,15 +448,27 @@ type SessionHistoryRequest struct {
 	SessionID string `json:"sessionId"` // 会话ID
 	Limit     int    `json:"limit"`     // 限制返回的记录数
 	Offset    int    `json:"offset"`    // 偏移量
+	Format    string `json:"format"`    // 渲染格式: "auto", "hex", "text", "both"
 }
 
 // MessageRecord 消息记录
 type MessageRecord struct {
-	Direction  string `json:"direction"`  // 方向: "send" 或 "receive"
-	Data       string `json:"data"`       // 数据
-	IsHex      bool   `json:"isHex"`      // 是否为十六进制数据
-	Timestamp  int64  `json:"timestamp"`  // 时间戳
-	ByteLength int    `json:"byteLength"` // 字节长度
+	Direction  string `json:"direction"`            // 方向: "send" 或 "receive"
+	Data       string `json:"data"`                 // 数据（按请求格式渲染）
+	IsHex      bool   `json:"isHex"`                // 是否为十六进制数据
+	Timestamp  int64  `json:"timestamp"`            // 时间戳
+	ByteLength int    `json:"byteLength"`           // 字节长度（原始字节数）
+	RemoteAddr string `json:"remoteAddr,omitempty"` // 对端地址(UDP数据报来源/目标)
+	LocalAddr  string `json:"localAddr,omitempty"`  // 本端地址(网络会话)
+	Rule       string `json:"rule,omitempty"`       // 触发自动应答的规则(仅自动应答发送的记录)
+	Raw        []byte `json:"raw,omitempty"`        // 原始字节（JSON中为base64）
+	Hex        string `json:"hex,omitempty"`        // 十六进制渲染（format为both时）
+	Text       string `json:"text,omitempty"`       // 文本渲染（format为both时）
+
+	ChecksumStatus   string `json:"checksumStatus,omitempty"`   // 接收校验结果: "ok", "mismatch"
+	ChecksumExpected string `json:"checksumExpected,omitempty"` // 校验不匹配时期望的校验值(十六进制)
+
+	Transfer *TransferSummary `json:"transfer,omitempty"` // 文件传输汇总(仅文件传输的汇总记录，数据为汇总说明，字节长度为已传输字节数)
 }
 
 // SessionHistoryResponse 会话历史记录响应
@@ -64,6 +478,27 @@ type SessionHistoryResponse struct {
 	Total     int             `json:"total"`     // 总记录数
 }
 
+// MessageFilter 消息过滤条件
+type MessageFilter struct {
+	StartTime int64  `json:"startTime"` // 起始时间（毫秒，含），0表示不限
+	EndTime   int64  `json:"endTime"`   // 结束时间（毫秒，含），0表示不限
+	Direction string `json:"direction"` // 方向: "send", "receive"，为空表示全部
+}
+
+// SessionExportRequest 导出会话历史记录请求
+type SessionExportRequest struct {
+	SessionID string `json:"sessionId"` // 会话ID
+	Format    string `json:"format"`    // 导出格式: "csv", "jsonl", "text", "hexdump", "pcapng"
+	Path      string `json:"path"`      // 导出文件路径，为空时保存到应用数据目录的exports下
+	MessageFilter
+}
+
+// SessionExportResponse 导出会话历史记录响应
+type SessionExportResponse struct {
+	Path  string `json:"path"`  // 导出文件路径
+	Count int    `json:"count"` // 导出的消息数
+}
+
 // SessionClearHistoryRequest 清除会话历史记录请求
 type SessionClearHistoryRequest struct {
 	SessionID string `json:"sessionId"` // 会话ID
@@ -21,6 +21,13 @@ type TCPSendRequest struct {
 	SessionID string `json:"sessionId"` // 会话ID
 	Data      string `json:"data"`      // 发送的数据
 	IsHex     bool   `json:"isHex"`     // 是否为十六进制数据
+	Target    string `json:"target"`    // 目标客户端地址(仅服务端，为空时广播)
+}
+
+// TCPKickPeerRequest TCP服务端断开客户端请求
+type TCPKickPeerRequest struct {
+	SessionID string `json:"sessionId"` // 会话ID
+	Address   string `json:"address"`   // 客户端地址
 }
 
 // TCPDisconnectRequest TCP断开连接请求
@@ -1,5 +1,7 @@
 package tcp
 
+import "github.com/zhoudm1743/Netser/dto/session"
+
 // TCPConnectResponse TCP连接响应
 type TCPConnectResponse struct {
 	SessionID string `json:"sessionId"` // 会话ID
@@ -47,3 +49,16 @@ type TCPSessionInfo struct {
 	IsHex       bool   `json:"isHex"`       // 是否使用十六进制模式
 	ConnectTime int64  `json:"connectTime"` // 连接时间
 }
+
+// TCPPeerInfo TCP服务端已连接的客户端信息
+type TCPPeerInfo struct {
+	Address     string            `json:"address"`       // 客户端地址
+	ConnectTime int64             `json:"connectTime"`   // 连接时间（毫秒）
+	TLS         *session.TLSState `json:"tls,omitempty"` // TLS握手结果(仅tlsServer)
+}
+
+// TCPPeerListResponse TCP服务端客户端列表响应
+type TCPPeerListResponse struct {
+	SessionID string        `json:"sessionId"` // 会话ID
+	Peers     []TCPPeerInfo `json:"peers"`     // 客户端列表
+}
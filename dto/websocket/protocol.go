@@ -3,6 +3,8 @@ package websocket
 import (
 	"encoding/json"
 	"time"
+
+	"github.com/zhoudm1743/Netser/dto/session"
 )
 
 // MessageType 消息类型枚举
@@ -20,6 +22,17 @@ const (
 	// 业务消息类型
 	MsgTypeTCPMessage    MessageType = "tcp_message"    // TCP消息推送
 	MsgTypeSessionStatus MessageType = "session_status" // 会话状态变化
+	MsgTypePeerStatus    MessageType = "peer_status"    // TCP服务端客户端连接/断开
+	MsgTypeAutoSend      MessageType = "auto_send"      // 定时发送进度
+	MsgTypeReplay        MessageType = "replay"         // 流量回放进度
+	MsgTypeScriptLog     MessageType = "script_log"     // 会话脚本日志
+	MsgTypeFileSend      MessageType = "file_send"      // 发送文件进度
+	MsgTypeXModem        MessageType = "xmodem"         // XMODEM/YMODEM传输进度
+	MsgTypeModbusPoll    MessageType = "modbus_poll"    // Modbus轮询结果
+	MsgTypeModbusSlave   MessageType = "modbus_slave"   // Modbus从站数据变化
+	MsgTypeModemStatus   MessageType = "modem_status"   // 串口调制解调器线状态变化
+	MsgTypePortAdded     MessageType = "port_added"     // 串口插入
+	MsgTypePortRemoved   MessageType = "port_removed"   // 串口拔出
 	MsgTypeSystemNotify  MessageType = "system_notify"  // 系统通知
 	MsgTypeError         MessageType = "error"          // 错误消息
 )
@@ -63,7 +76,8 @@ type AuthData struct {
 
 // SubscribeData 订阅数据
 type SubscribeData struct {
-	SessionID string `json:"sessionId"` // 会话ID
+	SessionID string `json:"sessionId"`        // 会话ID
+	Format    string `json:"format,omitempty"` // 消息渲染格式: auto/hex/text/both
 }
 
 // UnsubscribeData 取消订阅数据
@@ -73,21 +87,148 @@ type UnsubscribeData struct {
 
 // TCPMessageData TCP消息数据
 type TCPMessageData struct {
-	SessionID  string `json:"sessionId"`  // 会话ID
-	Direction  string `json:"direction"`  // 方向: send/receive
-	Content    string `json:"content"`    // 消息内容
-	IsHex      bool   `json:"isHex"`      // 是否为十六进制
-	ByteLength int    `json:"byteLength"` // 字节长度
-	Timestamp  int64  `json:"timestamp"`  // 时间戳（毫秒）
+	SessionID  string `json:"sessionId"`            // 会话ID
+	Direction  string `json:"direction"`            // 方向: send/receive
+	Content    string `json:"content"`              // 消息内容
+	IsHex      bool   `json:"isHex"`                // 是否为十六进制
+	ByteLength int    `json:"byteLength"`           // 字节长度
+	Timestamp  int64  `json:"timestamp"`            // 时间戳（毫秒）
+	RemoteAddr string `json:"remoteAddr,omitempty"` // 对端地址（可选）
+	Hex        string `json:"hex,omitempty"`        // 十六进制渲染（format为both时）
+	Text       string `json:"text,omitempty"`       // 文本渲染（format为both时）
+	Rule       string `json:"rule,omitempty"`       // 触发自动应答的规则（可选）
+	File       string `json:"file,omitempty"`       // 发送文件汇总记录对应的文件名（可选）
+
+	ChecksumStatus   string `json:"checksumStatus,omitempty"`   // 接收校验结果: ok/mismatch
+	ChecksumExpected string `json:"checksumExpected,omitempty"` // 校验不匹配时期望的校验值
 }
 
 // SessionStatusData 会话状态数据
 type SessionStatusData struct {
+	SessionID string `json:"sessionId"`           // 会话ID
+	Status    string `json:"status"`              // 状态: connected/disconnected/listening/connecting/reconnecting/reconnectFailed
+	Timestamp int64  `json:"timestamp"`           // 时间戳（毫秒）
+	Attempt   int    `json:"attempt,omitempty"`   // 重连尝试次数（reconnecting时）
+	LastError string `json:"lastError,omitempty"` // 最近一次连接错误
+}
+
+// PeerStatusData TCP服务端客户端状态数据
+type PeerStatusData struct {
+	SessionID string `json:"sessionId"` // 会话ID
+	Address   string `json:"address"`   // 客户端地址
+	Status    string `json:"status"`    // 状态: connected/disconnected
+	Timestamp int64  `json:"timestamp"` // 时间戳（毫秒）
+
+	TLS *session.TLSState `json:"tls,omitempty"` // 该客户端的TLS握手结果(仅tlsServer连接时)
+}
+
+// AutoSendData 定时发送进度数据
+type AutoSendData struct {
+	SessionID string `json:"sessionId"`           // 会话ID
+	Running   bool   `json:"running"`             // 是否正在运行
+	Sent      int    `json:"sent"`                // 已成功发送次数
+	Failed    int    `json:"failed"`              // 发送失败次数
+	Count     int    `json:"count"`               // 计划发送次数，0表示不限
+	LastError string `json:"lastError,omitempty"` // 最近一次发送错误
+	Timestamp int64  `json:"timestamp"`           // 时间戳（毫秒）
+}
+
+// ReplayData 流量回放进度数据
+type ReplayData struct {
+	SessionID  string `json:"sessionId"`           // 会话ID
+	Running    bool   `json:"running"`             // 是否正在运行
+	Total      int    `json:"total"`               // 待发送消息数
+	Sent       int    `json:"sent"`                // 已发送消息数
+	Failed     int    `json:"failed"`              // 发送失败次数
+	Matched    int    `json:"matched"`             // 应答匹配次数
+	Mismatched int    `json:"mismatched"`          // 应答不匹配次数
+	Expected   string `json:"expected,omitempty"`  // 最近一次不匹配的录制应答(十六进制)
+	Actual     string `json:"actual,omitempty"`    // 最近一次不匹配的实际应答(十六进制)
+	LastError  string `json:"lastError,omitempty"` // 最近一次错误
+	Timestamp  int64  `json:"timestamp"`           // 时间戳（毫秒）
+}
+
+// FileSendData 发送文件进度数据
+type FileSendData struct {
+	SessionID  string  `json:"sessionId"`           // 会话ID
+	Running    bool    `json:"running"`             // 是否正在发送
+	Path       string  `json:"path"`                // 文件路径
+	Size       int64   `json:"size"`                // 文件大小
+	Sent       int64   `json:"sent"`                // 已发送字节数
+	Chunks     int     `json:"chunks"`              // 已发送块数
+	Throughput float64 `json:"throughput"`          // 平均速率（字节/秒）
+	Canceled   bool    `json:"canceled"`            // 是否被取消
+	LastError  string  `json:"lastError,omitempty"` // 发送失败原因
+	Timestamp  int64   `json:"timestamp"`           // 时间戳（毫秒）
+}
+
+// XModemData XMODEM/YMODEM传输进度数据
+type XModemData struct {
+	SessionID  string  `json:"sessionId"`           // 会话ID
+	Running    bool    `json:"running"`             // 是否正在传输
+	Protocol   string  `json:"protocol"`            // 协议
+	Direction  string  `json:"direction"`           // 方向: send/receive
+	File       string  `json:"file"`                // 当前传输的文件名
+	Size       int64   `json:"size"`                // 文件大小，未知时为0
+	Bytes      int64   `json:"bytes"`               // 已传输字节数
+	Blocks     int     `json:"blocks"`              // 已传输数据包数
+	RetryCount int     `json:"retryCount"`          // 累计重传次数
+	Throughput float64 `json:"throughput"`          // 平均速率（字节/秒）
+	Canceled   bool    `json:"canceled"`            // 是否被取消
+	LastError  string  `json:"lastError,omitempty"` // 传输失败原因
+	Timestamp  int64   `json:"timestamp"`           // 时间戳（毫秒）
+}
+
+// ModbusPollData Modbus轮询结果数据
+type ModbusPollData struct {
+	SessionID string `json:"sessionId"`        // 会话ID
+	PollID    string `json:"pollId"`           // 轮询ID
+	Name      string `json:"name"`             // 轮询名称
+	Running   bool   `json:"running"`          // 是否正在运行
+	Polls     int    `json:"polls"`            // 已轮询次数
+	Failed    int    `json:"failed"`           // 失败次数
+	Result    any    `json:"result,omitempty"` // 最近一次请求结果
+	Timestamp int64  `json:"timestamp"`        // 时间戳（毫秒）
+}
+
+// ModbusSlaveData Modbus从站数据变化数据
+type ModbusSlaveData struct {
 	SessionID string `json:"sessionId"` // 会话ID
-	Status    string `json:"status"`    // 状态: connected/disconnected/listening/connecting
+	Table     string `json:"table"`     // 数据表
+	Address   int    `json:"address"`   // 起始地址
+	Values    []int  `json:"values"`    // 新的值
 	Timestamp int64  `json:"timestamp"` // 时间戳（毫秒）
 }
 
+// ScriptLogData 会话脚本日志数据
+type ScriptLogData struct {
+	SessionID string `json:"sessionId"` // 会话ID
+	Level     string `json:"level"`     // 级别: info/error
+	Message   string `json:"message"`   // 日志内容
+	Timestamp int64  `json:"timestamp"` // 时间戳（毫秒）
+}
+
+// ModemStatusData 串口调制解调器线状态数据
+type ModemStatusData struct {
+	SessionID string `json:"sessionId"` // 会话ID
+	CTS       bool   `json:"cts"`       // 清除发送
+	DSR       bool   `json:"dsr"`       // 数据设备就绪
+	RI        bool   `json:"ri"`        // 振铃指示
+	DCD       bool   `json:"dcd"`       // 载波检测
+	Timestamp int64  `json:"timestamp"` // 时间戳（毫秒）
+}
+
+// PortEventData 串口插拔事件数据
+type PortEventData struct {
+	Name         string `json:"name"`         // 串口名称
+	IsUSB        bool   `json:"isUsb"`        // 是否为USB串口
+	VID          string `json:"vid"`          // USB厂商ID
+	PID          string `json:"pid"`          // USB产品ID
+	SerialNumber string `json:"serialNumber"` // USB序列号
+	Product      string `json:"product"`      // 产品名称
+	Timestamp    int64  `json:"timestamp"`    // 时间戳（毫秒）
+}
+
 // SystemNotifyData 系统通知数据
 type SystemNotifyData struct {
 	Level     string `json:"level"`               // 级别: info/warning/error
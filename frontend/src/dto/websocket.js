@@ -11,6 +11,16 @@ export const MessageType = {
   // 业务消息类型
   TCP_MESSAGE: 'tcp_message',      // TCP消息推送
   SESSION_STATUS: 'session_status', // 会话状态变化
+  AUTO_SEND: 'auto_send',          // 定时发送进度
+  REPLAY: 'replay',                // 流量回放进度
+  SCRIPT_LOG: 'script_log',        // 会话脚本日志
+  FILE_SEND: 'file_send',          // 发送文件进度
+  XMODEM: 'xmodem',                // XMODEM/YMODEM传输进度
+  MODBUS_POLL: 'modbus_poll',      // Modbus轮询结果
+  MODBUS_SLAVE: 'modbus_slave',    // Modbus从站数据变化
+  MODEM_STATUS: 'modem_status',    // 串口调制解调器线状态变化
+  PORT_ADDED: 'port_added',        // 串口插入
+  PORT_REMOVED: 'port_removed',    // 串口拔出
   SYSTEM_NOTIFY: 'system_notify',   // 系统通知
   ERROR: 'error'                   // 错误消息
 }
@@ -88,8 +98,9 @@ export class AuthData {
 
 // 订阅数据
 export class SubscribeData {
-  constructor(sessionId) {
+  constructor(sessionId, format = 'both') {
     this.sessionId = sessionId
+    this.format = format // 消息渲染格式: auto/hex/text/both
   }
 }
 
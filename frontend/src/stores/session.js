@@ -204,7 +204,7 @@ export const useSessionStore = defineStore('session', () => {
           updateSession(baseResponse.data.Info)
         } else {
           // 否则手动更新状态
-          const newStatus = session.type === 'tcpClient' ? 'connected' : 'listening'
+          const newStatus = (session.type === 'tcpClient' || session.type === 'udpClient' || session.type === 'tlsClient') ? 'connected' : 'listening'
           console.log('手动更新状态为:', newStatus)
           updateSessionStatus(session.sessionId, newStatus)
         }
@@ -305,6 +305,9 @@ export const useSessionStore = defineStore('session', () => {
         data: data.content,
         timestamp: data.timestamp,
         isHex: data.isHex,
+        hex: data.hex,
+        text: data.text,
+        remoteAddr: data.remoteAddr,
         byteLength: data.byteLength
       })
       
@@ -340,7 +343,8 @@ export const useSessionStore = defineStore('session', () => {
       const request = new BaseRequest('get_session_messages', {
         sessionId: sessionId,
         limit: 100,
-        offset: 0
+        offset: 0,
+        format: 'both'
       })
 
       const response = await Greet(request.toJson())
@@ -357,6 +361,9 @@ export const useSessionStore = defineStore('session', () => {
           data: record.data,
           timestamp: record.timestamp,
           isHex: record.isHex || false,
+          hex: record.hex,
+          text: record.text,
+          remoteAddr: record.remoteAddr,
           byteLength: record.byteLength || record.data.length
         }))
         
@@ -8,7 +8,12 @@ import (
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"github.com/zhoudm1743/Netser/core"
 	"github.com/zhoudm1743/Netser/dto"
+	"github.com/zhoudm1743/Netser/dto/cert"
+	"github.com/zhoudm1743/Netser/dto/command"
+	"github.com/zhoudm1743/Netser/dto/modbus"
+	"github.com/zhoudm1743/Netser/dto/serial"
 	"github.com/zhoudm1743/Netser/dto/session"
+	"github.com/zhoudm1743/Netser/dto/tcp"
 )
 
 func Handle(ctx context.Context, data string) (string, error) {
@@ -68,12 +73,159 @@ func Handle(ctx context.Context, data string) (string, error) {
 	case "clear_session_messages":
 		return handleClearSessionMessages(request.Data)
 
+	case "export_messages":
+		return handleExportMessages(request.Data)
+
 	case "get_ws_info":
 		return handleGetWSInfo()
 
 	case "get_serial_ports":
 		return handleGetSerialPorts()
 
+	case "get_retention_policy":
+		return dto.Success(core.GlobalConfigStore.GetRetentionPolicy(), "获取保留策略成功"), nil
+
+	case "set_retention_policy":
+		return handleSetRetentionPolicy(request.Data)
+
+	case "set_framing":
+		return handleSetFraming(request.Data)
+
+	case "set_serial_mode":
+		return handleSetSerialMode(request.Data)
+
+	case "set_modem_lines":
+		return handleSetModemLines(request.Data)
+
+	case "send_break":
+		return handleSendBreak(request.Data)
+
+	case "get_modem_status":
+		return handleGetModemStatus(request.Data)
+
+	case "set_checksum":
+		return handleSetChecksum(request.Data)
+
+	case "set_responder":
+		return handleSetResponder(request.Data)
+
+	case "set_script":
+		return handleSetScript(request.Data)
+
+	case "get_script_state":
+		return handleGetScriptState(request.Data)
+
+	case "calc_checksum":
+		return handleCalcChecksum(request.Data)
+
+	case "start_auto_send":
+		return handleStartAutoSend(request.Data)
+
+	case "stop_auto_send":
+		return handleStopAutoSend(request.Data)
+
+	case "get_auto_send":
+		return handleGetAutoSend(request.Data)
+
+	case "start_replay":
+		return handleStartReplay(request.Data)
+
+	case "replay_step":
+		return handleReplayStep(request.Data)
+
+	case "stop_replay":
+		return handleStopReplay(request.Data)
+
+	case "get_replay":
+		return handleGetReplay(request.Data)
+
+	case "send_file":
+		return handleSendFile(request.Data)
+
+	case "stop_file_send":
+		return handleStopFileSend(request.Data)
+
+	case "get_file_send":
+		return handleGetFileSend(request.Data)
+
+	case "start_xmodem":
+		return handleStartXModem(request.Data)
+
+	case "stop_xmodem":
+		return handleStopXModem(request.Data)
+
+	case "get_xmodem":
+		return handleGetXModem(request.Data)
+
+	case "set_reconnect_policy":
+		return handleSetReconnectPolicy(request.Data)
+
+	case "get_peers":
+		return handleGetPeers(request.Data)
+
+	case "kick_peer":
+		return handleKickPeer(request.Data)
+
+	case "create_ca":
+		return handleCreateCA(request.Data)
+
+	case "issue_cert":
+		return handleIssueCert(request.Data)
+
+	case "get_certs":
+		return handleGetCerts()
+
+	case "delete_cert":
+		return handleDeleteCert(request.Data)
+
+	case "list_commands":
+		return handleListCommands(request.Data)
+
+	case "save_command":
+		return handleSaveCommand(request.Data)
+
+	case "delete_command":
+		return handleDeleteCommand(request.Data)
+
+	case "send_command":
+		return handleSendCommand(request.Data)
+
+	case "export_commands":
+		return handleExportCommands(request.Data)
+
+	case "import_commands":
+		return handleImportCommands(request.Data)
+
+	case "modbus_request":
+		return handleModbusRequest(request.Data)
+
+	case "start_modbus_poll":
+		return handleStartModbusPoll(request.Data)
+
+	case "stop_modbus_poll":
+		return handleStopModbusPoll(request.Data)
+
+	case "list_modbus_polls":
+		return handleListModbusPolls(request.Data)
+
+	case "set_modbus_slave":
+		return handleSetModbusSlave(request.Data)
+
+	case "get_modbus_slave":
+		return handleGetModbusSlave(request.Data)
+
+	case "write_modbus_slave":
+		return handleWriteModbusSlave(request.Data)
+
+	case "get_network_interfaces":
+		return handleGetNetworkInterfaces()
+
+	case "join_group":
+		return handleMulticastGroup(request.Data, true)
+
+	case "leave_group":
+		return handleMulticastGroup(request.Data, false)
+
 	default:
 		return dto.Error("未知的请求类型: " + request.Name), nil
 	}
@@ -118,16 +270,49 @@ func handleConnect(data any) (string, error) {
 			sessionID,
 			connectData.SessionData.Port,
 		)
-	case "serial":
-		// 对于串口，暂时使用默认参数
-		err = core.GlobalSerialManager.ConnectSerial(
+	case "tlsClient":
+		err = core.GlobalTLSManager.ConnectTLS(
+			sessionID,
+			connectData.SessionData.Host,
+			connectData.SessionData.Port,
+			5, // 默认超时5秒
+			connectData.SessionData.TLS,
+		)
+	case "tlsServer":
+		err = core.GlobalTLSManager.ListenTLS(
+			sessionID,
+			connectData.SessionData.Port,
+			connectData.SessionData.TLS,
+		)
+	case "udpClient":
+		err = core.GlobalUDPManager.ConnectUDP(
+			sessionID,
+			connectData.SessionData.Host,
+			connectData.SessionData.Port,
+		)
+	case "udpServer":
+		err = core.GlobalUDPManager.ListenUDP(
+			sessionID,
+			connectData.SessionData.Port,
+		)
+	case "udpMulticast":
+		err = core.GlobalMulticastManager.ListenMulticast(
 			sessionID,
-			connectData.SessionData.SerialPort, // 串口名称
-			9600,                               // 默认波特率
-			8,                                  // 默认数据位
-			1,                                  // 默认停止位
-			"none",                             // 默认无校验
+			connectData.SessionData.MulticastGroup,
+			connectData.SessionData.Port,
+			connectData.SessionData.Interface,
+			connectData.SessionData.TTL,
+			connectData.SessionData.Loopback,
 		)
+	case "serial":
+		serialConfig := connectData.SessionData.SerialConfig
+		if serialConfig.SerialPort == "" {
+			// 未携带串口参数时使用创建会话时保存的参数
+			if sess, getErr := core.GlobalSessionManager.GetSession(sessionID); getErr == nil {
+				serialConfig = sess.Info.SerialConfig
+			}
+		}
+		err = core.GlobalSerialManager.ConnectSerial(sessionID, serialConfig)
 	default:
 		return dto.Error("不支持的会话类型"), nil
 	}
@@ -138,9 +323,11 @@ func handleConnect(data any) (string, error) {
 
 	// 更新会话状态
 	var newStatus string
-	if connectData.SessionData.Type == "tcpClient" {
+	if connectData.SessionData.Type == "tcpClient" || connectData.SessionData.Type == "udpClient" ||
+		connectData.SessionData.Type == "tlsClient" {
 		newStatus = "connected"
-	} else if connectData.SessionData.Type == "tcpServer" {
+	} else if connectData.SessionData.Type == "tcpServer" || connectData.SessionData.Type == "udpServer" ||
+		connectData.SessionData.Type == "udpMulticast" || connectData.SessionData.Type == "tlsServer" {
 		newStatus = "listening"
 	} else if connectData.SessionData.Type == "serial" {
 		newStatus = "connected"
@@ -170,13 +357,31 @@ func handleDisconnect(data any) (string, error) {
 		return dto.Error("断开连接数据解析失败"), nil
 	}
 
-	err = core.GlobalTCPManager.DisconnectTCP(disconnectData.SessionID)
+	sess, err := core.GlobalSessionManager.GetSession(disconnectData.SessionID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("会话不存在: %v", err)), nil
+	}
+
+	switch sess.Info.Type {
+	case "udpClient", "udpServer":
+		err = core.GlobalUDPManager.DisconnectUDP(disconnectData.SessionID)
+	case "udpMulticast":
+		err = core.GlobalMulticastManager.DisconnectMulticast(disconnectData.SessionID)
+	case "serial":
+		err = core.GlobalSerialManager.DisconnectSerial(disconnectData.SessionID)
+	default:
+		err = core.GlobalTCPManager.DisconnectTCP(disconnectData.SessionID)
+	}
 	if err != nil {
 		return dto.Error(fmt.Sprintf("断开连接失败: %v", err)), nil
 	}
 
-	// 更新会话状态为断开连接
-	core.GlobalSessionManager.UpdateSessionStatus(disconnectData.SessionID, "disconnected")
+	// 主动断开时停止定时发送、回放、文件传输和Modbus轮询
+	core.GlobalAutoSendManager.Stop(disconnectData.SessionID)
+	core.GlobalReplayManager.Stop(disconnectData.SessionID)
+	core.GlobalFileSendManager.Stop(disconnectData.SessionID)
+	core.GlobalXModemManager.Stop(disconnectData.SessionID)
+	core.GlobalModbusMasterManager.StopPoll(disconnectData.SessionID, "")
 
 	// 获取更新后的会话信息
 	updatedSession, _ := core.GlobalSessionManager.GetSession(disconnectData.SessionID)
@@ -195,6 +400,7 @@ func handleSendData(data any) (string, error) {
 		SessionID string `json:"sessionId"`
 		Data      string `json:"data"`
 		IsHex     bool   `json:"isHex"`
+		Target    string `json:"target"` // UDP目标地址或TCP服务端客户端地址(可选)
 	}
 
 	err = json.Unmarshal(dataBytes, &sendData)
@@ -202,29 +408,13 @@ func handleSendData(data any) (string, error) {
 		return dto.Error("发送数据解析失败"), nil
 	}
 
-	// 获取会话信息以确定类型
-	sess, err := core.GlobalSessionManager.GetSession(sendData.SessionID)
-	if err != nil {
-		return dto.Error(fmt.Sprintf("会话不存在: %v", err)), nil
-	}
-
-	var record *session.MessageRecord
-
 	// 根据会话类型选择不同的发送方式
-	switch sess.Info.Type {
-	case "tcpClient", "tcpServer":
-		record, err = core.GlobalTCPManager.SendTCPData(sendData.SessionID, sendData.Data, sendData.IsHex)
-	case "serial":
-		record, err = core.GlobalSerialManager.SendSerialData(sendData.SessionID, sendData.Data, sendData.IsHex)
-	default:
-		return dto.Error("不支持的会话类型"), nil
-	}
-
+	record, err := core.GlobalSessionManager.SendData(sendData.SessionID, sendData.Data, sendData.IsHex, sendData.Target)
 	if err != nil {
 		return dto.Error(fmt.Sprintf("发送数据失败: %v", err)), nil
 	}
 
-	return dto.Success(record, "数据发送成功"), nil
+	return dto.Success(core.ClientRecord(*record), "数据发送成功"), nil
 }
 
 // handleCreateSession 处理创建会话请求
@@ -241,6 +431,30 @@ func handleCreateSession(data any) (string, error) {
 		Port    int    `json:"port"`
 		IsHex   bool   `json:"isHex"`
 		Timeout int    `json:"timeout"`
+
+		MulticastGroup string `json:"multicastGroup"`
+		Interface      string `json:"interface"`
+		TTL            int    `json:"ttl"`
+		Loopback       bool   `json:"loopback"`
+
+		TLS *session.TLSConfig `json:"tls"`
+
+		Reconnect *session.ReconnectPolicy `json:"reconnect"`
+		Framing   *session.FramingConfig   `json:"framing"`
+		Checksum  *session.ChecksumConfig  `json:"checksum"`
+
+		// 串口参数，前端可能以字符串形式提交数值
+		SerialPort string           `json:"serialPort"`
+		BaudRate   json.Number      `json:"baudRate"`
+		DataBits   json.Number      `json:"dataBits"`
+		StopBits   session.StopBits `json:"stopBits"`
+		Parity     string           `json:"parity"`
+		DTR        *bool            `json:"dtr"`
+		RTS        *bool            `json:"rts"`
+
+		FlowControl     string `json:"flowControl"`
+		FlowTimeout     int    `json:"flowTimeout"`
+		USBSerialNumber string `json:"usbSerialNumber"`
 	}
 
 	err = json.Unmarshal(dataBytes, &sessionData)
@@ -248,19 +462,85 @@ func handleCreateSession(data any) (string, error) {
 		return dto.Error("会话数据解析失败"), nil
 	}
 
-	sessionID, err := core.GlobalTCPManager.CreateTCPSession(
-		sessionData.Name,
-		sessionData.Type,
-		sessionData.Host,
-		sessionData.Port,
-		sessionData.IsHex,
-		sessionData.Timeout,
-	)
+	var sessionID string
+	switch sessionData.Type {
+	case "udpClient", "udpServer":
+		sessionID, err = core.GlobalUDPManager.CreateUDPSession(
+			sessionData.Name,
+			sessionData.Type,
+			sessionData.Host,
+			sessionData.Port,
+			sessionData.IsHex,
+		)
+	case "tlsClient", "tlsServer":
+		sessionID, err = core.GlobalTLSManager.CreateTLSSession(
+			sessionData.Name,
+			sessionData.Type,
+			sessionData.Host,
+			sessionData.Port,
+			sessionData.IsHex,
+			sessionData.TLS,
+		)
+	case "serial":
+		var serialConfig session.SerialConfig
+		serialConfig, err = parseSerialConfig(sessionData.SerialPort, sessionData.BaudRate, sessionData.DataBits,
+			sessionData.StopBits, sessionData.Parity)
+		if err != nil {
+			return dto.Error(fmt.Sprintf("串口参数错误: %v", err)), nil
+		}
+		serialConfig.DTR = sessionData.DTR
+		serialConfig.RTS = sessionData.RTS
+		serialConfig.FlowControl = sessionData.FlowControl
+		serialConfig.FlowTimeout = sessionData.FlowTimeout
+		serialConfig.USBSerialNumber = sessionData.USBSerialNumber
+		sessionID, err = core.GlobalSerialManager.CreateSerialSession(
+			sessionData.Name,
+			serialConfig,
+			sessionData.IsHex,
+		)
+	case "udpMulticast":
+		sessionID, err = core.GlobalMulticastManager.CreateMulticastSession(
+			sessionData.Name,
+			sessionData.MulticastGroup,
+			sessionData.Port,
+			sessionData.Interface,
+			sessionData.TTL,
+			sessionData.Loopback,
+			sessionData.IsHex,
+		)
+	default:
+		sessionID, err = core.GlobalTCPManager.CreateTCPSession(
+			sessionData.Name,
+			sessionData.Type,
+			sessionData.Host,
+			sessionData.Port,
+			sessionData.IsHex,
+			sessionData.Timeout,
+		)
+	}
 
 	if err != nil {
 		return dto.Error(fmt.Sprintf("创建会话失败: %v", err)), nil
 	}
 
+	if sessionData.Framing != nil {
+		if err := core.GlobalSessionManager.SetFraming(sessionID, sessionData.Framing); err != nil {
+			fmt.Printf("设置分帧配置失败: %v\n", err)
+		}
+	}
+
+	if sessionData.Checksum != nil {
+		if err := core.GlobalSessionManager.SetChecksum(sessionID, sessionData.Checksum); err != nil {
+			fmt.Printf("设置校验配置失败: %v\n", err)
+		}
+	}
+
+	if sessionData.Reconnect != nil {
+		if err := core.GlobalReconnectManager.SetPolicy(sessionID, sessionData.Reconnect); err != nil {
+			fmt.Printf("设置重连策略失败: %v\n", err)
+		}
+	}
+
 	// 获取创建的会话信息
 	sess, err := core.GlobalSessionManager.GetSession(sessionID)
 	if err != nil {
@@ -288,16 +568,20 @@ func handleRemoveSession(data any) (string, error) {
 		return dto.Error("数据格式错误"), nil
 	}
 
-	var removeData struct {
-		SessionID string `json:"sessionId"`
-	}
+	var removeData session.SessionRemoveRequest
 
 	err = json.Unmarshal(dataBytes, &removeData)
 	if err != nil {
 		return dto.Error("移除会话数据解析失败"), nil
 	}
 
-	err = core.GlobalSessionManager.RemoveSession(removeData.SessionID)
+	// 未明确指定时按保留策略决定是否删除历史记录
+	deleteHistory := core.GlobalConfigStore.GetRetentionPolicy().DeleteHistoryOnRemove
+	if removeData.DeleteHistory != nil {
+		deleteHistory = *removeData.DeleteHistory
+	}
+
+	err = core.GlobalSessionManager.RemoveSession(removeData.SessionID, deleteHistory)
 	if err != nil {
 		return dto.Error(fmt.Sprintf("移除会话失败: %v", err)), nil
 	}
@@ -316,6 +600,7 @@ func handleGetSessionMessages(data any) (string, error) {
 		SessionID string `json:"sessionId"`
 		Limit     int    `json:"limit"`
 		Offset    int    `json:"offset"`
+		Format    string `json:"format"`
 	}
 
 	err = json.Unmarshal(dataBytes, &messageData)
@@ -338,7 +623,7 @@ func handleGetSessionMessages(data any) (string, error) {
 		return dto.Error("会话不存在"), nil
 	}
 
-	messages := sess.GetMessages(messageData.Limit, messageData.Offset)
+	messages := sess.GetMessages(messageData.Limit, messageData.Offset, messageData.Format)
 
 	// 获取消息总数
 	var total int
@@ -384,6 +669,27 @@ func handleClearSessionMessages(data any) (string, error) {
 	return dto.Success(nil, "消息记录清空成功"), nil
 }
 
+// handleExportMessages 处理导出会话历史记录请求
+func handleExportMessages(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var exportData session.SessionExportRequest
+	err = json.Unmarshal(dataBytes, &exportData)
+	if err != nil {
+		return dto.Error("导出数据解析失败"), nil
+	}
+
+	result, err := core.ExportMessages(exportData)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("导出失败: %v", err)), nil
+	}
+
+	return dto.Success(result, "导出成功"), nil
+}
+
 // handleGetWSInfo 处理获取WebSocket信息请求
 func handleGetWSInfo() (string, error) {
 	if core.GlobalWebSocketManager == nil {
@@ -396,14 +702,1118 @@ func handleGetWSInfo() (string, error) {
 
 // handleGetSerialPorts 处理获取串口列表请求
 func handleGetSerialPorts() (string, error) {
-	ports, err := core.GlobalSerialManager.GetSerialPorts()
+	details, err := core.GlobalSerialManager.GetSerialPortDetails()
 	if err != nil {
 		return dto.Error(fmt.Sprintf("获取串口列表失败: %v", err)), nil
 	}
 
-	response := map[string]interface{}{
-		"ports": ports,
+	// 保留ports名称列表兼容旧版前端
+	response := serial.SerialPortListResponse{
+		Ports:   make([]string, 0, len(details)),
+		Details: details,
+	}
+	for _, port := range details {
+		response.Ports = append(response.Ports, port.Name)
 	}
 
 	return dto.Success(response, "获取串口列表成功"), nil
 }
+
+// handleGetNetworkInterfaces 处理获取网络接口列表请求
+func handleGetNetworkInterfaces() (string, error) {
+	interfaces, err := core.GlobalMulticastManager.GetNetworkInterfaces()
+	if err != nil {
+		return dto.Error(fmt.Sprintf("获取网络接口失败: %v", err)), nil
+	}
+
+	response := map[string]interface{}{
+		"interfaces": interfaces,
+	}
+
+	return dto.Success(response, "获取网络接口成功"), nil
+}
+
+// handleMulticastGroup 处理加入/离开组播组请求
+func handleMulticastGroup(data any, join bool) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var groupData struct {
+		SessionID string `json:"sessionId"`
+		Group     string `json:"group"`
+		Interface string `json:"interface"`
+	}
+
+	err = json.Unmarshal(dataBytes, &groupData)
+	if err != nil {
+		return dto.Error("组播数据解析失败"), nil
+	}
+
+	if join {
+		err = core.GlobalMulticastManager.JoinGroup(groupData.SessionID, groupData.Group, groupData.Interface)
+		if err != nil {
+			return dto.Error(fmt.Sprintf("加入组播组失败: %v", err)), nil
+		}
+		return dto.Success(groupData, "加入组播组成功"), nil
+	}
+
+	err = core.GlobalMulticastManager.LeaveGroup(groupData.SessionID, groupData.Group, groupData.Interface)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("离开组播组失败: %v", err)), nil
+	}
+	return dto.Success(groupData, "离开组播组成功"), nil
+}
+
+// handleGetPeers 处理获取TCP服务端客户端列表请求
+func handleGetPeers(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var peerData struct {
+		SessionID string `json:"sessionId"`
+	}
+
+	err = json.Unmarshal(dataBytes, &peerData)
+	if err != nil {
+		return dto.Error("客户端列表数据解析失败"), nil
+	}
+
+	peers, err := core.GlobalTCPManager.GetPeers(peerData.SessionID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("获取客户端列表失败: %v", err)), nil
+	}
+
+	response := tcp.TCPPeerListResponse{
+		SessionID: peerData.SessionID,
+		Peers:     peers,
+	}
+
+	return dto.Success(response, "获取客户端列表成功"), nil
+}
+
+// handleKickPeer 处理断开TCP服务端客户端请求
+func handleKickPeer(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var kickData tcp.TCPKickPeerRequest
+
+	err = json.Unmarshal(dataBytes, &kickData)
+	if err != nil {
+		return dto.Error("断开客户端数据解析失败"), nil
+	}
+
+	err = core.GlobalTCPManager.KickPeer(kickData.SessionID, kickData.Address)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("断开客户端失败: %v", err)), nil
+	}
+
+	return dto.Success(kickData, "客户端已断开"), nil
+}
+
+// handleCreateCA 处理创建本地CA请求
+func handleCreateCA(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var caData cert.CertCreateCARequest
+	err = json.Unmarshal(dataBytes, &caData)
+	if err != nil {
+		return dto.Error("CA数据解析失败"), nil
+	}
+
+	info, err := core.GlobalCertManager.CreateCA(caData)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("创建CA失败: %v", err)), nil
+	}
+
+	return dto.Success(info, "CA创建成功"), nil
+}
+
+// handleIssueCert 处理签发证书请求
+func handleIssueCert(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var issueData cert.CertIssueRequest
+	err = json.Unmarshal(dataBytes, &issueData)
+	if err != nil {
+		return dto.Error("证书数据解析失败"), nil
+	}
+
+	info, err := core.GlobalCertManager.IssueCert(issueData)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("签发证书失败: %v", err)), nil
+	}
+
+	return dto.Success(info, "证书签发成功"), nil
+}
+
+// handleGetCerts 处理获取证书列表请求
+func handleGetCerts() (string, error) {
+	certs, err := core.GlobalCertManager.ListCerts()
+	if err != nil {
+		return dto.Error(fmt.Sprintf("获取证书列表失败: %v", err)), nil
+	}
+
+	response := cert.CertListResponse{
+		Certs: certs,
+	}
+
+	return dto.Success(response, "获取证书列表成功"), nil
+}
+
+// handleDeleteCert 处理删除证书请求
+func handleDeleteCert(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var deleteData cert.CertDeleteRequest
+	err = json.Unmarshal(dataBytes, &deleteData)
+	if err != nil {
+		return dto.Error("删除证书数据解析失败"), nil
+	}
+
+	err = core.GlobalCertManager.DeleteCert(deleteData.Name)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("删除证书失败: %v", err)), nil
+	}
+
+	return dto.Success(nil, "证书删除成功"), nil
+}
+
+// handleListCommands 处理获取快捷指令列表请求
+func handleListCommands(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var listData command.CommandListRequest
+	err = json.Unmarshal(dataBytes, &listData)
+	if err != nil {
+		return dto.Error("指令数据解析失败"), nil
+	}
+
+	resp, err := core.GlobalCommandManager.List(listData)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("获取指令列表失败: %v", err)), nil
+	}
+
+	return dto.Success(resp, "获取指令列表成功"), nil
+}
+
+// handleSaveCommand 处理保存快捷指令请求
+func handleSaveCommand(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var entry command.CommandEntry
+	err = json.Unmarshal(dataBytes, &entry)
+	if err != nil {
+		return dto.Error("指令数据解析失败"), nil
+	}
+
+	saved, err := core.GlobalCommandManager.Save(entry)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("保存指令失败: %v", err)), nil
+	}
+
+	return dto.Success(saved, "指令保存成功"), nil
+}
+
+// handleDeleteCommand 处理删除快捷指令请求
+func handleDeleteCommand(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var deleteData command.CommandDeleteRequest
+	err = json.Unmarshal(dataBytes, &deleteData)
+	if err != nil {
+		return dto.Error("删除指令数据解析失败"), nil
+	}
+
+	err = core.GlobalCommandManager.Delete(deleteData.ID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("删除指令失败: %v", err)), nil
+	}
+
+	return dto.Success(nil, "指令删除成功"), nil
+}
+
+// handleSendCommand 处理通过会话发送快捷指令请求
+func handleSendCommand(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var sendData command.CommandSendRequest
+	err = json.Unmarshal(dataBytes, &sendData)
+	if err != nil {
+		return dto.Error("发送指令数据解析失败"), nil
+	}
+
+	record, err := core.GlobalCommandManager.Send(sendData)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("发送指令失败: %v", err)), nil
+	}
+
+	return dto.Success(core.ClientRecord(*record), "指令发送成功"), nil
+}
+
+// handleExportCommands 处理导出快捷指令请求
+func handleExportCommands(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var exportData command.CommandExportRequest
+	err = json.Unmarshal(dataBytes, &exportData)
+	if err != nil {
+		return dto.Error("导出指令数据解析失败"), nil
+	}
+
+	resp, err := core.GlobalCommandManager.Export(exportData)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("导出指令失败: %v", err)), nil
+	}
+
+	return dto.Success(resp, "指令导出成功"), nil
+}
+
+// handleImportCommands 处理导入快捷指令请求
+func handleImportCommands(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var importData command.CommandImportRequest
+	err = json.Unmarshal(dataBytes, &importData)
+	if err != nil {
+		return dto.Error("导入指令数据解析失败"), nil
+	}
+
+	resp, err := core.GlobalCommandManager.Import(importData)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("导入指令失败: %v", err)), nil
+	}
+
+	return dto.Success(resp, "指令导入成功"), nil
+}
+
+// handleModbusRequest 处理Modbus主站请求
+func handleModbusRequest(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var modbusData modbus.ModbusMasterRequest
+	err = json.Unmarshal(dataBytes, &modbusData)
+	if err != nil {
+		return dto.Error("Modbus请求数据解析失败"), nil
+	}
+
+	result, err := core.GlobalModbusMasterManager.Execute(modbusData.SessionID, modbusData.ModbusRequest)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("Modbus请求失败: %v", err)), nil
+	}
+
+	switch {
+	case result.Error != "":
+		return dto.Success(result, fmt.Sprintf("Modbus请求失败: %s", result.Error)), nil
+	case result.Exception != 0:
+		return dto.Success(result, fmt.Sprintf("从站返回异常: %s", result.ExceptionText)), nil
+	default:
+		return dto.Success(result, "Modbus请求成功"), nil
+	}
+}
+
+// handleStartModbusPoll 处理启动Modbus轮询请求
+func handleStartModbusPoll(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var pollData modbus.ModbusPollRequest
+	err = json.Unmarshal(dataBytes, &pollData)
+	if err != nil {
+		return dto.Error("Modbus轮询数据解析失败"), nil
+	}
+
+	state, err := core.GlobalModbusMasterManager.StartPoll(pollData.SessionID, pollData.ModbusPollConfig)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("启动Modbus轮询失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "Modbus轮询已启动"), nil
+}
+
+// handleStopModbusPoll 处理停止Modbus轮询请求
+func handleStopModbusPoll(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var stopData modbus.ModbusPollStopRequest
+	err = json.Unmarshal(dataBytes, &stopData)
+	if err != nil {
+		return dto.Error("Modbus轮询数据解析失败"), nil
+	}
+
+	polls, err := core.GlobalModbusMasterManager.StopPoll(stopData.SessionID, stopData.ID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("停止Modbus轮询失败: %v", err)), nil
+	}
+
+	return dto.Success(polls, "Modbus轮询已停止"), nil
+}
+
+// handleListModbusPolls 处理获取Modbus轮询列表请求
+func handleListModbusPolls(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var queryData struct {
+		SessionID string `json:"sessionId"`
+	}
+	err = json.Unmarshal(dataBytes, &queryData)
+	if err != nil {
+		return dto.Error("Modbus轮询数据解析失败"), nil
+	}
+
+	polls, err := core.GlobalModbusMasterManager.ListPolls(queryData.SessionID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("获取Modbus轮询失败: %v", err)), nil
+	}
+
+	return dto.Success(polls, "获取Modbus轮询成功"), nil
+}
+
+// handleSetModbusSlave 处理设置Modbus从站模拟请求
+func handleSetModbusSlave(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var slaveData modbus.ModbusSlaveRequest
+	err = json.Unmarshal(dataBytes, &slaveData)
+	if err != nil {
+		return dto.Error("Modbus从站数据解析失败"), nil
+	}
+
+	err = core.GlobalModbusSlaveManager.Set(slaveData.SessionID, slaveData.Slave)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("设置Modbus从站失败: %v", err)), nil
+	}
+
+	state, err := core.GlobalModbusSlaveManager.GetState(slaveData.SessionID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("获取Modbus从站状态失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "Modbus从站设置成功"), nil
+}
+
+// handleGetModbusSlave 处理获取Modbus从站状态请求
+func handleGetModbusSlave(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var queryData struct {
+		SessionID string `json:"sessionId"`
+	}
+	err = json.Unmarshal(dataBytes, &queryData)
+	if err != nil {
+		return dto.Error("Modbus从站数据解析失败"), nil
+	}
+
+	state, err := core.GlobalModbusSlaveManager.GetState(queryData.SessionID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("获取Modbus从站状态失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "获取Modbus从站状态成功"), nil
+}
+
+// handleWriteModbusSlave 处理修改Modbus从站数据请求
+func handleWriteModbusSlave(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var writeData modbus.ModbusSlaveWriteRequest
+	err = json.Unmarshal(dataBytes, &writeData)
+	if err != nil {
+		return dto.Error("Modbus从站数据解析失败"), nil
+	}
+
+	state, err := core.GlobalModbusSlaveManager.WriteValues(writeData.SessionID, writeData.Table, writeData.Address, writeData.Values)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("修改Modbus从站数据失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "Modbus从站数据已修改"), nil
+}
+
+// handleSetReconnectPolicy 处理设置自动重连策略请求
+func handleSetReconnectPolicy(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var policyData session.SessionReconnectRequest
+	err = json.Unmarshal(dataBytes, &policyData)
+	if err != nil {
+		return dto.Error("重连策略数据解析失败"), nil
+	}
+
+	err = core.GlobalReconnectManager.SetPolicy(policyData.SessionID, policyData.Policy)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("设置重连策略失败: %v", err)), nil
+	}
+
+	sess, err := core.GlobalSessionManager.GetSession(policyData.SessionID)
+	if err != nil {
+		return dto.Error("会话不存在"), nil
+	}
+
+	return dto.Success(sess.Info, "重连策略设置成功"), nil
+}
+
+// handleSetFraming 处理设置分帧配置请求
+func handleSetFraming(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var framingData session.SessionFramingRequest
+	err = json.Unmarshal(dataBytes, &framingData)
+	if err != nil {
+		return dto.Error("分帧配置数据解析失败"), nil
+	}
+
+	err = core.GlobalSessionManager.SetFraming(framingData.SessionID, framingData.Framing)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("设置分帧配置失败: %v", err)), nil
+	}
+
+	sess, err := core.GlobalSessionManager.GetSession(framingData.SessionID)
+	if err != nil {
+		return dto.Error("会话不存在"), nil
+	}
+
+	return dto.Success(sess.Info, "分帧配置设置成功"), nil
+}
+
+// handleStartAutoSend 处理启动定时发送请求
+func handleStartAutoSend(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var autoSendData session.AutoSendRequest
+	err = json.Unmarshal(dataBytes, &autoSendData)
+	if err != nil {
+		return dto.Error("定时发送数据解析失败"), nil
+	}
+
+	state, err := core.GlobalAutoSendManager.Start(autoSendData.SessionID, autoSendData.AutoSendConfig)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("启动定时发送失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "定时发送已启动"), nil
+}
+
+// handleStopAutoSend 处理停止定时发送请求
+func handleStopAutoSend(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var stopData struct {
+		SessionID string `json:"sessionId"`
+	}
+	err = json.Unmarshal(dataBytes, &stopData)
+	if err != nil {
+		return dto.Error("定时发送数据解析失败"), nil
+	}
+
+	state, err := core.GlobalAutoSendManager.Stop(stopData.SessionID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("停止定时发送失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "定时发送已停止"), nil
+}
+
+// handleGetAutoSend 处理获取定时发送状态请求
+func handleGetAutoSend(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var queryData struct {
+		SessionID string `json:"sessionId"`
+	}
+	err = json.Unmarshal(dataBytes, &queryData)
+	if err != nil {
+		return dto.Error("定时发送数据解析失败"), nil
+	}
+
+	state, err := core.GlobalAutoSendManager.GetState(queryData.SessionID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("获取定时发送状态失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "获取定时发送状态成功"), nil
+}
+
+// handleStartReplay 处理启动流量回放请求
+func handleStartReplay(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var replayData session.ReplayRequest
+	err = json.Unmarshal(dataBytes, &replayData)
+	if err != nil {
+		return dto.Error("回放数据解析失败"), nil
+	}
+
+	state, err := core.GlobalReplayManager.Start(replayData.SessionID, replayData.ReplayConfig)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("启动回放失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "回放已启动"), nil
+}
+
+// handleReplayStep 处理单步回放请求
+func handleReplayStep(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var stepData struct {
+		SessionID string `json:"sessionId"`
+	}
+	err = json.Unmarshal(dataBytes, &stepData)
+	if err != nil {
+		return dto.Error("回放数据解析失败"), nil
+	}
+
+	if err := core.GlobalReplayManager.Step(stepData.SessionID); err != nil {
+		return dto.Error(fmt.Sprintf("单步回放失败: %v", err)), nil
+	}
+
+	return dto.Success(nil, "已发送下一条"), nil
+}
+
+// handleStopReplay 处理停止流量回放请求
+func handleStopReplay(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var stopData struct {
+		SessionID string `json:"sessionId"`
+	}
+	err = json.Unmarshal(dataBytes, &stopData)
+	if err != nil {
+		return dto.Error("回放数据解析失败"), nil
+	}
+
+	state, err := core.GlobalReplayManager.Stop(stopData.SessionID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("停止回放失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "回放已停止"), nil
+}
+
+// handleGetReplay 处理获取流量回放状态请求
+func handleGetReplay(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var queryData struct {
+		SessionID string `json:"sessionId"`
+	}
+	err = json.Unmarshal(dataBytes, &queryData)
+	if err != nil {
+		return dto.Error("回放数据解析失败"), nil
+	}
+
+	state, err := core.GlobalReplayManager.GetState(queryData.SessionID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("获取回放状态失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "获取回放状态成功"), nil
+}
+
+// handleSendFile 处理发送文件请求
+func handleSendFile(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var fileData session.FileSendRequest
+	err = json.Unmarshal(dataBytes, &fileData)
+	if err != nil {
+		return dto.Error("发送文件数据解析失败"), nil
+	}
+
+	state, err := core.GlobalFileSendManager.Start(fileData.SessionID, fileData.FileSendConfig)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("发送文件失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "开始发送文件"), nil
+}
+
+// handleStopFileSend 处理取消发送文件请求
+func handleStopFileSend(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var queryData struct {
+		SessionID string `json:"sessionId"`
+	}
+	err = json.Unmarshal(dataBytes, &queryData)
+	if err != nil {
+		return dto.Error("发送文件数据解析失败"), nil
+	}
+
+	state, err := core.GlobalFileSendManager.Stop(queryData.SessionID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("取消发送文件失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "已取消发送文件"), nil
+}
+
+// handleGetFileSend 处理获取发送文件状态请求
+func handleGetFileSend(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var queryData struct {
+		SessionID string `json:"sessionId"`
+	}
+	err = json.Unmarshal(dataBytes, &queryData)
+	if err != nil {
+		return dto.Error("发送文件数据解析失败"), nil
+	}
+
+	state, err := core.GlobalFileSendManager.GetState(queryData.SessionID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("获取发送文件状态失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "获取发送文件状态成功"), nil
+}
+
+// handleStartXModem 处理启动XMODEM/YMODEM传输请求
+func handleStartXModem(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var xmodemData session.XModemRequest
+	err = json.Unmarshal(dataBytes, &xmodemData)
+	if err != nil {
+		return dto.Error("传输数据解析失败"), nil
+	}
+
+	state, err := core.GlobalXModemManager.Start(xmodemData.SessionID, xmodemData.XModemConfig)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("启动传输失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "开始传输"), nil
+}
+
+// handleStopXModem 处理取消XMODEM/YMODEM传输请求
+func handleStopXModem(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var queryData struct {
+		SessionID string `json:"sessionId"`
+	}
+	err = json.Unmarshal(dataBytes, &queryData)
+	if err != nil {
+		return dto.Error("传输数据解析失败"), nil
+	}
+
+	state, err := core.GlobalXModemManager.Stop(queryData.SessionID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("取消传输失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "已取消传输"), nil
+}
+
+// handleGetXModem 处理获取XMODEM/YMODEM传输状态请求
+func handleGetXModem(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var queryData struct {
+		SessionID string `json:"sessionId"`
+	}
+	err = json.Unmarshal(dataBytes, &queryData)
+	if err != nil {
+		return dto.Error("传输数据解析失败"), nil
+	}
+
+	state, err := core.GlobalXModemManager.GetState(queryData.SessionID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("获取传输状态失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "获取传输状态成功"), nil
+}
+
+// handleSetChecksum 处理设置校验配置请求
+func handleSetChecksum(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var checksumData session.SessionChecksumRequest
+	err = json.Unmarshal(dataBytes, &checksumData)
+	if err != nil {
+		return dto.Error("校验配置数据解析失败"), nil
+	}
+
+	err = core.GlobalSessionManager.SetChecksum(checksumData.SessionID, checksumData.Checksum)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("设置校验配置失败: %v", err)), nil
+	}
+
+	sess, err := core.GlobalSessionManager.GetSession(checksumData.SessionID)
+	if err != nil {
+		return dto.Error("会话不存在"), nil
+	}
+
+	return dto.Success(sess.Info, "校验配置设置成功"), nil
+}
+
+// handleSetResponder 处理设置自动应答规则请求
+func handleSetResponder(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var responderData session.SessionResponderRequest
+	err = json.Unmarshal(dataBytes, &responderData)
+	if err != nil {
+		return dto.Error("自动应答数据解析失败"), nil
+	}
+
+	err = core.GlobalSessionManager.SetResponder(responderData.SessionID, responderData.Responder)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("设置自动应答失败: %v", err)), nil
+	}
+
+	sess, err := core.GlobalSessionManager.GetSession(responderData.SessionID)
+	if err != nil {
+		return dto.Error("会话不存在"), nil
+	}
+
+	return dto.Success(sess.Info, "自动应答设置成功"), nil
+}
+
+// handleSetScript 处理设置会话脚本请求
+func handleSetScript(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var scriptData session.SessionScriptRequest
+	err = json.Unmarshal(dataBytes, &scriptData)
+	if err != nil {
+		return dto.Error("脚本数据解析失败"), nil
+	}
+
+	state, err := core.GlobalScriptManager.SetScript(scriptData.SessionID, scriptData.Script)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("设置脚本失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "脚本设置成功"), nil
+}
+
+// handleGetScriptState 处理获取脚本运行状态请求
+func handleGetScriptState(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var queryData struct {
+		SessionID string `json:"sessionId"`
+	}
+	err = json.Unmarshal(dataBytes, &queryData)
+	if err != nil {
+		return dto.Error("脚本数据解析失败"), nil
+	}
+
+	state, err := core.GlobalScriptManager.GetState(queryData.SessionID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("获取脚本状态失败: %v", err)), nil
+	}
+
+	return dto.Success(state, "获取脚本状态成功"), nil
+}
+
+// handleCalcChecksum 处理计算校验值请求
+func handleCalcChecksum(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var calcData session.ChecksumCalcRequest
+	err = json.Unmarshal(dataBytes, &calcData)
+	if err != nil {
+		return dto.Error("校验数据解析失败"), nil
+	}
+
+	payload, err := core.DecodePayload(calcData.Data, calcData.IsHex)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("数据格式错误: %v", err)), nil
+	}
+
+	sum, err := core.ComputeChecksum(calcData.Algorithm, payload)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("计算校验值失败: %v", err)), nil
+	}
+
+	result := struct {
+		Algorithm string `json:"algorithm"`
+		Checksum  string `json:"checksum"`
+	}{
+		Algorithm: calcData.Algorithm,
+		Checksum:  core.EncodeHex(sum),
+	}
+
+	return dto.Success(result, "计算校验值成功"), nil
+}
+
+// handleSetSerialMode 处理修改串口参数请求
+func handleSetSerialMode(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var modeData struct {
+		SessionID string           `json:"sessionId"`
+		BaudRate  json.Number      `json:"baudRate"`
+		DataBits  json.Number      `json:"dataBits"`
+		StopBits  session.StopBits `json:"stopBits"`
+		Parity    string           `json:"parity"`
+		DTR       *bool            `json:"dtr"`
+		RTS       *bool            `json:"rts"`
+
+		FlowControl string `json:"flowControl"`
+		FlowTimeout int    `json:"flowTimeout"`
+	}
+	err = json.Unmarshal(dataBytes, &modeData)
+	if err != nil {
+		return dto.Error("串口参数数据解析失败"), nil
+	}
+
+	serialConfig, err := parseSerialConfig("", modeData.BaudRate, modeData.DataBits, modeData.StopBits, modeData.Parity)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("串口参数错误: %v", err)), nil
+	}
+	serialConfig.DTR = modeData.DTR
+	serialConfig.RTS = modeData.RTS
+	serialConfig.FlowControl = modeData.FlowControl
+	serialConfig.FlowTimeout = modeData.FlowTimeout
+
+	err = core.GlobalSerialManager.SetSerialMode(modeData.SessionID, serialConfig)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("修改串口参数失败: %v", err)), nil
+	}
+
+	sess, err := core.GlobalSessionManager.GetSession(modeData.SessionID)
+	if err != nil {
+		return dto.Error("会话不存在"), nil
+	}
+
+	return dto.Success(sess.Info, "串口参数修改成功"), nil
+}
+
+// parseSerialConfig 解析串口参数，数值可以是数字或字符串，空值使用默认值
+func parseSerialConfig(port string, baudRate, dataBits json.Number, stopBits session.StopBits, parity string) (session.SerialConfig, error) {
+	cfg := session.SerialConfig{
+		SerialPort: port,
+		StopBits:   stopBits,
+		Parity:     parity,
+	}
+
+	if baudRate != "" {
+		value, err := baudRate.Int64()
+		if err != nil {
+			return cfg, fmt.Errorf("波特率格式错误: %s", baudRate)
+		}
+		cfg.BaudRate = int(value)
+	}
+	if dataBits != "" {
+		value, err := dataBits.Int64()
+		if err != nil {
+			return cfg, fmt.Errorf("数据位格式错误: %s", dataBits)
+		}
+		cfg.DataBits = int(value)
+	}
+
+	return cfg, nil
+}
+
+// handleSetModemLines 处理设置DTR/RTS请求
+func handleSetModemLines(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var linesData session.SerialLinesRequest
+	err = json.Unmarshal(dataBytes, &linesData)
+	if err != nil {
+		return dto.Error("串口控制线数据解析失败"), nil
+	}
+
+	err = core.GlobalSerialManager.SetModemLines(linesData.SessionID, linesData.DTR, linesData.RTS)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("设置串口控制线失败: %v", err)), nil
+	}
+
+	sess, err := core.GlobalSessionManager.GetSession(linesData.SessionID)
+	if err != nil {
+		return dto.Error("会话不存在"), nil
+	}
+
+	return dto.Success(sess.Info, "串口控制线设置成功"), nil
+}
+
+// handleSendBreak 处理发送Break信号请求
+func handleSendBreak(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var breakData session.SerialBreakRequest
+	err = json.Unmarshal(dataBytes, &breakData)
+	if err != nil {
+		return dto.Error("Break数据解析失败"), nil
+	}
+
+	err = core.GlobalSerialManager.SendBreak(breakData.SessionID, breakData.Duration)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("发送Break信号失败: %v", err)), nil
+	}
+
+	return dto.Success(nil, "Break信号发送成功"), nil
+}
+
+// handleGetModemStatus 处理读取调制解调器状态请求
+func handleGetModemStatus(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var statusData struct {
+		SessionID string `json:"sessionId"`
+	}
+	err = json.Unmarshal(dataBytes, &statusData)
+	if err != nil {
+		return dto.Error("调制解调器状态数据解析失败"), nil
+	}
+
+	status, err := core.GlobalSerialManager.GetModemStatus(statusData.SessionID)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("读取调制解调器状态失败: %v", err)), nil
+	}
+
+	return dto.Success(status, "读取调制解调器状态成功"), nil
+}
+
+// handleSetRetentionPolicy 处理设置历史记录保留策略请求
+func handleSetRetentionPolicy(data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dto.Error("数据格式错误"), nil
+	}
+
+	var policy session.RetentionPolicy
+	err = json.Unmarshal(dataBytes, &policy)
+	if err != nil {
+		return dto.Error("保留策略数据解析失败"), nil
+	}
+
+	err = core.GlobalConfigStore.SetRetentionPolicy(policy)
+	if err != nil {
+		return dto.Error(fmt.Sprintf("设置保留策略失败: %v", err)), nil
+	}
+
+	return dto.Success(policy, "保留策略设置成功"), nil
+}